@@ -0,0 +1,298 @@
+package ast
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// An ApplyFunc is invoked by Apply for each node, before and/or after the
+// node's children, using a Cursor describing the current node and
+// providing operations on it.
+//
+// The return value of ApplyFunc controls the syntax tree traversal.
+// See Apply for details.
+type ApplyFunc func(*Cursor) bool
+
+// Apply rewrites a tree, starting with root, and calling pre and post for
+// each node as described below. It returns the rewritten tree; root
+// itself is never modified.
+//
+// Rewriting is copy-on-write: a node is copied the first time one of its
+// children is replaced, deleted or inserted next to, and so are its
+// ancestors, while the subtrees that do not change are shared between
+// root and the result. Copies keep the spans of the nodes they were made
+// from. This makes Apply safe to use on trees that are shared, such as
+// those from cypher.Cache.
+//
+// If pre is not nil, it is called for each node before the node's
+// children are traversed (pre-order). If pre returns false, no children
+// are traversed, and post is not called for that node.
+//
+// If post is not nil, and a prior call of pre didn't return false, post is
+// called for each node after its children are traversed (post-order). If
+// post returns false, traversal is terminated and Apply returns
+// immediately, with the changes made so far.
+//
+// Only fields that refer to nodes are traversed, in the same order as by
+// Walk. Nodes put in place with Cursor.Replace, Cursor.InsertBefore and
+// Cursor.InsertAfter are not traversed.
+//
+// A change that does not fit the tree, such as putting a clause where an
+// expression belongs, stops the rewrite, and Apply returns root and an
+// error describing the change.
+func Apply(root Node, pre, post ApplyFunc) (result Node, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(applyError)
+			if !ok {
+				panic(r)
+			}
+			result, err = root, e.err
+		}
+	}()
+
+	a := &application{pre: pre, post: post}
+	return a.apply(nil, "", -1, root, nodeType, false)[0], nil
+}
+
+// applyError is the value a Cursor panics with when asked for a change
+// that does not fit the tree.
+type applyError struct {
+	err error
+}
+
+var nodeType = reflect.TypeOf((*Node)(nil)).Elem()
+
+// A Cursor describes a node encountered during Apply. Information about
+// the node and its parent is available from the Node, Parent, Name and
+// Index methods.
+type Cursor struct {
+	parent Node
+	name   string
+	index  int          // index in the parent's slice, or -1
+	typ    reflect.Type // type of the field or slice element
+	fixed  bool         // whether the slice must keep its length
+
+	node     Node
+	before   []Node
+	after    []Node
+	replaced bool
+	deleted  bool
+}
+
+// Node returns the current Node, or its replacement. It returns nil once
+// the node is deleted.
+func (c *Cursor) Node() Node { return c.node }
+
+// Parent returns the parent of the current Node, as it is in the tree
+// passed to Apply. It is nil for the root.
+func (c *Cursor) Parent() Node { return c.parent }
+
+// Name returns the name of the parent Node field that contains the
+// current Node, as in "Where" or "Clauses". It is empty for the root.
+func (c *Cursor) Name() string { return c.name }
+
+// Index reports the index of the current Node in the slice of Nodes that
+// contains it, as it is in the tree passed to Apply, or a value < 0 if the
+// current Node is not part of a slice.
+func (c *Cursor) Index() int { return c.index }
+
+// Replace replaces the current Node with n. The replacement is not
+// traversed by Apply.
+func (c *Cursor) Replace(n Node) {
+	if c.deleted {
+		c.fail("Replace", "the node has been deleted")
+	}
+	c.check("Replace", n)
+	c.node = n
+	c.replaced = true
+}
+
+// Delete deletes the current Node from its containing slice. Neither its
+// children nor post are then visited.
+func (c *Cursor) Delete() {
+	c.inSlice("Delete")
+	c.node = nil
+	c.deleted = true
+}
+
+// InsertBefore inserts n before the current Node in its containing slice.
+// n is not traversed by Apply.
+func (c *Cursor) InsertBefore(n Node) {
+	c.inSlice("InsertBefore")
+	c.check("InsertBefore", n)
+	c.before = append(c.before, n)
+}
+
+// InsertAfter inserts n after the current Node in its containing slice.
+// Nodes inserted after the same node keep the order they were inserted
+// in. n is not traversed by Apply.
+func (c *Cursor) InsertAfter(n Node) {
+	c.inSlice("InsertAfter")
+	c.check("InsertAfter", n)
+	c.after = append(c.after, n)
+}
+
+// field describes where the current node is, as in "Match.Where".
+func (c *Cursor) field() string {
+	if c.parent == nil {
+		return "the root"
+	}
+	return reflect.TypeOf(c.parent).Elem().Name() + "." + c.name
+}
+
+func (c *Cursor) fail(op, msg string) {
+	panic(applyError{fmt.Errorf("ast.Apply: %s in %s: %s", op, c.field(), msg)})
+}
+
+// check fails unless n may go where the current node is.
+func (c *Cursor) check(op string, n Node) {
+	if n == nil || reflect.ValueOf(n).IsNil() {
+		c.fail(op, "node is nil")
+	}
+	if t := reflect.TypeOf(n); !t.AssignableTo(c.typ) {
+		c.fail(op, fmt.Sprintf("cannot use %s as %s", t, c.typ))
+	}
+}
+
+// inSlice fails unless the current node is part of a slice whose length
+// may change.
+func (c *Cursor) inSlice(op string) {
+	switch {
+	case c.index < 0:
+		c.fail(op, "node is not part of a slice")
+	case c.fixed:
+		c.fail(op, "the nodes and relationships of a path must be replaced together")
+	}
+}
+
+// nodes returns what takes the place of the current node.
+func (c *Cursor) nodes() []Node {
+	nodes := c.before
+	if !c.deleted {
+		nodes = append(nodes, c.node)
+	}
+	return append(nodes, c.after...)
+}
+
+type application struct {
+	pre, post ApplyFunc
+	stopped   bool // whether post has returned false
+}
+
+// apply applies pre and post to n, which is found in parent's field name,
+// at index if that is a slice, and has the type typ there. It returns the
+// nodes that take the place of n.
+func (a *application) apply(parent Node, name string, index int, n Node, typ reflect.Type, fixed bool) []Node {
+	c := &Cursor{parent: parent, name: name, index: index, typ: typ, fixed: fixed, node: n}
+	if a.stopped {
+		return c.nodes()
+	}
+	if a.pre != nil && !a.pre(c) {
+		return c.nodes()
+	}
+	if c.deleted {
+		return c.nodes()
+	}
+	if !c.replaced {
+		c.node = a.applyChildren(n)
+	}
+	if a.post != nil && !a.stopped && !a.post(c) {
+		a.stopped = true
+	}
+	return c.nodes()
+}
+
+// applyChildren applies pre and post to the children of n, returning a
+// copy of n if any of them change.
+func (a *application) applyChildren(n Node) Node {
+	if path, ok := n.(*PathPattern); ok {
+		return a.applyPath(path)
+	}
+
+	v := reflect.ValueOf(n).Elem()
+	var cp reflect.Value // pointer to the copy of n, once it is made
+	set := func(i int, x reflect.Value) {
+		if !cp.IsValid() {
+			cp = reflect.New(v.Type())
+			cp.Elem().Set(v)
+		}
+		cp.Elem().Field(i).Set(x)
+	}
+
+	for i := 0; i < v.NumField(); i++ {
+		f, sf := v.Field(i), v.Type().Field(i)
+		switch {
+		case sf.Anonymous:
+			// NodeInfo
+		case f.Kind() == reflect.Slice && f.Type().Elem().Implements(nodeType):
+			if list, changed := a.applyList(n, sf.Name, f, false); changed {
+				set(i, list)
+			}
+		case f.Type().Implements(nodeType) && !f.IsNil():
+			old := f.Interface().(Node)
+			if x := a.apply(n, sf.Name, -1, old, f.Type(), false)[0]; x != old {
+				set(i, reflect.ValueOf(x))
+			}
+		}
+	}
+	if cp.IsValid() {
+		return cp.Interface().(Node)
+	}
+	return n
+}
+
+// applyList applies pre and post to the nodes in list, which is found in
+// parent's field name. It returns the new list, and whether it differs
+// from the old one. If fixed is set, the list must keep its length.
+func (a *application) applyList(parent Node, name string, list reflect.Value, fixed bool) (reflect.Value, bool) {
+	out := reflect.MakeSlice(list.Type(), 0, list.Len())
+	changed := false
+	for i := 0; i < list.Len(); i++ {
+		old := list.Index(i).Interface().(Node)
+		nodes := a.apply(parent, name, i, old, list.Type().Elem(), fixed)
+		if len(nodes) != 1 || nodes[0] != old {
+			changed = true
+		}
+		for _, x := range nodes {
+			out = reflect.Append(out, reflect.ValueOf(x))
+		}
+	}
+	return out, changed
+}
+
+// applyPath is applyChildren for a PathPattern, whose nodes and
+// relationships are visited in turn, as they appear in the path.
+func (a *application) applyPath(path *PathPattern) Node {
+	var cp *PathPattern
+	copied := func() *PathPattern {
+		if cp == nil {
+			c := *path
+			c.Nodes = append([]*NodePattern(nil), path.Nodes...)
+			c.Relationships = append([]*RelationshipPattern(nil), path.Relationships...)
+			cp = &c
+		}
+		return cp
+	}
+
+	if path.Variable != nil {
+		if x := a.apply(path, "Variable", -1, path.Variable, reflect.TypeOf(path.Variable), false)[0]; x != path.Variable {
+			copied().Variable = x.(*Variable)
+		}
+	}
+	for i, node := range path.Nodes {
+		if i > 0 && i-1 < len(path.Relationships) {
+			r := path.Relationships[i-1]
+			if x := a.apply(path, "Relationships", i-1, r, reflect.TypeOf(r), true)[0]; x != r {
+				copied().Relationships[i-1] = x.(*RelationshipPattern)
+			}
+		}
+		if x := a.apply(path, "Nodes", i, node, reflect.TypeOf(node), true)[0]; x != node {
+			copied().Nodes[i] = x.(*NodePattern)
+		}
+	}
+	if cp != nil {
+		return cp
+	}
+	return path
+}
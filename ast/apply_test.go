@@ -0,0 +1,146 @@
+package ast_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/a-poor/cypher/ast"
+)
+
+func TestApplyCopyOnWrite(t *testing.T) {
+	const query = "MATCH (n) WHERE n.age > 18 RETURN n.name, n.age"
+	q := mustParse(t, query)
+	before := texts(q)
+	where := q.Clauses[0].(*ast.Match).Where
+
+	// Replace every n.age with $age.
+	result, err := ast.Apply(q, func(c *ast.Cursor) bool {
+		if p, ok := c.Node().(*ast.PropertyAccess); ok && p.Key == "age" {
+			c.Replace(&ast.Parameter{Name: "age"})
+		}
+		return true
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := texts(q); !reflect.DeepEqual(got, before) {
+		t.Errorf("Apply changed its input:\n%s", strings.Join(got, "\n"))
+	}
+	r := result.(*ast.Query)
+	if r == q || r.Clauses[0] == q.Clauses[0] {
+		t.Error("changed nodes were not copied")
+	}
+	if r.Clauses[0].(*ast.Match).Pattern != q.Clauses[0].(*ast.Match).Pattern {
+		t.Error("unchanged pattern was copied")
+	}
+	if got := r.Clauses[0].(*ast.Match).Where.(*ast.BinaryExpr).X; !isParam(got, "age") {
+		t.Errorf("WHERE operand = %#v, want $age", got)
+	}
+	if got := r.Clauses[1].(*ast.Return).Projection.Items[1].Expr; !isParam(got, "age") {
+		t.Errorf("RETURN item = %#v, want $age", got)
+	}
+	if where.(*ast.BinaryExpr).X.(*ast.PropertyAccess).Key != "age" {
+		t.Error("original WHERE was modified")
+	}
+}
+
+func isParam(x ast.Expr, name string) bool {
+	p, ok := x.(*ast.Parameter)
+	return ok && p.Name == name
+}
+
+func TestApplySlices(t *testing.T) {
+	q := mustParse(t, "MATCH (n) SET n.a = 1, n.b = 2 RETURN n")
+	result, err := ast.Apply(q, func(c *ast.Cursor) bool {
+		switch n := c.Node().(type) {
+		case *ast.SetItem:
+			if c.Index() == 0 {
+				c.Delete()
+			} else {
+				c.InsertAfter(&ast.SetItem{Target: n.Target, Value: &ast.NullLiteral{}})
+			}
+		case *ast.Return:
+			c.InsertBefore(&ast.With{Projection: n.Projection})
+		}
+		return true
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := result.(*ast.Query)
+	if len(r.Clauses) != 4 {
+		t.Fatalf("got %d clauses, want 4", len(r.Clauses))
+	}
+	if _, ok := r.Clauses[2].(*ast.With); !ok {
+		t.Errorf("clause 2 is %T, want *ast.With", r.Clauses[2])
+	}
+	set := r.Clauses[1].(*ast.Set)
+	if len(set.Items) != 2 || set.Items[0].Text() != "n.b = 2" {
+		t.Errorf("SET items = %v", texts(set))
+	}
+	if n := len(q.Clauses[1].(*ast.Set).Items); n != 2 || len(q.Clauses) != 3 {
+		t.Error("Apply changed its input")
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		pre  ast.ApplyFunc
+		want string
+	}{
+		{
+			"clause for expression",
+			func(c *ast.Cursor) bool {
+				if c.Name() == "Where" {
+					c.Replace(&ast.Return{})
+				}
+				return true
+			},
+			"ast.Apply: Replace in Match.Where: cannot use *ast.Return as ast.Expr",
+		},
+		{
+			"expression for clause",
+			func(c *ast.Cursor) bool {
+				if _, ok := c.Node().(*ast.Match); ok {
+					c.InsertAfter(&ast.Variable{Name: "x"})
+				}
+				return true
+			},
+			"ast.Apply: InsertAfter in Query.Clauses: cannot use *ast.Variable as ast.Clause",
+		},
+		{
+			"delete field",
+			func(c *ast.Cursor) bool {
+				if c.Name() == "Where" {
+					c.Delete()
+				}
+				return true
+			},
+			"ast.Apply: Delete in Match.Where: node is not part of a slice",
+		},
+		{
+			"delete from path",
+			func(c *ast.Cursor) bool {
+				if _, ok := c.Node().(*ast.RelationshipPattern); ok {
+					c.Delete()
+				}
+				return true
+			},
+			"ast.Apply: Delete in PathPattern.Relationships: the nodes and relationships of a path must be replaced together",
+		},
+	}
+	for _, tt := range tests {
+		q := mustParse(t, "MATCH (a)-->(b) WHERE a.x = 1 RETURN a")
+		result, err := ast.Apply(q, tt.pre, nil)
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("%s: error = %v, want %q", tt.name, err, tt.want)
+		}
+		if result != q {
+			t.Errorf("%s: did not return the original tree", tt.name)
+		}
+	}
+}
@@ -1,12 +1,48 @@
 package ast
 
+// Node is implemented by every node of the tree.
 type Node interface {
 	// Text returns the the text of the node.
 	Text() string
+
+	// Span returns the range of source text the node was parsed from.
+	Span() Span
 }
 
-type Visitor interface {
+// NodeInfo holds the fields shared by every node. It is embedded in each of
+// the node types.
+type NodeInfo struct {
+	// Raw is the source text the node was parsed from. It is empty for
+	// nodes that were built by hand.
+	Raw string
+
+	// Loc is the range of source text the node was parsed from. It is the
+	// zero Span for nodes that were built by hand. A node that was parsed
+	// from no text at all, such as a missing expression, has an empty Loc
+	// at the place it should have been.
+	Loc Span
+
+	// Comments holds the comments next to the node in the source text.
+	// Parse leaves it empty; it is filled in when the tree is built from a
+	// concrete syntax tree, as by ParseCST.
+	Comments []*Comment
 }
 
-type CypherQuery struct {
+// Text returns the source text of the node.
+func (n *NodeInfo) Text() string { return n.Raw }
+
+// Span returns the range of source text the node was parsed from.
+func (n *NodeInfo) Span() Span { return n.Loc }
+
+// Query is the root of a parsed statement.
+//
+// A Query may be shared between goroutines, as it is by cypher.Cache, so
+// a tree that did not come straight from a parse must be treated as
+// read-only. The functions in this package never modify a tree.
+type Query struct {
+	NodeInfo
+
+	// Clauses lists the clauses of the statement in source order. The
+	// parts of a UNION are separated by a *Union clause.
+	Clauses []Clause
 }
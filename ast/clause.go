@@ -0,0 +1,239 @@
+package ast
+
+// Clause is implemented by every node that can appear in Query.Clauses.
+type Clause interface {
+	Node
+	clauseNode()
+}
+
+// BadClause stands in for source text that could not be parsed as a clause.
+type BadClause struct {
+	NodeInfo
+}
+
+// Reading clauses
+
+// Match is a MATCH or OPTIONAL MATCH clause.
+type Match struct {
+	NodeInfo
+
+	Optional bool
+	Pattern  *Pattern
+	Where    Expr // nil if there is no WHERE
+}
+
+// Unwind is an UNWIND clause, as in "UNWIND list AS x".
+type Unwind struct {
+	NodeInfo
+
+	Expr     Expr
+	Variable *Variable
+}
+
+// InQueryCall is a CALL of a procedure in the middle of a query, as in
+// "CALL db.labels() YIELD label".
+type InQueryCall struct {
+	NodeInfo
+
+	// Procedure is the name of the procedure, including its namespace,
+	// as in "db.labels".
+	Procedure string
+	Args      []Expr
+
+	Yield []*YieldItem // nil if there is no YIELD
+	Where Expr         // nil if there is no WHERE
+}
+
+// StandaloneCall is a CALL of a procedure that makes up a whole statement.
+// Unlike an InQueryCall, it may leave out the arguments, which are then
+// taken from the parameters of the same names, and may yield "*".
+type StandaloneCall struct {
+	NodeInfo
+
+	// Procedure is the name of the procedure, including its namespace,
+	// as in "db.labels".
+	Procedure string
+	Args      []Expr
+
+	// Implicit is set if the arguments are left out, parentheses and all.
+	Implicit bool
+
+	YieldAll bool         // YIELD *
+	Yield    []*YieldItem // nil if there is no YIELD, or for YIELD *
+	Where    Expr         // nil if there is no WHERE
+}
+
+// YieldItem is a field yielded by a procedure, as in "label" or
+// "label AS l".
+type YieldItem struct {
+	NodeInfo
+
+	// Field is the name of the procedure's result field, if it is given
+	// a variable of a different name with AS. It is empty otherwise, and
+	// the field has the name of the variable.
+	Field    string
+	Variable *Variable
+}
+
+// Updating clauses
+
+// Create is a CREATE clause.
+type Create struct {
+	NodeInfo
+
+	Pattern *Pattern
+}
+
+// Merge is a MERGE clause, with the actions to take when the pattern is
+// created or matched.
+type Merge struct {
+	NodeInfo
+
+	Pattern *PathPattern
+	Actions []*MergeAction
+}
+
+// MergeAction is an ON CREATE SET or ON MATCH SET of a MERGE clause.
+type MergeAction struct {
+	NodeInfo
+
+	OnCreate bool // ON CREATE rather than ON MATCH
+	Set      *Set
+}
+
+// Set is a SET clause.
+type Set struct {
+	NodeInfo
+
+	Items []*SetItem
+}
+
+// SetOp is the operation of a SetItem.
+type SetOp int
+
+const (
+	SetAssign SetOp = iota // "n.x = value" or "n = map"
+	SetMerge               // "n += map"
+	SetLabels              // "n:Label"
+)
+
+func (op SetOp) String() string {
+	switch op {
+	case SetAssign:
+		return "="
+	case SetMerge:
+		return "+="
+	case SetLabels:
+		return ":"
+	}
+	return "SetOp(?)"
+}
+
+// SetItem is a single change made by a SET clause.
+type SetItem struct {
+	NodeInfo
+
+	// Target is the *PropertyAccess being set, as in "n.name = 'x'", or
+	// else the *Variable of the node or relationship being changed.
+	Target Expr
+	Op     SetOp
+	Value  Expr     // nil for SetLabels
+	Labels []string // for SetLabels only
+}
+
+// Remove is a REMOVE clause.
+type Remove struct {
+	NodeInfo
+
+	Items []*RemoveItem
+}
+
+// RemoveItem is a property or labels removed by a REMOVE clause.
+type RemoveItem struct {
+	NodeInfo
+
+	// Target is the *PropertyAccess to remove, as in "n.name", or else
+	// the *Variable of the node to remove Labels from.
+	Target Expr
+	Labels []string // nil when removing a property
+}
+
+// Delete is a DELETE or DETACH DELETE clause.
+type Delete struct {
+	NodeInfo
+
+	Detach bool
+	Exprs  []Expr
+}
+
+// Projections
+
+// With is a WITH clause.
+type With struct {
+	NodeInfo
+
+	Projection *Projection
+	Where      Expr // nil if there is no WHERE
+}
+
+// Return is a RETURN clause.
+type Return struct {
+	NodeInfo
+
+	Projection *Projection
+}
+
+// Projection is what follows WITH or RETURN: the items to project, and how
+// to order, skip and limit the results.
+type Projection struct {
+	NodeInfo
+
+	Distinct bool
+
+	// Star is set if the projection starts with "*", which projects every
+	// variable in scope. Items then lists any further items.
+	Star  bool
+	Items []*ProjectionItem
+
+	OrderBy []*SortItem // nil if there is no ORDER BY
+	Skip    Expr        // nil if there is no SKIP
+	Limit   Expr        // nil if there is no LIMIT
+}
+
+// ProjectionItem is an expression projected by WITH or RETURN, as in
+// "n.name AS name".
+type ProjectionItem struct {
+	NodeInfo
+
+	Expr  Expr
+	Alias *Variable // nil if there is no AS
+}
+
+// SortItem is an expression in an ORDER BY.
+type SortItem struct {
+	NodeInfo
+
+	Expr       Expr
+	Descending bool // DESC or DESCENDING
+}
+
+// Union separates the parts of a query combined with UNION or UNION ALL.
+type Union struct {
+	NodeInfo
+
+	All bool
+}
+
+func (*BadClause) clauseNode()      {}
+func (*Match) clauseNode()          {}
+func (*Unwind) clauseNode()         {}
+func (*InQueryCall) clauseNode()    {}
+func (*StandaloneCall) clauseNode() {}
+func (*Create) clauseNode()         {}
+func (*Merge) clauseNode()          {}
+func (*Set) clauseNode()            {}
+func (*Remove) clauseNode()         {}
+func (*Delete) clauseNode()         {}
+func (*With) clauseNode()           {}
+func (*Return) clauseNode()         {}
+func (*Union) clauseNode()          {}
@@ -0,0 +1,141 @@
+package ast_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/a-poor/cypher"
+	"github.com/a-poor/cypher/ast"
+)
+
+// Helpers for building trees by hand, to compare parsed ones with.
+
+func variable(name string) *ast.Variable { return &ast.Variable{Name: name} }
+
+func integer(n int64) *ast.IntegerLiteral {
+	return &ast.IntegerLiteral{Literal: strconv.FormatInt(n, 10), Value: n}
+}
+
+func str(literal, value string) *ast.StringLiteral {
+	return &ast.StringLiteral{Literal: literal, Value: value}
+}
+
+func property(x ast.Expr, key string) *ast.PropertyAccess {
+	return &ast.PropertyAccess{X: x, Key: key}
+}
+
+func node(name string, labels ...string) *ast.NodePattern {
+	n := &ast.NodePattern{Labels: labels}
+	if name != "" {
+		n.Variable = variable(name)
+	}
+	return n
+}
+
+func items(exprs ...ast.Expr) []*ast.ProjectionItem {
+	var items []*ast.ProjectionItem
+	for _, x := range exprs {
+		items = append(items, &ast.ProjectionItem{Expr: x})
+	}
+	return items
+}
+
+// checkShape parses a query and compares it with want, leaving out spans.
+func checkShape(t *testing.T, query string, want *ast.Query) {
+	t.Helper()
+	q, err := cypher.Parse(query)
+	if err != nil {
+		t.Errorf("%s: %v", query, err)
+		return
+	}
+	if !ast.Equal(q, want, ast.IgnoreSpans) {
+		got, _ := ast.MarshalJSON(q)
+		t.Errorf("%s: got\n%s", query, got)
+	}
+}
+
+func TestClauseShapes(t *testing.T) {
+	n := variable("n")
+	tests := []struct {
+		query string
+		want  []ast.Clause
+	}{
+		{
+			"OPTIONAL MATCH (n:Person) WHERE n.age > 1 RETURN DISTINCT n.name AS name ORDER BY name DESC SKIP 1 LIMIT 2",
+			[]ast.Clause{
+				&ast.Match{
+					Optional: true,
+					Pattern:  &ast.Pattern{Paths: []*ast.PathPattern{{Nodes: []*ast.NodePattern{node("n", "Person")}}}},
+					Where:    &ast.BinaryExpr{X: property(n, "age"), Op: ast.OpGt, Y: integer(1)},
+				},
+				&ast.Return{Projection: &ast.Projection{
+					Distinct: true,
+					Items:    []*ast.ProjectionItem{{Expr: property(n, "name"), Alias: variable("name")}},
+					OrderBy:  []*ast.SortItem{{Expr: variable("name"), Descending: true}},
+					Skip:     integer(1),
+					Limit:    integer(2),
+				}},
+			},
+		},
+		{
+			"UNWIND [1] AS x WITH *, x AS y WHERE y > 0 RETURN y UNION ALL RETURN 2 AS y",
+			[]ast.Clause{
+				&ast.Unwind{Expr: &ast.ListLiteral{Elems: []ast.Expr{integer(1)}}, Variable: variable("x")},
+				&ast.With{
+					Projection: &ast.Projection{Star: true, Items: []*ast.ProjectionItem{{Expr: variable("x"), Alias: variable("y")}}},
+					Where:      &ast.BinaryExpr{X: variable("y"), Op: ast.OpGt, Y: integer(0)},
+				},
+				&ast.Return{Projection: &ast.Projection{Items: items(variable("y"))}},
+				&ast.Union{All: true},
+				&ast.Return{Projection: &ast.Projection{Items: []*ast.ProjectionItem{{Expr: integer(2), Alias: variable("y")}}}},
+			},
+		},
+		{
+			"CREATE (n) MERGE (n)-->(m) ON CREATE SET n.x = 1 ON MATCH SET n += {}, n:A:B REMOVE n.x, n:A DETACH DELETE n, m",
+			[]ast.Clause{
+				&ast.Create{Pattern: &ast.Pattern{Paths: []*ast.PathPattern{{Nodes: []*ast.NodePattern{node("n")}}}}},
+				&ast.Merge{
+					Pattern: &ast.PathPattern{
+						Nodes:         []*ast.NodePattern{node("n"), node("m")},
+						Relationships: []*ast.RelationshipPattern{{Direction: ast.DirectionRight}},
+					},
+					Actions: []*ast.MergeAction{
+						{OnCreate: true, Set: &ast.Set{Items: []*ast.SetItem{{Target: property(n, "x"), Op: ast.SetAssign, Value: integer(1)}}}},
+						{Set: &ast.Set{Items: []*ast.SetItem{
+							{Target: n, Op: ast.SetMerge, Value: &ast.MapLiteral{}},
+							{Target: n, Op: ast.SetLabels, Labels: []string{"A", "B"}},
+						}}},
+					},
+				},
+				&ast.Remove{Items: []*ast.RemoveItem{{Target: property(n, "x")}, {Target: n, Labels: []string{"A"}}}},
+				&ast.Delete{Detach: true, Exprs: []ast.Expr{n, variable("m")}},
+			},
+		},
+		{
+			"CALL db.labels() YIELD label AS l, x WHERE l <> '' RETURN l",
+			[]ast.Clause{
+				&ast.InQueryCall{
+					Procedure: "db.labels",
+					Yield:     []*ast.YieldItem{{Field: "label", Variable: variable("l")}, {Variable: variable("x")}},
+					Where:     &ast.BinaryExpr{X: variable("l"), Op: ast.OpNeq, Y: str("''", "")},
+				},
+				&ast.Return{Projection: &ast.Projection{Items: items(variable("l"))}},
+			},
+		},
+		{
+			"CALL db.ping",
+			[]ast.Clause{&ast.StandaloneCall{Procedure: "db.ping", Implicit: true}},
+		},
+		{
+			"CALL my.proc(1, $p) YIELD *",
+			[]ast.Clause{&ast.StandaloneCall{
+				Procedure: "my.proc",
+				Args:      []ast.Expr{integer(1), &ast.Parameter{Name: "p"}},
+				YieldAll:  true,
+			}},
+		},
+	}
+	for _, tt := range tests {
+		checkShape(t, tt.query, &ast.Query{Clauses: tt.want})
+	}
+}
@@ -0,0 +1,50 @@
+package ast
+
+import "reflect"
+
+// Clone returns a deep copy of a tree, which shares nothing with it: every
+// node, slice, comment and hop count is copied. Trees that are shared, such
+// as those from cypher.Cache, may then be changed in place through the
+// copy. To change a few nodes of a large tree, Apply, which only copies
+// what it changes, is cheaper.
+func Clone(n Node) Node {
+	if n == nil {
+		return nil
+	}
+	return cloneValue(reflect.ValueOf(n)).Interface().(Node)
+}
+
+func cloneValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(cloneValue(v.Elem()))
+		return c
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type().Elem())
+		c.Elem().Set(cloneValue(v.Elem()))
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(cloneValue(v.Index(i)))
+		}
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.NumField(); i++ {
+			c.Field(i).Set(cloneValue(v.Field(i)))
+		}
+		return c
+	}
+	return v
+}
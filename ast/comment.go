@@ -0,0 +1,14 @@
+package ast
+
+// Comment is a "//" or "/* */" comment in the source text.
+type Comment struct {
+	// Raw is the text of the comment, including the comment markers but
+	// not the line break after a "//" comment.
+	Raw string
+
+	Loc Span
+
+	// Trailing is set for a comment that comes after its node, either on
+	// the same line or at the end of the text, rather than before it.
+	Trailing bool
+}
@@ -0,0 +1,222 @@
+package ast
+
+import (
+	"encoding/binary"
+	"hash"
+	"hash/fnv"
+	"math"
+	"reflect"
+)
+
+// Ignore says which parts of the nodes Equal and Hash leave out of the
+// comparison.
+type Ignore int
+
+const (
+	// IgnoreSpans leaves out the source text and span of each node, and
+	// of each comment, so that trees parsed from text that only differs
+	// in whitespace compare equal.
+	IgnoreSpans Ignore = 1 << iota
+
+	// IgnoreComments leaves out the comments of each node.
+	IgnoreComments
+)
+
+// Equal reports whether two trees have nodes of the same types, with the
+// same fields, apart from those left out by ignore. A nil slice is equal
+// to an empty one.
+//
+// Fields are compared as they are, so literals written differently, as
+// "42" and "0x2a", and names in different case, as "count" and "COUNT",
+// are not equal, even though they mean the same.
+func Equal(a, b Node, ignore Ignore) bool {
+	return equalValues(reflect.ValueOf(a), reflect.ValueOf(b), ignore)
+}
+
+func equalValues(a, b reflect.Value, ignore Ignore) bool {
+	if a.IsValid() != b.IsValid() {
+		return false
+	}
+	if !a.IsValid() {
+		return true
+	}
+	if a.Kind() == reflect.Interface {
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+		a, b = a.Elem(), b.Elem()
+	}
+	if a.Type() != b.Type() {
+		return false
+	}
+
+	switch a.Kind() {
+	case reflect.Ptr:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+		if a.Pointer() == b.Pointer() {
+			return true
+		}
+		return equalValues(a.Elem(), b.Elem(), ignore)
+	case reflect.Slice:
+		if a.Len() != b.Len() {
+			return false
+		}
+		for i := 0; i < a.Len(); i++ {
+			if !equalValues(a.Index(i), b.Index(i), ignore) {
+				return false
+			}
+		}
+		return true
+	case reflect.Struct:
+		if a.Type() == nodeInfoType {
+			return equalInfo(a.Addr().Interface().(*NodeInfo), b.Addr().Interface().(*NodeInfo), ignore)
+		}
+		for i := 0; i < a.NumField(); i++ {
+			if !equalValues(a.Field(i), b.Field(i), ignore) {
+				return false
+			}
+		}
+		return true
+	case reflect.Float64:
+		return a.Float() == b.Float()
+	}
+	return a.Interface() == b.Interface()
+}
+
+var nodeInfoType = reflect.TypeOf(NodeInfo{})
+
+func equalInfo(a, b *NodeInfo, ignore Ignore) bool {
+	if ignore&IgnoreSpans == 0 && (a.Raw != b.Raw || a.Loc != b.Loc) {
+		return false
+	}
+	if ignore&IgnoreComments != 0 {
+		return true
+	}
+	if len(a.Comments) != len(b.Comments) {
+		return false
+	}
+	for i, c := range a.Comments {
+		d := b.Comments[i]
+		if c.Raw != d.Raw || c.Trailing != d.Trailing {
+			return false
+		}
+		if ignore&IgnoreSpans == 0 && c.Loc != d.Loc {
+			return false
+		}
+	}
+	return true
+}
+
+// Hash returns a 128-bit hash of a tree, leaving out the parts of the nodes
+// given by ignore. Trees that are Equal with the same ignore have the same
+// hash, and trees that are not almost certainly do not. Either half of the
+// hash may be used on its own where 64 bits are enough.
+//
+// The hash is stable: it depends only on the tree, and not on the process
+// or platform computing it, so it may be stored. It may change in new
+// versions of this package, if the node types change.
+func Hash(n Node, ignore Ignore) [16]byte {
+	h := &hasher{h: fnv.New128a(), ignore: ignore}
+	h.value(reflect.ValueOf(n))
+	var sum [16]byte
+	h.h.Sum(sum[:0])
+	return sum
+}
+
+type hasher struct {
+	h      hash.Hash
+	ignore Ignore
+	buf    [8]byte
+}
+
+func (h *hasher) uint(x uint64) {
+	binary.LittleEndian.PutUint64(h.buf[:], x)
+	h.h.Write(h.buf[:])
+}
+
+func (h *hasher) string(s string) {
+	h.uint(uint64(len(s)))
+	h.h.Write([]byte(s))
+}
+
+func (h *hasher) span(s Span) {
+	for _, p := range []Position{s.Start, s.End} {
+		h.uint(uint64(p.Offset))
+		h.uint(uint64(p.Line))
+		h.uint(uint64(p.Column))
+		h.uint(uint64(p.UTF16Column))
+	}
+}
+
+// value hashes v, with a prefix for each kind of value so that different
+// trees do not hash the same bytes.
+func (h *hasher) value(v reflect.Value) {
+	if !v.IsValid() || (v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr) && v.IsNil() {
+		h.uint(0)
+		return
+	}
+	switch v.Kind() {
+	case reflect.Interface:
+		h.value(v.Elem())
+	case reflect.Ptr:
+		if v.Type().Implements(nodeType) {
+			// The type name tells nodes apart, even those with no
+			// fields.
+			h.string(v.Type().Elem().Name())
+		} else {
+			h.uint(1)
+		}
+		h.value(v.Elem())
+	case reflect.Slice:
+		h.uint(uint64(v.Len()))
+		for i := 0; i < v.Len(); i++ {
+			h.value(v.Index(i))
+		}
+	case reflect.Struct:
+		if v.Type() == nodeInfoType {
+			h.info(v.Addr().Interface().(*NodeInfo))
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			h.value(v.Field(i))
+		}
+	case reflect.String:
+		h.string(v.String())
+	case reflect.Bool:
+		if v.Bool() {
+			h.uint(1)
+		} else {
+			h.uint(0)
+		}
+	case reflect.Int, reflect.Int64:
+		h.uint(uint64(v.Int()))
+	case reflect.Float64:
+		f := v.Float()
+		if f == 0 {
+			f = 0 // as -0 == 0
+		}
+		h.uint(math.Float64bits(f))
+	default:
+		panic("ast.Hash: unexpected " + v.Type().String())
+	}
+}
+
+func (h *hasher) info(info *NodeInfo) {
+	if h.ignore&IgnoreSpans == 0 {
+		h.string(info.Raw)
+		h.span(info.Loc)
+	}
+	if h.ignore&IgnoreComments != 0 {
+		return
+	}
+	h.uint(uint64(len(info.Comments)))
+	for _, c := range info.Comments {
+		h.string(c.Raw)
+		h.value(reflect.ValueOf(c.Trailing))
+		if h.ignore&IgnoreSpans == 0 {
+			h.span(c.Loc)
+		}
+	}
+}
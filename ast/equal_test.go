@@ -0,0 +1,87 @@
+package ast_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/a-poor/cypher"
+	"github.com/a-poor/cypher/ast"
+)
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		a, b   string
+		ignore ast.Ignore
+		want   bool
+	}{
+		{"MATCH (n) RETURN n", "MATCH (n) RETURN n", 0, true},
+		{"MATCH (n) RETURN n", "MATCH  (n)\nRETURN n", 0, false},
+		{"MATCH (n) RETURN n", "MATCH  (n)\nRETURN n", ast.IgnoreSpans, true},
+		{"MATCH (n) RETURN n", "MATCH (m) RETURN m", ast.IgnoreSpans, false},
+		{"MATCH (n) RETURN n", "MATCH (n) RETURN n // all", ast.IgnoreSpans, false},
+		{"MATCH (n) RETURN n", "MATCH (n) RETURN n // all", ast.IgnoreSpans | ast.IgnoreComments, true},
+		{"RETURN 1 + 2 * 3", "RETURN (1 + 2) * 3", ast.IgnoreSpans, false},
+		{"RETURN (1 + 2) * 3", "RETURN (1+2)*3", ast.IgnoreSpans, true},
+		{"RETURN 42", "RETURN 0x2a", ast.IgnoreSpans, false},
+		{"RETURN 0.0", "RETURN -0.0", ast.IgnoreSpans, false},
+		{"MATCH (a)-->(b) RETURN a", "MATCH (a)<--(b) RETURN a", ast.IgnoreSpans, false},
+	}
+	for _, tt := range tests {
+		a, b := parseWithComments(t, tt.a), parseWithComments(t, tt.b)
+		if got := ast.Equal(a, b, tt.ignore); got != tt.want {
+			t.Errorf("Equal(%q, %q, %d) = %v, want %v", tt.a, tt.b, tt.ignore, got, tt.want)
+		}
+		if got := ast.Hash(a, tt.ignore) == ast.Hash(b, tt.ignore); got != tt.want {
+			t.Errorf("hashes of %q and %q equal = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func parseWithComments(t *testing.T, query string) ast.Node {
+	t.Helper()
+	tree, err := cypher.ParseCST(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tree.AST()
+}
+
+// The hash must not change from one run or platform to another.
+func TestHashStable(t *testing.T) {
+	q := mustParse(t, "MATCH (n:Person {name: $name})-[:KNOWS*1..2]->(m) RETURN m.name, 1.5")
+	const want = "8ab8f9ea8bdb824657ecc6120243c76d"
+	if got := fmt.Sprintf("%x", ast.Hash(q, ast.IgnoreSpans)); got != want {
+		t.Errorf("got hash %s, want %s", got, want)
+	}
+}
+
+func TestClone(t *testing.T) {
+	for name, query := range corpus(t) {
+		tree, _ := cypher.ParseCST(query)
+		q := tree.AST()
+		c := ast.Clone(q)
+		if !reflect.DeepEqual(c, q) || !ast.Equal(c, q, 0) {
+			t.Errorf("%s: clone differs", name)
+		}
+		if ast.Hash(c, 0) != ast.Hash(q, 0) {
+			t.Errorf("%s: clone has a different hash", name)
+		}
+
+		// No node of the clone may be one of the original's.
+		orig := map[ast.Node]bool{}
+		ast.Inspect(q, func(n ast.Node) bool {
+			orig[n] = n != nil
+			return true
+		})
+		ast.Inspect(c, func(n ast.Node) bool {
+			if orig[n] {
+				t.Errorf("%s: %T shared with the original", name, n)
+			}
+			return true
+		})
+	}
+	if ast.Clone(nil) != nil {
+		t.Error("Clone(nil) is not nil")
+	}
+}
@@ -0,0 +1,412 @@
+package ast
+
+// Expr is implemented by every expression node.
+//
+// Expressions are flat: each node stands for an operator or an atom, and
+// there are no nodes for the grammar's levels of precedence, nor for
+// parentheses, which only show in how the nodes are nested.
+type Expr interface {
+	Node
+	exprNode()
+}
+
+// BadExpr stands in for source text that could not be parsed as an
+// expression.
+type BadExpr struct {
+	NodeInfo
+}
+
+// Variable is a reference to, or the declaration of, a variable.
+type Variable struct {
+	NodeInfo
+
+	Name string
+}
+
+// Parameter is a query parameter, as in "$name" or "$0".
+type Parameter struct {
+	NodeInfo
+
+	Name string // without the "$"
+}
+
+// Literals
+
+// IntegerLiteral is an integer, in decimal, hexadecimal or octal, as in
+// "42", "0x2a" or "052".
+//
+// Integers are written without a sign, so Parse reports those larger than
+// math.MaxInt64 as out of range, except for 9223372036854775808 right after
+// a unary minus, as in "-9223372036854775808". Its Value is then
+// math.MinInt64, which the minus leaves as it is, since integers wrap
+// around on overflow.
+type IntegerLiteral struct {
+	NodeInfo
+
+	Literal string // as written
+	Value   int64
+}
+
+// FloatLiteral is a floating point number, as in "1.5" or "1e-3".
+type FloatLiteral struct {
+	NodeInfo
+
+	Literal string // as written
+	Value   float64
+}
+
+// StringLiteral is a string, as in "it's" or 'a\tb'.
+type StringLiteral struct {
+	NodeInfo
+
+	Literal string // as written, with quotes and escape sequences
+	Value   string // with the quotes removed and escape sequences decoded
+}
+
+// BooleanLiteral is TRUE or FALSE.
+type BooleanLiteral struct {
+	NodeInfo
+
+	Value bool
+}
+
+// NullLiteral is NULL.
+type NullLiteral struct {
+	NodeInfo
+}
+
+// ListLiteral is a list, as in "[1, 2, 3]".
+type ListLiteral struct {
+	NodeInfo
+
+	Elems []Expr
+}
+
+// MapLiteral is a map, as in "{name: 'x', age: 42}".
+type MapLiteral struct {
+	NodeInfo
+
+	Entries []*MapEntry
+}
+
+// MapEntry is a key and its value in a MapLiteral.
+type MapEntry struct {
+	NodeInfo
+
+	Key   string
+	Value Expr
+}
+
+// Operators
+
+// BinaryOp is the operator of a BinaryExpr.
+type BinaryOp int
+
+const (
+	OpOr  BinaryOp = iota // OR
+	OpXor                 // XOR
+	OpAnd                 // AND
+	OpEq                  // =
+	OpNeq                 // <>
+	OpLt                  // <
+	OpGt                  // >
+	OpLe                  // <=
+	OpGe                  // >=
+	OpAdd                 // +
+	OpSub                 // -
+	OpMul                 // *
+	OpDiv                 // /
+	OpMod                 // %
+	OpPow                 // ^
+)
+
+var binaryOps = [...]string{
+	OpOr:  "OR",
+	OpXor: "XOR",
+	OpAnd: "AND",
+	OpEq:  "=",
+	OpNeq: "<>",
+	OpLt:  "<",
+	OpGt:  ">",
+	OpLe:  "<=",
+	OpGe:  ">=",
+	OpAdd: "+",
+	OpSub: "-",
+	OpMul: "*",
+	OpDiv: "/",
+	OpMod: "%",
+	OpPow: "^",
+}
+
+func (op BinaryOp) String() string {
+	if op < 0 || int(op) >= len(binaryOps) {
+		return "BinaryOp(?)"
+	}
+	return binaryOps[op]
+}
+
+// BinaryExpr is an expression with a binary operator, as in "a + b".
+//
+// Operators of the same precedence nest to the left, so "a - b - c" is
+// (a - b) - c. That includes chains of comparisons, even though Cypher
+// evaluates "a < b < c" as "a < b AND b < c".
+type BinaryExpr struct {
+	NodeInfo
+
+	Op   BinaryOp
+	X, Y Expr
+}
+
+// UnaryOp is the operator of a UnaryExpr.
+type UnaryOp int
+
+const (
+	OpNot   UnaryOp = iota // NOT
+	OpPlus                 // +
+	OpMinus                // -
+)
+
+func (op UnaryOp) String() string {
+	switch op {
+	case OpNot:
+		return "NOT"
+	case OpPlus:
+		return "+"
+	case OpMinus:
+		return "-"
+	}
+	return "UnaryOp(?)"
+}
+
+// UnaryExpr is an expression with a prefix operator, as in "NOT x" or
+// "-x".
+type UnaryExpr struct {
+	NodeInfo
+
+	Op UnaryOp
+	X  Expr
+}
+
+// StringOp is the operator of a StringPredicate.
+type StringOp int
+
+const (
+	StartsWith StringOp = iota // STARTS WITH
+	EndsWith                   // ENDS WITH
+	Contains                   // CONTAINS
+
+	// RegexMatch is "=~". It is not part of the openCypher grammar
+	// the parser follows, so Parse never produces it, but it may be
+	// used in trees built by hand.
+	RegexMatch
+)
+
+func (op StringOp) String() string {
+	switch op {
+	case StartsWith:
+		return "STARTS WITH"
+	case EndsWith:
+		return "ENDS WITH"
+	case Contains:
+		return "CONTAINS"
+	case RegexMatch:
+		return "=~"
+	}
+	return "StringOp(?)"
+}
+
+// StringPredicate tests a string against another, as in
+// "name STARTS WITH 'A'".
+type StringPredicate struct {
+	NodeInfo
+
+	Op   StringOp
+	X, Y Expr
+}
+
+// IsNull is "x IS NULL", or "x IS NOT NULL" if Not is set.
+type IsNull struct {
+	NodeInfo
+
+	X   Expr
+	Not bool
+}
+
+// In tests whether a list holds a value, as in "x IN [1, 2]".
+type In struct {
+	NodeInfo
+
+	X    Expr
+	List Expr
+}
+
+// Lookups
+
+// PropertyAccess is a property lookup, as in "n.name".
+type PropertyAccess struct {
+	NodeInfo
+
+	X   Expr
+	Key string
+}
+
+// HasLabels tests whether a node has labels, as in "n:Person".
+type HasLabels struct {
+	NodeInfo
+
+	X      Expr
+	Labels []string
+}
+
+// IndexExpr is an element of a list or map, as in "xs[0]".
+type IndexExpr struct {
+	NodeInfo
+
+	X     Expr
+	Index Expr
+}
+
+// SliceExpr is a range of a list, as in "xs[1..3]".
+type SliceExpr struct {
+	NodeInfo
+
+	X    Expr
+	Low  Expr // nil if omitted, as in "xs[..3]"
+	High Expr // nil if omitted, as in "xs[1..]"
+}
+
+// Compound expressions
+
+// CaseExpr is a CASE expression. Subject is nil for the generic form,
+// where each WHEN holds a condition, and set for the simple form, where
+// each WHEN holds a value to compare the subject with.
+type CaseExpr struct {
+	NodeInfo
+
+	Subject Expr
+	Whens   []*CaseWhen
+	Else    Expr // nil if there is no ELSE
+}
+
+// CaseWhen is a WHEN ... THEN ... of a CaseExpr.
+type CaseWhen struct {
+	NodeInfo
+
+	When Expr
+	Then Expr
+}
+
+// FunctionCall is a call of a function, as in "count(DISTINCT n)".
+type FunctionCall struct {
+	NodeInfo
+
+	// Name is the name of the function, including its namespace, as in
+	// "apoc.coll.sum". It is as written: function names are matched
+	// without regard to case.
+	Name     string
+	Distinct bool
+	Args     []Expr
+}
+
+// CountStar is "count(*)".
+type CountStar struct {
+	NodeInfo
+}
+
+// FilterKind says which of the elements of a list a FilterExpr requires
+// to match.
+type FilterKind int
+
+const (
+	FilterAll    FilterKind = iota // ALL
+	FilterAny                      // ANY
+	FilterNone                     // NONE
+	FilterSingle                   // SINGLE
+)
+
+func (k FilterKind) String() string {
+	switch k {
+	case FilterAll:
+		return "ALL"
+	case FilterAny:
+		return "ANY"
+	case FilterNone:
+		return "NONE"
+	case FilterSingle:
+		return "SINGLE"
+	}
+	return "FilterKind(?)"
+}
+
+// FilterExpr tests the elements of a list, as in
+// "all(x IN xs WHERE x > 0)".
+type FilterExpr struct {
+	NodeInfo
+
+	Kind     FilterKind
+	Variable *Variable
+	List     Expr
+	Where    Expr // nil if there is no WHERE
+}
+
+// ListComprehension builds a list from another, as in
+// "[x IN xs WHERE x > 0 | x * 2]".
+type ListComprehension struct {
+	NodeInfo
+
+	Variable *Variable
+	List     Expr
+	Where    Expr // nil if there is no WHERE
+	Result   Expr // nil if there is no "|"
+}
+
+// PatternComprehension builds a list from the matches of a pattern, as in
+// "[(a)-->(b) WHERE b.x > 0 | b.name]".
+type PatternComprehension struct {
+	NodeInfo
+
+	// Pattern is the pattern to match. Its Variable is set if the path is
+	// given a name, as in "[p = (a)-->(b) | p]".
+	Pattern *PathPattern
+	Where   Expr // nil if there is no WHERE
+	Result  Expr
+}
+
+// ExistsSubquery is an EXISTS { ... } expression. It holds either a whole
+// query, or a pattern with an optional WHERE.
+type ExistsSubquery struct {
+	NodeInfo
+
+	Query *Query // nil if the subquery is a pattern
+
+	Pattern *Pattern // nil if the subquery is a query
+	Where   Expr     // nil if there is no WHERE
+}
+
+func (*BadExpr) exprNode()              {}
+func (*Variable) exprNode()             {}
+func (*Parameter) exprNode()            {}
+func (*IntegerLiteral) exprNode()       {}
+func (*FloatLiteral) exprNode()         {}
+func (*StringLiteral) exprNode()        {}
+func (*BooleanLiteral) exprNode()       {}
+func (*NullLiteral) exprNode()          {}
+func (*ListLiteral) exprNode()          {}
+func (*MapLiteral) exprNode()           {}
+func (*BinaryExpr) exprNode()           {}
+func (*UnaryExpr) exprNode()            {}
+func (*StringPredicate) exprNode()      {}
+func (*IsNull) exprNode()               {}
+func (*In) exprNode()                   {}
+func (*PropertyAccess) exprNode()       {}
+func (*HasLabels) exprNode()            {}
+func (*IndexExpr) exprNode()            {}
+func (*SliceExpr) exprNode()            {}
+func (*CaseExpr) exprNode()             {}
+func (*FunctionCall) exprNode()         {}
+func (*CountStar) exprNode()            {}
+func (*FilterExpr) exprNode()           {}
+func (*ListComprehension) exprNode()    {}
+func (*PatternComprehension) exprNode() {}
+func (*ExistsSubquery) exprNode()       {}
+func (*PathPattern) exprNode()          {}
@@ -0,0 +1,102 @@
+package ast_test
+
+import (
+	"testing"
+
+	"github.com/a-poor/cypher/ast"
+)
+
+func TestExprShapes(t *testing.T) {
+	a, b, c := variable("a"), variable("b"), variable("c")
+	x, xs := variable("x"), variable("xs")
+	tests := []struct {
+		expr string
+		want ast.Expr
+	}{
+		// Literals carry their text and their value.
+		{"0x1F", &ast.IntegerLiteral{Literal: "0x1F", Value: 31}},
+		{"1.5e3", &ast.FloatLiteral{Literal: "1.5e3", Value: 1500}},
+		{`'a\tb'`, str(`'a\tb'`, "a\tb")},
+		{"true", &ast.BooleanLiteral{Value: true}},
+		{"null", &ast.NullLiteral{}},
+		{"$p", &ast.Parameter{Name: "p"}},
+		{"{k: 1}", &ast.MapLiteral{Entries: []*ast.MapEntry{{Key: "k", Value: integer(1)}}}},
+
+		// Precedence and associativity.
+		{"a OR b AND c", &ast.BinaryExpr{Op: ast.OpOr, X: a, Y: &ast.BinaryExpr{Op: ast.OpAnd, X: b, Y: c}}},
+		{"a - b - c", &ast.BinaryExpr{Op: ast.OpSub, X: &ast.BinaryExpr{Op: ast.OpSub, X: a, Y: b}, Y: c}},
+		{"a + b * c ^ 2", &ast.BinaryExpr{Op: ast.OpAdd, X: a, Y: &ast.BinaryExpr{
+			Op: ast.OpMul, X: b, Y: &ast.BinaryExpr{Op: ast.OpPow, X: c, Y: integer(2)},
+		}}},
+		{"NOT a = -b", &ast.UnaryExpr{Op: ast.OpNot, X: &ast.BinaryExpr{
+			Op: ast.OpEq, X: a, Y: &ast.UnaryExpr{Op: ast.OpMinus, X: b},
+		}}},
+		{"a < b < c", &ast.BinaryExpr{Op: ast.OpLt, X: &ast.BinaryExpr{Op: ast.OpLt, X: a, Y: b}, Y: c}},
+
+		// Predicates.
+		{"a STARTS WITH 'x'", &ast.StringPredicate{Op: ast.StartsWith, X: a, Y: str("'x'", "x")}},
+		{"a IS NOT NULL", &ast.IsNull{X: a, Not: true}},
+		{"a IN [1]", &ast.In{X: a, List: &ast.ListLiteral{Elems: []ast.Expr{integer(1)}}}},
+		{"a:A:B", &ast.HasLabels{X: a, Labels: []string{"A", "B"}}},
+
+		// Postfix operators.
+		{"a.b.c", property(property(a, "b"), "c")},
+		{"xs[0]", &ast.IndexExpr{X: xs, Index: integer(0)}},
+		{"xs[..3]", &ast.SliceExpr{X: xs, High: integer(3)}},
+		{"xs[1..]", &ast.SliceExpr{X: xs, Low: integer(1)}},
+
+		// Compound expressions.
+		{"CASE a WHEN 1 THEN b ELSE c END", &ast.CaseExpr{
+			Subject: a,
+			Whens:   []*ast.CaseWhen{{When: integer(1), Then: b}},
+			Else:    c,
+		}},
+		{"CASE WHEN a THEN b END", &ast.CaseExpr{Whens: []*ast.CaseWhen{{When: a, Then: b}}}},
+		{"count(DISTINCT a)", &ast.FunctionCall{Name: "count", Distinct: true, Args: []ast.Expr{a}}},
+		{"apoc.text.join(xs, '')", &ast.FunctionCall{Name: "apoc.text.join", Args: []ast.Expr{xs, str("''", "")}}},
+		{"count(*)", &ast.CountStar{}},
+		{"any(x IN xs WHERE x > 0)", &ast.FilterExpr{
+			Kind:     ast.FilterAny,
+			Variable: x,
+			List:     xs,
+			Where:    &ast.BinaryExpr{Op: ast.OpGt, X: x, Y: integer(0)},
+		}},
+		{"[x IN xs | x * 2]", &ast.ListComprehension{
+			Variable: x,
+			List:     xs,
+			Result:   &ast.BinaryExpr{Op: ast.OpMul, X: x, Y: integer(2)},
+		}},
+		{"[x IN xs WHERE x > 0]", &ast.ListComprehension{
+			Variable: x,
+			List:     xs,
+			Where:    &ast.BinaryExpr{Op: ast.OpGt, X: x, Y: integer(0)},
+		}},
+		{"[p = (a)-->(b) WHERE b.x > 0 | p]", &ast.PatternComprehension{
+			Pattern: &ast.PathPattern{
+				Variable:      variable("p"),
+				Nodes:         []*ast.NodePattern{node("a"), node("b")},
+				Relationships: []*ast.RelationshipPattern{{Direction: ast.DirectionRight}},
+			},
+			Where:  &ast.BinaryExpr{Op: ast.OpGt, X: property(b, "x"), Y: integer(0)},
+			Result: variable("p"),
+		}},
+		{"EXISTS { (a)-->() WHERE a.x }", &ast.ExistsSubquery{
+			Pattern: &ast.Pattern{Paths: []*ast.PathPattern{{
+				Nodes:         []*ast.NodePattern{node("a"), node("")},
+				Relationships: []*ast.RelationshipPattern{{Direction: ast.DirectionRight}},
+			}}},
+			Where: property(a, "x"),
+		}},
+		{"EXISTS { MATCH (a) RETURN a }", &ast.ExistsSubquery{
+			Query: &ast.Query{Clauses: []ast.Clause{
+				&ast.Match{Pattern: &ast.Pattern{Paths: []*ast.PathPattern{{Nodes: []*ast.NodePattern{node("a")}}}}},
+				&ast.Return{Projection: &ast.Projection{Items: items(a)}},
+			}},
+		}},
+	}
+	for _, tt := range tests {
+		checkShape(t, "RETURN "+tt.expr, &ast.Query{Clauses: []ast.Clause{
+			&ast.Return{Projection: &ast.Projection{Items: items(tt.want)}},
+		}})
+	}
+}
@@ -0,0 +1,557 @@
+package ast
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+//go:generate go test -run TestJSONSchema -update
+
+// JSONVersion is the version of the JSON encoding written by MarshalJSON
+// and described by JSONSchema. It changes whenever the encoding does in a
+// way that readers would notice.
+const JSONVersion = 1
+
+// MarshalJSON encodes a tree as JSON, for use by programs written in other
+// languages. UnmarshalJSON decodes it again.
+//
+// The tree is wrapped in a document that records the version of the
+// encoding and the source text of the root:
+//
+//	{"version": 1, "text": "RETURN 1", "offset": 0, "root": {...}}
+//
+// Each node is an object whose "type" is the name of its Go type, as in
+// "Match" or "BinaryExpr". Its "span" is given unless it is unknown, and
+// its "comments" unless it has none. The rest of its fields follow, named
+// as in Go but starting with a lower case letter, as in "orderBy":
+//
+//   - children are nodes, or null where there are none;
+//   - lists are arrays, or null where Go has a nil slice;
+//   - enumerations, such as BinaryOp and Direction, are the strings their
+//     String methods return, as in "+" or "<--";
+//   - IntegerLiteral values are strings of decimal digits, as JSON numbers
+//     cannot hold every int64 in languages like JavaScript.
+//
+// A node's source text is not repeated in each object, but is taken from
+// the document's text at the node's span, offset by the document's offset.
+// A node whose text cannot be found that way, such as one built by hand,
+// has a "text" field instead.
+func MarshalJSON(root Node) ([]byte, error) {
+	var e jsonEncoder
+	if root != nil {
+		info := infoOf(reflect.ValueOf(root))
+		e.text, e.base = info.Raw, info.Loc.Start.Offset
+	}
+	e.buf.WriteString(`{"version":`)
+	e.buf.WriteString(strconv.Itoa(JSONVersion))
+	e.buf.WriteString(`,"text":`)
+	e.scalar(e.text)
+	e.buf.WriteString(`,"offset":`)
+	e.buf.WriteString(strconv.Itoa(e.base))
+	e.buf.WriteString(`,"root":`)
+	if err := e.node(reflect.ValueOf(root)); err != nil {
+		return nil, fmt.Errorf("ast.MarshalJSON: %v", err)
+	}
+	e.buf.WriteByte('}')
+	return e.buf.Bytes(), nil
+}
+
+// UnmarshalJSON decodes a tree encoded by MarshalJSON. It fails if the
+// document has a version other than JSONVersion, or does not describe a
+// well-formed tree.
+func UnmarshalJSON(data []byte) (Node, error) {
+	var doc struct {
+		Version int             `json:"version"`
+		Text    string          `json:"text"`
+		Offset  int             `json:"offset"`
+		Root    json.RawMessage `json:"root"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("ast.UnmarshalJSON: %v", err)
+	}
+	if doc.Version != JSONVersion {
+		return nil, fmt.Errorf("ast.UnmarshalJSON: unsupported version %d", doc.Version)
+	}
+	d := &jsonDecoder{text: doc.Text, base: doc.Offset}
+	v, err := d.node(doc.Root, nodeType, "root")
+	if err != nil {
+		return nil, fmt.Errorf("ast.UnmarshalJSON: %v", err)
+	}
+	if v.IsNil() {
+		return nil, nil
+	}
+	return v.Interface().(Node), nil
+}
+
+// jsonNodes lists every type of node, by which its JSON "type" is found.
+var jsonNodes = []Node{
+	// Statements
+	(*Script)(nil),
+	(*Query)(nil),
+	(*ParamCommand)(nil),
+	(*UseCommand)(nil),
+	(*BeginCommand)(nil),
+	(*CommitCommand)(nil),
+	(*RollbackCommand)(nil),
+	(*Command)(nil),
+
+	// Clauses
+	(*BadClause)(nil),
+	(*Match)(nil),
+	(*Unwind)(nil),
+	(*InQueryCall)(nil),
+	(*StandaloneCall)(nil),
+	(*YieldItem)(nil),
+	(*Create)(nil),
+	(*Merge)(nil),
+	(*MergeAction)(nil),
+	(*Set)(nil),
+	(*SetItem)(nil),
+	(*Remove)(nil),
+	(*RemoveItem)(nil),
+	(*Delete)(nil),
+	(*With)(nil),
+	(*Return)(nil),
+	(*Projection)(nil),
+	(*ProjectionItem)(nil),
+	(*SortItem)(nil),
+	(*Union)(nil),
+
+	// Patterns
+	(*Pattern)(nil),
+	(*PathPattern)(nil),
+	(*NodePattern)(nil),
+	(*RelationshipPattern)(nil),
+
+	// Expressions
+	(*BadExpr)(nil),
+	(*Variable)(nil),
+	(*Parameter)(nil),
+	(*IntegerLiteral)(nil),
+	(*FloatLiteral)(nil),
+	(*StringLiteral)(nil),
+	(*BooleanLiteral)(nil),
+	(*NullLiteral)(nil),
+	(*ListLiteral)(nil),
+	(*MapLiteral)(nil),
+	(*MapEntry)(nil),
+	(*BinaryExpr)(nil),
+	(*UnaryExpr)(nil),
+	(*StringPredicate)(nil),
+	(*IsNull)(nil),
+	(*In)(nil),
+	(*PropertyAccess)(nil),
+	(*HasLabels)(nil),
+	(*IndexExpr)(nil),
+	(*SliceExpr)(nil),
+	(*CaseExpr)(nil),
+	(*CaseWhen)(nil),
+	(*FunctionCall)(nil),
+	(*CountStar)(nil),
+	(*FilterExpr)(nil),
+	(*ListComprehension)(nil),
+	(*PatternComprehension)(nil),
+	(*ExistsSubquery)(nil),
+}
+
+// jsonTypes maps the JSON "type" of each node to its struct type.
+var jsonTypes = func() map[string]reflect.Type {
+	m := make(map[string]reflect.Type, len(jsonNodes))
+	for _, n := range jsonNodes {
+		t := reflect.TypeOf(n).Elem()
+		m[t.Name()] = t
+	}
+	return m
+}()
+
+// The keys of the fields every node has, which come from its NodeInfo.
+const (
+	keyType     = "type"
+	keySpan     = "span"
+	keyText     = "text"
+	keyComments = "comments"
+)
+
+// jsonField is a field of a node, other than its NodeInfo.
+type jsonField struct {
+	name  string // the JSON name
+	index int    // the index in the struct
+	typ   reflect.Type
+}
+
+// jsonFields returns the fields of the node struct type t.
+func jsonFields(t reflect.Type) []jsonField {
+	var fields []jsonField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous {
+			continue
+		}
+		fields = append(fields, jsonField{name: jsonName(f.Name), index: i, typ: f.Type})
+	}
+	return fields
+}
+
+// jsonName returns the JSON name of a Go field: the name with its leading
+// capitals in lower case, as in "orderBy", "x" or "utf16Column", except
+// for the last of them when it starts a word.
+func jsonName(name string) string {
+	r := []rune(name)
+	n := 0
+	for n < len(r) && unicode.IsUpper(r[n]) {
+		n++
+	}
+	if n > 1 && n < len(r) && unicode.IsLower(r[n]) {
+		n--
+	}
+	for i := 0; i < n; i++ {
+		r[i] = unicode.ToLower(r[i])
+	}
+	return string(r)
+}
+
+// isEnum reports whether t is one of the package's enumerations, such as
+// BinaryOp.
+func isEnum(t reflect.Type) bool {
+	return t.Kind() == reflect.Int && t.PkgPath() == nodeType.PkgPath()
+}
+
+// enumValues returns the names of the values of an enumeration, in order.
+// Its String method names each value from 0 on, and returns a name ending
+// in "(?)" past the last.
+func enumValues(t reflect.Type) []string {
+	var names []string
+	for i := 0; ; i++ {
+		s := reflect.New(t).Elem()
+		s.SetInt(int64(i))
+		name := s.Interface().(fmt.Stringer).String()
+		if strings.HasSuffix(name, "(?)") {
+			return names
+		}
+		names = append(names, name)
+	}
+}
+
+// infoOf returns the NodeInfo of the node n points to.
+func infoOf(n reflect.Value) *NodeInfo {
+	return n.Elem().FieldByName("NodeInfo").Addr().Interface().(*NodeInfo)
+}
+
+// sourceText returns the part of text, which starts at byte offset base,
+// that loc covers, and whether it is there.
+func sourceText(text string, base int, loc Span) (string, bool) {
+	if !loc.IsValid() {
+		return "", false
+	}
+	from, to := loc.Start.Offset-base, loc.End.Offset-base
+	if from < 0 || from > to || to > len(text) {
+		return "", false
+	}
+	return text[from:to], true
+}
+
+type jsonEncoder struct {
+	buf  bytes.Buffer
+	text string // the source text of the root
+	base int    // the offset of text
+}
+
+// scalar writes a string, bool or number.
+func (e *jsonEncoder) scalar(x interface{}) error {
+	b, err := marshal(x)
+	if err != nil {
+		return err
+	}
+	e.buf.Write(b)
+	return nil
+}
+
+// marshal is json.Marshal, but leaves '<', '>' and '&' as they are, since
+// they are common in queries, and the JSON is not meant for HTML.
+func marshal(x interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(x); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// node writes the node n points to, or null.
+func (e *jsonEncoder) node(n reflect.Value) error {
+	if !n.IsValid() || n.IsNil() {
+		e.buf.WriteString("null")
+		return nil
+	}
+	if n.Kind() == reflect.Interface {
+		n = n.Elem()
+	}
+	t := n.Type().Elem()
+	if jsonTypes[t.Name()] != t {
+		return fmt.Errorf("unexpected node type %s", n.Type())
+	}
+
+	e.buf.WriteString(`{"type":`)
+	e.scalar(t.Name())
+	info := infoOf(n)
+	if info.Loc.IsValid() {
+		e.buf.WriteString(`,"span":`)
+		e.span(info.Loc)
+	}
+	if text, _ := sourceText(e.text, e.base, info.Loc); text != info.Raw {
+		e.buf.WriteString(`,"text":`)
+		e.scalar(info.Raw)
+	}
+	if len(info.Comments) > 0 {
+		e.buf.WriteString(`,"comments":[`)
+		for i, c := range info.Comments {
+			if i > 0 {
+				e.buf.WriteByte(',')
+			}
+			e.buf.WriteString(`{"text":`)
+			e.scalar(c.Raw)
+			e.buf.WriteString(`,"span":`)
+			e.span(c.Loc)
+			e.buf.WriteString(`,"trailing":`)
+			e.scalar(c.Trailing)
+			e.buf.WriteByte('}')
+		}
+		e.buf.WriteByte(']')
+	}
+	for _, f := range jsonFields(t) {
+		e.buf.WriteString(`,"` + f.name + `":`)
+		if err := e.value(n.Elem().Field(f.index)); err != nil {
+			return fmt.Errorf("%s.%s: %v", t.Name(), f.name, err)
+		}
+	}
+	e.buf.WriteByte('}')
+	return nil
+}
+
+// value writes a field of a node.
+func (e *jsonEncoder) value(v reflect.Value) error {
+	t := v.Type()
+	switch {
+	case t.Implements(nodeType):
+		return e.node(v)
+	case t.Kind() == reflect.Slice:
+		if v.IsNil() {
+			e.buf.WriteString("null")
+			return nil
+		}
+		e.buf.WriteByte('[')
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				e.buf.WriteByte(',')
+			}
+			if err := e.value(v.Index(i)); err != nil {
+				return err
+			}
+		}
+		e.buf.WriteByte(']')
+		return nil
+	case t.Kind() == reflect.Ptr:
+		if v.IsNil() {
+			e.buf.WriteString("null")
+			return nil
+		}
+		return e.value(v.Elem())
+	case isEnum(t):
+		return e.scalar(v.Interface().(fmt.Stringer).String())
+	case t.Kind() == reflect.Int64:
+		return e.scalar(strconv.FormatInt(v.Int(), 10))
+	}
+	return e.scalar(v.Interface())
+}
+
+func (e *jsonEncoder) span(s Span) {
+	e.buf.WriteString(`{"start":`)
+	e.position(s.Start)
+	e.buf.WriteString(`,"end":`)
+	e.position(s.End)
+	e.buf.WriteByte('}')
+}
+
+func (e *jsonEncoder) position(p Position) {
+	fmt.Fprintf(&e.buf, `{"offset":%d,"line":%d,"column":%d,"utf16Column":%d}`,
+		p.Offset, p.Line, p.Column, p.UTF16Column)
+}
+
+type jsonDecoder struct {
+	text string // the source text of the root
+	base int    // the offset of text
+}
+
+// jsonSpan and jsonComment are how spans and comments are encoded.
+type jsonSpan struct {
+	Start jsonPosition `json:"start"`
+	End   jsonPosition `json:"end"`
+}
+
+type jsonPosition struct {
+	Offset      int `json:"offset"`
+	Line        int `json:"line"`
+	Column      int `json:"column"`
+	UTF16Column int `json:"utf16Column"`
+}
+
+type jsonComment struct {
+	Text     string   `json:"text"`
+	Span     jsonSpan `json:"span"`
+	Trailing bool     `json:"trailing"`
+}
+
+func (s jsonSpan) span() Span {
+	return Span{Start: Position(s.Start), End: Position(s.End)}
+}
+
+// unmarshal is json.Unmarshal, but fails on fields that x does not have.
+func unmarshal(data []byte, x interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(x)
+}
+
+// node decodes a node, or null, where a value of type typ belongs. path
+// says where it is in the document, for errors.
+func (d *jsonDecoder) node(data json.RawMessage, typ reflect.Type, path string) (reflect.Value, error) {
+	if isNull(data) {
+		return reflect.Zero(typ), nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return reflect.Value{}, fmt.Errorf("%s: %v", path, err)
+	}
+	var name string
+	if err := json.Unmarshal(fields[keyType], &name); err != nil || name == "" {
+		return reflect.Value{}, fmt.Errorf("%s: missing node type", path)
+	}
+	t, ok := jsonTypes[name]
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("%s: unknown node type %q", path, name)
+	}
+	if !reflect.PtrTo(t).AssignableTo(typ) {
+		return reflect.Value{}, fmt.Errorf("%s: cannot use %s as %s", path, name, typ)
+	}
+
+	n := reflect.New(t)
+	info := infoOf(n)
+	if data, ok := fields[keySpan]; ok {
+		var s jsonSpan
+		if err := unmarshal(data, &s); err != nil {
+			return reflect.Value{}, fmt.Errorf("%s.span: %v", path, err)
+		}
+		info.Loc = s.span()
+	}
+	info.Raw, _ = sourceText(d.text, d.base, info.Loc)
+	if data, ok := fields[keyText]; ok {
+		if err := json.Unmarshal(data, &info.Raw); err != nil {
+			return reflect.Value{}, fmt.Errorf("%s.text: %v", path, err)
+		}
+	}
+	if data, ok := fields[keyComments]; ok {
+		var comments []jsonComment
+		if err := unmarshal(data, &comments); err != nil {
+			return reflect.Value{}, fmt.Errorf("%s.comments: %v", path, err)
+		}
+		for _, c := range comments {
+			info.Comments = append(info.Comments, &Comment{Raw: c.Text, Loc: c.Span.span(), Trailing: c.Trailing})
+		}
+	}
+
+	known := map[string]bool{keyType: true, keySpan: true, keyText: true, keyComments: true}
+	for _, f := range jsonFields(t) {
+		known[f.name] = true
+		data, ok := fields[f.name]
+		if !ok {
+			continue
+		}
+		v, err := d.value(data, f.typ, path+"."+f.name)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		n.Elem().Field(f.index).Set(v)
+	}
+	for name := range fields {
+		if !known[name] {
+			return reflect.Value{}, fmt.Errorf("%s: unknown field %q in %s", path, name, t.Name())
+		}
+	}
+	return n, nil
+}
+
+// value decodes a field of a node, of type typ.
+func (d *jsonDecoder) value(data json.RawMessage, typ reflect.Type, path string) (reflect.Value, error) {
+	switch {
+	case typ.Implements(nodeType):
+		return d.node(data, typ, path)
+	case typ.Kind() == reflect.Slice:
+		if isNull(data) {
+			return reflect.Zero(typ), nil
+		}
+		var elems []json.RawMessage
+		if err := json.Unmarshal(data, &elems); err != nil {
+			return reflect.Value{}, fmt.Errorf("%s: %v", path, err)
+		}
+		list := reflect.MakeSlice(typ, len(elems), len(elems))
+		for i, elem := range elems {
+			v, err := d.value(elem, typ.Elem(), fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			list.Index(i).Set(v)
+		}
+		return list, nil
+	case typ.Kind() == reflect.Ptr:
+		if isNull(data) {
+			return reflect.Zero(typ), nil
+		}
+		v, err := d.value(data, typ.Elem(), path)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		p := reflect.New(typ.Elem())
+		p.Elem().Set(v)
+		return p, nil
+	case isEnum(typ):
+		var name string
+		if err := json.Unmarshal(data, &name); err != nil {
+			return reflect.Value{}, fmt.Errorf("%s: %v", path, err)
+		}
+		for i, s := range enumValues(typ) {
+			if s == name {
+				v := reflect.New(typ).Elem()
+				v.SetInt(int64(i))
+				return v, nil
+			}
+		}
+		return reflect.Value{}, fmt.Errorf("%s: unknown %s %q", path, typ.Name(), name)
+	case typ.Kind() == reflect.Int64:
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return reflect.Value{}, fmt.Errorf("%s: %v", path, err)
+		}
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("%s: invalid integer %q", path, s)
+		}
+		return reflect.ValueOf(i).Convert(typ), nil
+	}
+	v := reflect.New(typ)
+	if err := json.Unmarshal(data, v.Interface()); err != nil {
+		return reflect.Value{}, fmt.Errorf("%s: %v", path, err)
+	}
+	return v.Elem(), nil
+}
+
+func isNull(data json.RawMessage) bool {
+	return len(data) == 0 || string(bytes.TrimSpace(data)) == "null"
+}
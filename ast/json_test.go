@@ -0,0 +1,229 @@
+package ast_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/a-poor/cypher"
+	"github.com/a-poor/cypher/ast"
+)
+
+var update = flag.Bool("update", false, "rewrite ast.v1.schema.json")
+
+// corpus returns the queries in the repository's test corpus.
+func corpus(t *testing.T) map[string]string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join("..", "testdata", "corpus", "*.cypher"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no corpus: %v", err)
+	}
+	queries := map[string]string{}
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		queries[filepath.Base(f)] = string(b)
+	}
+	return queries
+}
+
+func roundTrip(t *testing.T, name string, n ast.Node) {
+	t.Helper()
+	data, err := ast.MarshalJSON(n)
+	if err != nil {
+		t.Errorf("%s: %v", name, err)
+		return
+	}
+	got, err := ast.UnmarshalJSON(data)
+	if err != nil {
+		t.Errorf("%s: %v", name, err)
+		return
+	}
+	if !reflect.DeepEqual(got, n) {
+		t.Errorf("%s: tree changed by a round trip through\n%s", name, data)
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	for name, query := range corpus(t) {
+		tree, err := cypher.ParseCST(query)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		roundTrip(t, name, tree.AST())
+	}
+
+	s, _ := cypher.ParseScript("// setup\n:param x => 1;\nMATCH (n) RETURN n; RETURN -9223372036854775808, 1.5e3, 'a\\tb'")
+	roundTrip(t, "script", s)
+
+	// Nodes from elsewhere keep their text.
+	q, _ := cypher.Parse("MATCH (n) RETURN n")
+	q.Clauses = append(q.Clauses, &ast.Return{NodeInfo: ast.NodeInfo{Raw: "RETURN 1"}})
+	roundTrip(t, "built by hand", q)
+	roundTrip(t, "nil", nil)
+}
+
+func TestJSONFormat(t *testing.T) {
+	q, _ := cypher.Parse("RETURN -[1]")
+	data, err := ast.MarshalJSON(q.Clauses[0].(*ast.Return).Projection.Items[0].Expr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = `{"version":1,"text":"-[1]","offset":7,"root":` +
+		`{"type":"UnaryExpr","span":{"start":{"offset":7,"line":1,"column":8,"utf16Column":8},"end":{"offset":11,"line":1,"column":12,"utf16Column":12}},"op":"-","x":` +
+		`{"type":"ListLiteral","span":{"start":{"offset":8,"line":1,"column":9,"utf16Column":9},"end":{"offset":11,"line":1,"column":12,"utf16Column":12}},"elems":[` +
+		`{"type":"IntegerLiteral","span":{"start":{"offset":9,"line":1,"column":10,"utf16Column":10},"end":{"offset":10,"line":1,"column":11,"utf16Column":11}},"literal":"1","value":"1"}]}}}`
+	if string(data) != want {
+		t.Errorf("got\n%s\nwant\n%s", data, want)
+	}
+}
+
+func TestJSONErrors(t *testing.T) {
+	tests := []struct {
+		doc, want string
+	}{
+		{`{"version":2,"root":null}`, "unsupported version 2"},
+		{`{"version":1,"root":{"type":"Nope"}}`, `root: unknown node type "Nope"`},
+		{`{"version":1,"root":{"type":"Match","where":{"type":"Return"}}}`, "root.where: cannot use Return as ast.Expr"},
+		{`{"version":1,"root":{"type":"Variable","nmae":"x"}}`, `root: unknown field "nmae" in Variable`},
+		{`{"version":1,"root":{"type":"UnaryExpr","op":"!"}}`, `root.op: unknown UnaryOp "!"`},
+		{`{"version":1,"root":{"type":"IntegerLiteral","value":1}}`, "root.value: json: cannot unmarshal number"},
+	}
+	for _, tt := range tests {
+		_, err := ast.UnmarshalJSON([]byte(tt.doc))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: got error %v, want %q", tt.doc, err, tt.want)
+		}
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	const file = "ast.v1.schema.json"
+	got := ast.JSONSchema()
+	if *update {
+		if err := os.WriteFile(file, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s is out of date; run go generate ./ast", file)
+	}
+}
+
+// validate checks a JSON value against a schema, supporting just the
+// keywords JSONSchema uses.
+func validate(t *testing.T, defs map[string]interface{}, schema map[string]interface{}, v interface{}, path string) bool {
+	if r, ok := schema["$ref"].(string); ok {
+		return validate(t, defs, defs[strings.TrimPrefix(r, "#/$defs/")].(map[string]interface{}), v, path)
+	}
+	if alts, ok := schema["oneOf"].([]interface{}); ok {
+		n := 0
+		for _, alt := range alts {
+			if validate(nil, defs, alt.(map[string]interface{}), v, path) {
+				n++
+			}
+		}
+		if n != 1 && t != nil {
+			t.Errorf("%s: matches %d alternatives of %v", path, n, schema)
+		}
+		return n == 1
+	}
+	fail := func(msg string) bool {
+		if t != nil {
+			t.Errorf("%s: %s", path, msg)
+		}
+		return false
+	}
+	if c, ok := schema["const"]; ok && !reflect.DeepEqual(c, v) {
+		return fail("not " + reflect.ValueOf(c).String())
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			found = found || e == v
+		}
+		if !found {
+			return fail("not in enum")
+		}
+	}
+	switch schema["type"] {
+	case "null":
+		return v == nil || fail("not null")
+	case "string":
+		if _, ok := v.(string); !ok {
+			return fail("not a string")
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fail("not a boolean")
+		}
+	case "integer", "number":
+		if _, ok := v.(float64); !ok {
+			return fail("not a number")
+		}
+	case "array":
+		list, ok := v.([]interface{})
+		if !ok {
+			return fail("not an array")
+		}
+		for i, e := range list {
+			if !validate(t, defs, schema["items"].(map[string]interface{}), e, path+"["+strconv.Itoa(i)+"]") {
+				return false
+			}
+		}
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return fail("not an object")
+		}
+		props := schema["properties"].(map[string]interface{})
+		for _, r := range schema["required"].([]interface{}) {
+			if _, ok := obj[r.(string)]; !ok {
+				return fail("missing " + r.(string))
+			}
+		}
+		for k, e := range obj {
+			p, ok := props[k]
+			if !ok {
+				return fail("unexpected " + k)
+			}
+			if !validate(t, defs, p.(map[string]interface{}), e, path+"."+k) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// The corpus, encoded, must match the schema.
+func TestJSONSchemaValid(t *testing.T) {
+	var schema map[string]interface{}
+	if err := json.Unmarshal(ast.JSONSchema(), &schema); err != nil {
+		t.Fatal(err)
+	}
+	defs := schema["$defs"].(map[string]interface{})
+	for name, query := range corpus(t) {
+		tree, _ := cypher.ParseCST(query)
+		data, err := ast.MarshalJSON(tree.AST())
+		if err != nil {
+			t.Fatal(err)
+		}
+		var doc interface{}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			t.Fatal(err)
+		}
+		validate(t, defs, schema, doc, name)
+	}
+}
@@ -0,0 +1,164 @@
+package ast
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// JSONSchemaID identifies the JSON Schema document returned by JSONSchema.
+// It names the version of the encoding it describes.
+var JSONSchemaID = fmt.Sprintf("https://github.com/a-poor/cypher/ast/ast.v%d.schema.json", JSONVersion)
+
+// The interfaces that fields of nodes may have as their types. Each has a
+// definition of the same name in the schema.
+var jsonInterfaces = []reflect.Type{
+	nodeType,
+	reflect.TypeOf((*Statement)(nil)).Elem(),
+	reflect.TypeOf((*Clause)(nil)).Elem(),
+	reflect.TypeOf((*Expr)(nil)).Elem(),
+}
+
+// JSONSchema returns a JSON Schema (draft 2020-12) describing the documents
+// written by MarshalJSON. It is derived from the types of the nodes, so it
+// always matches the encoding; a copy is kept in ast.v1.schema.json for
+// programs that cannot run Go.
+func JSONSchema() []byte {
+	defs := map[string]interface{}{
+		"Position": object(map[string]interface{}{
+			"offset":      integer,
+			"line":        integer,
+			"column":      integer,
+			"utf16Column": integer,
+		}, "offset", "line", "column", "utf16Column"),
+		"Span": object(map[string]interface{}{
+			"start": ref("Position"),
+			"end":   ref("Position"),
+		}, "start", "end"),
+		"Comment": object(map[string]interface{}{
+			"text":     str,
+			"span":     ref("Span"),
+			"trailing": boolean,
+		}, "text", "span", "trailing"),
+	}
+
+	for _, iface := range jsonInterfaces {
+		var refs []interface{}
+		for _, n := range jsonNodes {
+			if t := reflect.TypeOf(n); t.Implements(iface) {
+				refs = append(refs, ref(t.Elem().Name()))
+			}
+		}
+		defs[iface.Name()] = map[string]interface{}{"oneOf": refs}
+	}
+
+	for _, n := range jsonNodes {
+		t := reflect.TypeOf(n).Elem()
+		props := map[string]interface{}{
+			keyType:     map[string]interface{}{"const": t.Name()},
+			keySpan:     ref("Span"),
+			keyText:     str,
+			keyComments: array(ref("Comment")),
+		}
+		for _, f := range jsonFields(t) {
+			props[f.name] = schemaOf(f.typ, defs)
+		}
+		defs[t.Name()] = object(props, keyType)
+	}
+
+	schema := map[string]interface{}{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"$id":     JSONSchemaID,
+		"title":   "Cypher syntax tree",
+		"type":    "object",
+		"properties": map[string]interface{}{
+			"version": map[string]interface{}{"const": JSONVersion},
+			"text":    str,
+			"offset":  integer,
+			"root":    nullable(ref("Node")),
+		},
+		"required":             []string{"version", "root"},
+		"additionalProperties": false,
+		"$defs":                defs,
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(schema); err != nil {
+		panic(err)
+	}
+	return buf.Bytes()
+}
+
+// Schemas of the JSON types.
+var (
+	str     = map[string]interface{}{"type": "string"}
+	boolean = map[string]interface{}{"type": "boolean"}
+	integer = map[string]interface{}{"type": "integer"}
+	number  = map[string]interface{}{"type": "number"}
+)
+
+func ref(name string) map[string]interface{} {
+	return map[string]interface{}{"$ref": "#/$defs/" + name}
+}
+
+func nullable(schema interface{}) map[string]interface{} {
+	return map[string]interface{}{"oneOf": []interface{}{schema, map[string]interface{}{"type": "null"}}}
+}
+
+func array(items interface{}) map[string]interface{} {
+	return map[string]interface{}{"type": "array", "items": items}
+}
+
+func object(props map[string]interface{}, required ...string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           props,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+// schemaOf returns the schema of a field of type t, adding the definitions
+// of any enumerations it uses to defs.
+func schemaOf(t reflect.Type, defs map[string]interface{}) interface{} {
+	switch {
+	case t.Kind() == reflect.Interface:
+		return nullable(ref(t.Name()))
+	case t.Kind() == reflect.Ptr && t.Implements(nodeType):
+		return nullable(ref(t.Elem().Name()))
+	case t.Kind() == reflect.Ptr:
+		return nullable(schemaOf(t.Elem(), defs))
+	case t.Kind() == reflect.Slice:
+		items := schemaOf(t.Elem(), defs)
+		if t.Elem().Implements(nodeType) {
+			// Lists hold no nulls.
+			items = ref(nodeName(t.Elem()))
+		}
+		return nullable(array(items))
+	case isEnum(t):
+		defs[t.Name()] = map[string]interface{}{"enum": enumValues(t)}
+		return ref(t.Name())
+	case t.Kind() == reflect.Int64:
+		return map[string]interface{}{"type": "string", "pattern": "^-?[0-9]+$"}
+	case t.Kind() == reflect.Int:
+		return integer
+	case t.Kind() == reflect.Float64:
+		return number
+	case t.Kind() == reflect.Bool:
+		return boolean
+	case t.Kind() == reflect.String:
+		return str
+	}
+	panic(fmt.Sprintf("ast: no JSON schema for %s", t))
+}
+
+// nodeName returns the name of a node or interface type.
+func nodeName(t reflect.Type) string {
+	if t.Kind() == reflect.Ptr {
+		return t.Elem().Name()
+	}
+	return t.Name()
+}
@@ -0,0 +1,132 @@
+package ast
+
+// Pattern is a comma separated list of path patterns, as found in MATCH and
+// CREATE clauses.
+type Pattern struct {
+	NodeInfo
+
+	Paths []*PathPattern
+}
+
+// Variables returns the variables declared by the pattern, in source order.
+// A variable that occurs more than once is only listed the first time.
+func (p *Pattern) Variables() []*Variable {
+	var vars []*Variable
+	seen := map[string]bool{}
+	add := func(v *Variable) {
+		if v != nil && !seen[v.Name] {
+			seen[v.Name] = true
+			vars = append(vars, v)
+		}
+	}
+	for _, path := range p.Paths {
+		add(path.Variable)
+		for i, n := range path.Nodes {
+			if i > 0 && i-1 < len(path.Relationships) {
+				add(path.Relationships[i-1].Variable)
+			}
+			add(n.Variable)
+		}
+	}
+	return vars
+}
+
+// Shortest says whether a path pattern is wrapped in a call of
+// shortestPath or allShortestPaths.
+type Shortest int
+
+const (
+	NotShortest      Shortest = iota // a plain pattern
+	ShortestPath                     // shortestPath(...)
+	AllShortestPaths                 // allShortestPaths(...)
+)
+
+func (s Shortest) String() string {
+	switch s {
+	case NotShortest:
+		return ""
+	case ShortestPath:
+		return "shortestPath"
+	case AllShortestPaths:
+		return "allShortestPaths"
+	}
+	return "Shortest(?)"
+}
+
+// PathPattern is a chain of node patterns joined by relationship patterns.
+// Relationships[i] connects Nodes[i] and Nodes[i+1].
+//
+// A PathPattern with at least one relationship is also an expression, which
+// tests whether the pattern matches, as in "WHERE (a)-->(b)".
+type PathPattern struct {
+	NodeInfo
+
+	// Variable names the whole path, as in "p = (a)-->(b)". It is nil
+	// for anonymous paths.
+	Variable *Variable
+
+	// Shortest is set for a pattern wrapped in shortestPath or
+	// allShortestPaths. The openCypher grammar only has them as
+	// functions, so they are only recognized in expressions, as in
+	// "RETURN shortestPath((a)-[*]-(b))", where the PathPattern stands
+	// for the whole call.
+	Shortest Shortest
+
+	Nodes         []*NodePattern
+	Relationships []*RelationshipPattern
+}
+
+// NodePattern is a node in a pattern, such as "(n:Person {name: 'x'})".
+type NodePattern struct {
+	NodeInfo
+
+	Variable   *Variable // nil for anonymous nodes
+	Labels     []string
+	Properties Expr // a *MapLiteral or *Parameter, or nil
+}
+
+// Direction is the direction of a RelationshipPattern.
+type Direction int
+
+const (
+	DirectionNone  Direction = iota // (a)--(b)
+	DirectionLeft                   // (a)<--(b)
+	DirectionRight                  // (a)-->(b)
+	DirectionBoth                   // (a)<-->(b)
+)
+
+func (d Direction) String() string {
+	switch d {
+	case DirectionNone:
+		return "--"
+	case DirectionLeft:
+		return "<--"
+	case DirectionRight:
+		return "-->"
+	case DirectionBoth:
+		return "<-->"
+	}
+	return "Direction(?)"
+}
+
+// RelationshipPattern is a relationship in a pattern, such as
+// "-[r:KNOWS|LIKES*1..3]->".
+type RelationshipPattern struct {
+	NodeInfo
+
+	Variable  *Variable // nil for anonymous relationships
+	Direction Direction
+
+	// Types lists the relationship types to match, any of which will
+	// do, as in "[:KNOWS|LIKES]". It is nil if any type will do.
+	Types []string
+
+	// VarLength is set for a variable length relationship, as in "[*]"
+	// or "[*1..3]". MinHops and MaxHops are then its bounds, with nil
+	// for no bound. A single number, as in "[*3]", sets both.
+	VarLength bool
+	MinHops   *int
+	MaxHops   *int
+
+	Properties Expr // a *MapLiteral or *Parameter, or nil
+}
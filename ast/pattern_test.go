@@ -0,0 +1,95 @@
+package ast_test
+
+import (
+	"testing"
+
+	"github.com/a-poor/cypher"
+	"github.com/a-poor/cypher/ast"
+)
+
+func hops(n int) *int { return &n }
+
+func TestPatternShapes(t *testing.T) {
+	returnA := &ast.Return{Projection: &ast.Projection{Items: items(variable("a"))}}
+
+	// rel returns the query matching a pattern from a to b through r.
+	rel := func(r *ast.RelationshipPattern) *ast.Query {
+		return &ast.Query{Clauses: []ast.Clause{
+			&ast.Match{Pattern: &ast.Pattern{Paths: []*ast.PathPattern{{
+				Nodes:         []*ast.NodePattern{node("a"), node("b")},
+				Relationships: []*ast.RelationshipPattern{r},
+			}}}},
+			returnA,
+		}}
+	}
+	tests := []struct {
+		query string
+		want  *ast.Query
+	}{
+		{"MATCH (a)--(b) RETURN a", rel(&ast.RelationshipPattern{Direction: ast.DirectionNone})},
+		{"MATCH (a)<--(b) RETURN a", rel(&ast.RelationshipPattern{Direction: ast.DirectionLeft})},
+		{"MATCH (a)-->(b) RETURN a", rel(&ast.RelationshipPattern{Direction: ast.DirectionRight})},
+		{"MATCH (a)<-->(b) RETURN a", rel(&ast.RelationshipPattern{Direction: ast.DirectionBoth})},
+		{"MATCH (a)-[r:KNOWS|:LIKES {since: 1}]->(b) RETURN a", rel(&ast.RelationshipPattern{
+			Variable:   variable("r"),
+			Direction:  ast.DirectionRight,
+			Types:      []string{"KNOWS", "LIKES"},
+			Properties: &ast.MapLiteral{Entries: []*ast.MapEntry{{Key: "since", Value: integer(1)}}},
+		})},
+
+		// Variable length relationships, with nil for missing bounds.
+		{"MATCH (a)-[*]-(b) RETURN a", rel(&ast.RelationshipPattern{VarLength: true})},
+		{"MATCH (a)-[*2]-(b) RETURN a", rel(&ast.RelationshipPattern{VarLength: true, MinHops: hops(2), MaxHops: hops(2)})},
+		{"MATCH (a)-[*..3]-(b) RETURN a", rel(&ast.RelationshipPattern{VarLength: true, MaxHops: hops(3)})},
+		{"MATCH (a)-[*1..]-(b) RETURN a", rel(&ast.RelationshipPattern{VarLength: true, MinHops: hops(1)})},
+		{"MATCH (a)-[*1..3]-(b) RETURN a", rel(&ast.RelationshipPattern{VarLength: true, MinHops: hops(1), MaxHops: hops(3)})},
+
+		{"MATCH p = (a:A:B {x: $x}), (b) RETURN a", &ast.Query{Clauses: []ast.Clause{
+			&ast.Match{Pattern: &ast.Pattern{Paths: []*ast.PathPattern{
+				{Variable: variable("p"), Nodes: []*ast.NodePattern{{
+					Variable:   variable("a"),
+					Labels:     []string{"A", "B"},
+					Properties: &ast.MapLiteral{Entries: []*ast.MapEntry{{Key: "x", Value: &ast.Parameter{Name: "x"}}}},
+				}}},
+				{Nodes: []*ast.NodePattern{node("b")}},
+			}}},
+			returnA,
+		}}},
+	}
+	for _, tt := range tests {
+		checkShape(t, tt.query, tt.want)
+	}
+
+	// shortestPath and allShortestPaths are only recognized in
+	// expressions, where the pattern stands for the whole call.
+	for _, tt := range []struct {
+		query string
+		want  ast.Shortest
+	}{
+		{"RETURN shortestPath((a)-[*]-(b))", ast.ShortestPath},
+		{"RETURN allShortestPaths((a)-[*]-(b))", ast.AllShortestPaths},
+	} {
+		checkShape(t, tt.query, &ast.Query{Clauses: []ast.Clause{
+			&ast.Return{Projection: &ast.Projection{Items: items(&ast.PathPattern{
+				Shortest:      tt.want,
+				Nodes:         []*ast.NodePattern{node("a"), node("b")},
+				Relationships: []*ast.RelationshipPattern{{VarLength: true}},
+			})}},
+		}})
+	}
+}
+
+func TestHopsNotShared(t *testing.T) {
+	q, err := cypher.Parse("MATCH (a)-[*3]->(b) RETURN a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := q.Clauses[0].(*ast.Match).Pattern.Paths[0].Relationships[0]
+	if r.MinHops == nil || r.MaxHops == nil || *r.MinHops != 3 || *r.MaxHops != 3 {
+		t.Fatalf("[*3]: got MinHops %v, MaxHops %v", r.MinHops, r.MaxHops)
+	}
+	*r.MaxHops = 5
+	if *r.MinHops != 3 {
+		t.Errorf("[*3]: setting MaxHops changed MinHops to %d", *r.MinHops)
+	}
+}
@@ -0,0 +1,39 @@
+package ast
+
+import "fmt"
+
+// Position is a location in the source text.
+//
+// Columns are given both in runes and in UTF-16 code units, which is how
+// editors speaking the Language Server Protocol count them. The two only
+// differ on lines with characters outside the Basic Multilingual Plane.
+type Position struct {
+	Offset      int // byte offset, starting at 0
+	Line        int // line number, starting at 1
+	Column      int // column number in runes, starting at 1
+	UTF16Column int // column number in UTF-16 code units, starting at 1
+}
+
+// IsValid reports whether the position is known.
+func (p Position) IsValid() bool { return p.Line > 0 }
+
+func (p Position) String() string {
+	if !p.IsValid() {
+		return "-"
+	}
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Span is the range of source text covered by a node. End is the position
+// just past the last character.
+type Span struct {
+	Start Position
+	End   Position
+}
+
+// IsValid reports whether the span is known.
+func (s Span) IsValid() bool { return s.Start.IsValid() }
+
+func (s Span) String() string {
+	return s.Start.String() + "-" + s.End.String()
+}
@@ -0,0 +1,73 @@
+package ast
+
+// Script is a sequence of statements, as found in a migration file or a
+// cypher-shell script.
+type Script struct {
+	NodeInfo
+
+	Statements []Statement
+}
+
+// Statement is implemented by every node that can appear in
+// Script.Statements: queries and cypher-shell commands.
+type Statement interface {
+	Node
+	statementNode()
+}
+
+func (*Query) statementNode() {}
+
+// Commands are cypher-shell meta-commands. They start with a colon and run
+// to the end of the line rather than to a semicolon.
+
+// ParamCommand sets a query parameter, as in ":param name => 1".
+type ParamCommand struct {
+	NodeInfo
+
+	// Name is the name of the parameter. It is empty when the command
+	// sets several parameters from a map, as in ":param {a: 1, b: 2}".
+	Name string
+
+	// Value is the source text of the parameter's value.
+	Value string
+}
+
+// UseCommand switches to another database, as in ":use neo4j".
+type UseCommand struct {
+	NodeInfo
+
+	Database string
+}
+
+// BeginCommand opens an explicit transaction.
+type BeginCommand struct {
+	NodeInfo
+}
+
+// CommitCommand commits the open transaction.
+type CommitCommand struct {
+	NodeInfo
+}
+
+// RollbackCommand rolls back the open transaction.
+type RollbackCommand struct {
+	NodeInfo
+}
+
+// Command is any other meta-command, such as ":help" or ":params".
+type Command struct {
+	NodeInfo
+
+	// Name is the command's name in lower case, without the colon.
+	Name string
+
+	// Args is the rest of the line, with surrounding space removed.
+	Args string
+}
+
+func (*ParamCommand) statementNode()    {}
+func (*UseCommand) statementNode()      {}
+func (*BeginCommand) statementNode()    {}
+func (*CommitCommand) statementNode()   {}
+func (*RollbackCommand) statementNode() {}
+func (*Command) statementNode()         {}
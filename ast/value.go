@@ -0,0 +1,80 @@
+package ast
+
+import "fmt"
+
+// Value returns the value of a literal expression, as a Go value:
+//
+//	IntegerLiteral  int64
+//	FloatLiteral    float64
+//	StringLiteral   string
+//	BooleanLiteral  bool
+//	NullLiteral     nil
+//	ListLiteral     []interface{}
+//	MapLiteral      map[string]interface{}
+//
+// A number may have a sign, as in "-1", which is a UnaryExpr. Lists and
+// maps must hold only literals; if a map has the same key more than once,
+// the last value wins. For anything else, such as a parameter or a
+// variable, Value returns an error giving its position.
+func Value(x Expr) (interface{}, error) {
+	switch x := x.(type) {
+	case *IntegerLiteral:
+		return x.Value, nil
+	case *FloatLiteral:
+		return x.Value, nil
+	case *StringLiteral:
+		return x.Value, nil
+	case *BooleanLiteral:
+		return x.Value, nil
+	case *NullLiteral:
+		return nil, nil
+	case *UnaryExpr:
+		return signed(x)
+	case *ListLiteral:
+		list := make([]interface{}, len(x.Elems))
+		for i, e := range x.Elems {
+			v, err := Value(e)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = v
+		}
+		return list, nil
+	case *MapLiteral:
+		m := make(map[string]interface{}, len(x.Entries))
+		for _, e := range x.Entries {
+			v, err := Value(e.Value)
+			if err != nil {
+				return nil, err
+			}
+			m[e.Key] = v
+		}
+		return m, nil
+	}
+	return nil, notLiteral(x)
+}
+
+// signed returns the value of a number with a sign.
+func signed(x *UnaryExpr) (interface{}, error) {
+	switch y := x.X.(type) {
+	case *IntegerLiteral:
+		if x.Op == OpMinus {
+			return -y.Value, nil
+		}
+		if x.Op == OpPlus {
+			return y.Value, nil
+		}
+	case *FloatLiteral:
+		if x.Op == OpMinus {
+			return -y.Value, nil
+		}
+		if x.Op == OpPlus {
+			return y.Value, nil
+		}
+	}
+	return nil, notLiteral(x)
+}
+
+func notLiteral(x Expr) error {
+	return fmt.Errorf("ast.Value: %s: %T is not a literal", x.Span().Start, x)
+}
@@ -0,0 +1,289 @@
+package ast
+
+import "fmt"
+
+// A Visitor's Visit method is invoked for each node encountered by Walk.
+// If the result visitor w is not nil, Walk visits each of the children of
+// node with the visitor w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses a tree in depth-first order: it starts by calling
+// v.Visit(node); node must not be nil. If the visitor w returned by
+// v.Visit(node) is not nil, Walk is invoked recursively with visitor w for
+// each of the non-nil children of node, followed by a call of
+// w.Visit(nil).
+//
+// Children are visited in the order they appear in the source text. In
+// particular:
+//
+//   - the nodes and relationships of a PathPattern alternate, starting
+//     and ending with a node, after the path's Variable;
+//   - an expression's operands come before what is applied to them, so
+//     the X of a PropertyAccess, IndexExpr or IsNull is visited first;
+//   - the Variable of a FilterExpr, ListComprehension or YieldItem comes
+//     before the list or expression it is bound to, and the Alias of a
+//     ProjectionItem after its Expr.
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	// Statements
+	case *Script:
+		for _, s := range n.Statements {
+			Walk(v, s)
+		}
+	case *Query:
+		walkClauses(v, n.Clauses)
+	case *ParamCommand, *UseCommand, *BeginCommand, *CommitCommand,
+		*RollbackCommand, *Command:
+		// nothing to do
+
+	// Clauses
+	case *BadClause, *Union:
+		// nothing to do
+	case *Match:
+		walkPattern(v, n.Pattern)
+		walkExpr(v, n.Where)
+	case *Unwind:
+		walkExpr(v, n.Expr)
+		walkVariable(v, n.Variable)
+	case *InQueryCall:
+		walkExprs(v, n.Args)
+		for _, item := range n.Yield {
+			Walk(v, item)
+		}
+		walkExpr(v, n.Where)
+	case *StandaloneCall:
+		walkExprs(v, n.Args)
+		for _, item := range n.Yield {
+			Walk(v, item)
+		}
+		walkExpr(v, n.Where)
+	case *YieldItem:
+		walkVariable(v, n.Variable)
+	case *Create:
+		walkPattern(v, n.Pattern)
+	case *Merge:
+		if n.Pattern != nil {
+			Walk(v, n.Pattern)
+		}
+		for _, a := range n.Actions {
+			Walk(v, a)
+		}
+	case *MergeAction:
+		if n.Set != nil {
+			Walk(v, n.Set)
+		}
+	case *Set:
+		for _, item := range n.Items {
+			Walk(v, item)
+		}
+	case *SetItem:
+		walkExpr(v, n.Target)
+		walkExpr(v, n.Value)
+	case *Remove:
+		for _, item := range n.Items {
+			Walk(v, item)
+		}
+	case *RemoveItem:
+		walkExpr(v, n.Target)
+	case *Delete:
+		walkExprs(v, n.Exprs)
+	case *With:
+		if n.Projection != nil {
+			Walk(v, n.Projection)
+		}
+		walkExpr(v, n.Where)
+	case *Return:
+		if n.Projection != nil {
+			Walk(v, n.Projection)
+		}
+	case *Projection:
+		for _, item := range n.Items {
+			Walk(v, item)
+		}
+		for _, item := range n.OrderBy {
+			Walk(v, item)
+		}
+		walkExpr(v, n.Skip)
+		walkExpr(v, n.Limit)
+	case *ProjectionItem:
+		walkExpr(v, n.Expr)
+		walkVariable(v, n.Alias)
+	case *SortItem:
+		walkExpr(v, n.Expr)
+
+	// Patterns
+	case *Pattern:
+		for _, path := range n.Paths {
+			Walk(v, path)
+		}
+	case *PathPattern:
+		walkVariable(v, n.Variable)
+		for i, node := range n.Nodes {
+			if i > 0 && i-1 < len(n.Relationships) {
+				Walk(v, n.Relationships[i-1])
+			}
+			Walk(v, node)
+		}
+	case *NodePattern:
+		walkVariable(v, n.Variable)
+		walkExpr(v, n.Properties)
+	case *RelationshipPattern:
+		walkVariable(v, n.Variable)
+		walkExpr(v, n.Properties)
+
+	// Expressions
+	case *BadExpr, *Variable, *Parameter, *IntegerLiteral, *FloatLiteral,
+		*StringLiteral, *BooleanLiteral, *NullLiteral, *CountStar:
+		// nothing to do
+	case *ListLiteral:
+		walkExprs(v, n.Elems)
+	case *MapLiteral:
+		for _, e := range n.Entries {
+			Walk(v, e)
+		}
+	case *MapEntry:
+		walkExpr(v, n.Value)
+	case *BinaryExpr:
+		walkExpr(v, n.X)
+		walkExpr(v, n.Y)
+	case *UnaryExpr:
+		walkExpr(v, n.X)
+	case *StringPredicate:
+		walkExpr(v, n.X)
+		walkExpr(v, n.Y)
+	case *IsNull:
+		walkExpr(v, n.X)
+	case *In:
+		walkExpr(v, n.X)
+		walkExpr(v, n.List)
+	case *PropertyAccess:
+		walkExpr(v, n.X)
+	case *HasLabels:
+		walkExpr(v, n.X)
+	case *IndexExpr:
+		walkExpr(v, n.X)
+		walkExpr(v, n.Index)
+	case *SliceExpr:
+		walkExpr(v, n.X)
+		walkExpr(v, n.Low)
+		walkExpr(v, n.High)
+	case *CaseExpr:
+		walkExpr(v, n.Subject)
+		for _, w := range n.Whens {
+			Walk(v, w)
+		}
+		walkExpr(v, n.Else)
+	case *CaseWhen:
+		walkExpr(v, n.When)
+		walkExpr(v, n.Then)
+	case *FunctionCall:
+		walkExprs(v, n.Args)
+	case *FilterExpr:
+		walkVariable(v, n.Variable)
+		walkExpr(v, n.List)
+		walkExpr(v, n.Where)
+	case *ListComprehension:
+		walkVariable(v, n.Variable)
+		walkExpr(v, n.List)
+		walkExpr(v, n.Where)
+		walkExpr(v, n.Result)
+	case *PatternComprehension:
+		if n.Pattern != nil {
+			Walk(v, n.Pattern)
+		}
+		walkExpr(v, n.Where)
+		walkExpr(v, n.Result)
+	case *ExistsSubquery:
+		if n.Query != nil {
+			Walk(v, n.Query)
+		}
+		walkPattern(v, n.Pattern)
+		walkExpr(v, n.Where)
+
+	default:
+		panic(fmt.Sprintf("ast.Walk: unexpected node type %T", n))
+	}
+
+	v.Visit(nil)
+}
+
+func walkClauses(v Visitor, list []Clause) {
+	for _, c := range list {
+		Walk(v, c)
+	}
+}
+
+func walkExprs(v Visitor, list []Expr) {
+	for _, x := range list {
+		Walk(v, x)
+	}
+}
+
+// walkExpr walks x, which may be nil.
+func walkExpr(v Visitor, x Expr) {
+	if x != nil {
+		Walk(v, x)
+	}
+}
+
+// walkVariable walks x, which may be nil.
+func walkVariable(v Visitor, x *Variable) {
+	if x != nil {
+		Walk(v, x)
+	}
+}
+
+// walkPattern walks x, which may be nil.
+func walkPattern(v Visitor, x *Pattern) {
+	if x != nil {
+		Walk(v, x)
+	}
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses a tree in depth-first order: it starts by calling
+// f(node); node must not be nil. If f returns true, Inspect invokes f
+// recursively for each of the non-nil children of node, followed by a
+// call of f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+// stackInspector is the Visitor of InspectWithStack.
+type stackInspector struct {
+	f     func(Node, []Node) bool
+	stack []Node
+}
+
+func (s *stackInspector) Visit(node Node) Visitor {
+	if node == nil {
+		s.stack = s.stack[:len(s.stack)-1]
+		return nil
+	}
+	if !s.f(node, s.stack) {
+		return nil
+	}
+	s.stack = append(s.stack, node)
+	return s
+}
+
+// InspectWithStack is like Inspect, but also passes f the ancestors of each
+// node, from the root down to its parent. f is not called with nil. The
+// stack is only valid during the call: f must copy it to keep it.
+func InspectWithStack(node Node, f func(n Node, stack []Node) bool) {
+	Walk(&stackInspector{f: f}, node)
+}
@@ -0,0 +1,147 @@
+package ast_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/a-poor/cypher"
+	"github.com/a-poor/cypher/ast"
+)
+
+func mustParse(t *testing.T, query string) *ast.Query {
+	t.Helper()
+	q, err := cypher.Parse(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return q
+}
+
+// texts returns the source text of the nodes of a tree, in the order Walk
+// visits them, for comparing trees.
+func texts(root ast.Node) []string {
+	var s []string
+	ast.Inspect(root, func(n ast.Node) bool {
+		if n != nil {
+			s = append(s, reflect.TypeOf(n).String()+" "+n.Text())
+		}
+		return true
+	})
+	return s
+}
+
+func TestWalkOrder(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{
+			// The path's variable comes first and its nodes and
+			// relationships alternate; the X of a PropertyAccess or IsNull
+			// comes before it is applied, the variable of a comprehension
+			// before its list, and an alias after its expression.
+			"MATCH p = (a)-[r]->(b) WHERE a.x IS NULL RETURN [x IN a.xs WHERE x > 0] AS y",
+			[]string{
+				"*ast.Query MATCH p = (a)-[r]->(b) WHERE a.x IS NULL RETURN [x IN a.xs WHERE x > 0] AS y",
+				"*ast.Match MATCH p = (a)-[r]->(b) WHERE a.x IS NULL",
+				"*ast.Pattern p = (a)-[r]->(b)",
+				"*ast.PathPattern p = (a)-[r]->(b)",
+				"*ast.Variable p",
+				"*ast.NodePattern (a)",
+				"*ast.Variable a",
+				"*ast.RelationshipPattern -[r]->",
+				"*ast.Variable r",
+				"*ast.NodePattern (b)",
+				"*ast.Variable b",
+				"*ast.IsNull a.x IS NULL",
+				"*ast.PropertyAccess a.x",
+				"*ast.Variable a",
+				"*ast.Return RETURN [x IN a.xs WHERE x > 0] AS y",
+				"*ast.Projection [x IN a.xs WHERE x > 0] AS y",
+				"*ast.ProjectionItem [x IN a.xs WHERE x > 0] AS y",
+				"*ast.ListComprehension [x IN a.xs WHERE x > 0]",
+				"*ast.Variable x",
+				"*ast.PropertyAccess a.xs",
+				"*ast.Variable a",
+				"*ast.BinaryExpr x > 0",
+				"*ast.Variable x",
+				"*ast.IntegerLiteral 0",
+				"*ast.Variable y",
+			},
+		},
+		{
+			"UNWIND $xs AS x CALL db.labels() YIELD label AS l RETURN l ORDER BY l SKIP 1",
+			[]string{
+				"*ast.Query UNWIND $xs AS x CALL db.labels() YIELD label AS l RETURN l ORDER BY l SKIP 1",
+				"*ast.Unwind UNWIND $xs AS x",
+				"*ast.Parameter $xs",
+				"*ast.Variable x",
+				"*ast.InQueryCall CALL db.labels() YIELD label AS l",
+				"*ast.YieldItem label AS l",
+				"*ast.Variable l",
+				"*ast.Return RETURN l ORDER BY l SKIP 1",
+				"*ast.Projection l ORDER BY l SKIP 1",
+				"*ast.ProjectionItem l",
+				"*ast.Variable l",
+				"*ast.SortItem l",
+				"*ast.Variable l",
+				"*ast.IntegerLiteral 1",
+			},
+		},
+	}
+	for _, tt := range tests {
+		if got := texts(mustParse(t, tt.query)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.query, strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+		}
+	}
+}
+
+// tracer records the calls of Visit, with "(" and the node's type for each
+// node and ")" for nil.
+type tracer struct{ calls *[]string }
+
+func (v tracer) Visit(n ast.Node) ast.Visitor {
+	if n == nil {
+		*v.calls = append(*v.calls, ")")
+		return nil
+	}
+	*v.calls = append(*v.calls, "("+reflect.TypeOf(n).Elem().Name())
+	return v
+}
+
+func TestWalkVisitNil(t *testing.T) {
+	var calls []string
+	ast.Walk(tracer{&calls}, mustParse(t, "RETURN a.x, 1"))
+	want := "(Query (Return (Projection (ProjectionItem (PropertyAccess (Variable ) ) ) (ProjectionItem (IntegerLiteral ) ) ) ) )"
+	if got := strings.Join(calls, " "); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestInspectWithStack(t *testing.T) {
+	q := mustParse(t, "MATCH (a) WHERE a.x RETURN a")
+	var got []string
+	ast.InspectWithStack(q, func(n ast.Node, stack []ast.Node) bool {
+		var s []string
+		for _, n := range stack {
+			s = append(s, reflect.TypeOf(n).Elem().Name())
+		}
+		got = append(got, reflect.TypeOf(n).Elem().Name()+": "+strings.Join(s, " "))
+		// The children of the MATCH are skipped, and it must not be left
+		// on the stack of the nodes after it.
+		_, match := n.(*ast.Match)
+		return !match
+	})
+	want := []string{
+		"Query: ",
+		"Match: Query",
+		"Return: Query",
+		"Projection: Query Return",
+		"ProjectionItem: Query Return Projection",
+		"Variable: Query Return Projection ProjectionItem",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
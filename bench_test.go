@@ -0,0 +1,91 @@
+package cypher
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func BenchmarkParse(b *testing.B) {
+	for _, q := range readCorpus(b) {
+		q := q
+		b.Run(q.name, func(b *testing.B) {
+			b.SetBytes(int64(len(q.text)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				Parse(q.text)
+			}
+		})
+	}
+}
+
+func BenchmarkParseParallel(b *testing.B) {
+	queries := readCorpus(b)
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			Parse(queries[i%len(queries)].text)
+			i++
+		}
+	})
+}
+
+func BenchmarkParseSyntaxError(b *testing.B) {
+	for _, q := range []corpusQuery{
+		{"typo", "MACTH (n:Person) RETURN n.name"},
+		{"missing_expression", "MATCH (n:Person) WHERE n.age > RETURN n.name"},
+		{"unclosed", "MATCH (n:Person RETURN n.name ORDER BY"},
+	} {
+		q := q
+		b.Run(q.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				Parse(q.text)
+			}
+		})
+	}
+}
+
+func BenchmarkParseLarge(b *testing.B) {
+	var sb strings.Builder
+	sb.WriteString("UNWIND [")
+	for i := 0; i < 2000; i++ {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString("{id: 1, name: 'x', tags: ['a', 'b'], score: 1.5}")
+	}
+	sb.WriteString("] AS row\nMERGE (n:Item {id: row.id})\nSET n += row\nRETURN count(n)")
+	text := sb.String()
+
+	b.SetBytes(int64(len(text)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Parse(text)
+	}
+}
+
+func BenchmarkParseContext(b *testing.B) {
+	queries := readCorpus(b)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	opts := &Options{MaxBytes: 1 << 20, MaxTokens: 10000, MaxDepth: 64}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ParseContext(ctx, queries[i%len(queries)].text, opts)
+	}
+}
+
+func BenchmarkParseANTLR(b *testing.B) {
+	for _, q := range readCorpus(b) {
+		q := q
+		b.Run(q.name, func(b *testing.B) {
+			b.SetBytes(int64(len(q.text)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				antlrAccepts(q.text)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package cypher
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/a-poor/cypher/ast"
+)
+
+// Cache is a bounded cache of parsed queries, keyed by their text. When
+// it is full, the least recently used query is evicted to make room.
+//
+// A Cache may be used from several goroutines at once. All callers that
+// parse the same text while it is cached get the same *ast.Query, rather
+// than copies, which would cost more than parsing the text again. The
+// queries it returns are shared and must be treated as immutable: nothing
+// reachable from them may be modified. To change a cached query, rewrite
+// it with ast.Apply, which copies the nodes on the path to each change
+// and shares the rest, or change a copy made with ast.Clone. Functions in
+// the ast package never modify the trees they are given.
+//
+// Syntax errors are cached along with the query, so a query that failed
+// to parse fails again, with the same errors, without being parsed again.
+// Each caller gets its own copy of the ErrorList, which it may change.
+type Cache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	lru     list.List // of *cacheEntry, most recently used first
+	stats   CacheStats
+}
+
+// cacheEntry is the result of parsing a single query.
+type cacheEntry struct {
+	text string
+	q    *ast.Query
+	err  error
+}
+
+// CacheStats counts what a Cache has done since it was created.
+type CacheStats struct {
+	Hits      int64 // lookups that found the query in the cache
+	Misses    int64 // lookups that had to parse the query
+	Evictions int64 // queries dropped to make room for others
+}
+
+// NewCache returns a cache holding at most size queries. It panics if
+// size is not positive.
+func NewCache(size int) *Cache {
+	if size <= 0 {
+		panic("cypher: cache size must be positive")
+	}
+	return &Cache{size: size, entries: make(map[string]*list.Element)}
+}
+
+// Parse is like the package's Parse function, but returns the cached
+// result if the same text was parsed before. The query returned is shared
+// with other callers and must not be modified; the error is not shared.
+func (c *Cache) Parse(query string) (*ast.Query, error) {
+	c.mu.Lock()
+	if el, ok := c.entries[query]; ok {
+		c.lru.MoveToFront(el)
+		c.stats.Hits++
+		e := el.Value.(*cacheEntry)
+		c.mu.Unlock()
+		return e.q, copyErrors(e.err)
+	}
+	c.stats.Misses++
+	c.mu.Unlock()
+
+	// The lock is not held while parsing, so that a long query does not
+	// hold up the others. Two goroutines may then parse the same text at
+	// once; the first to finish adds its result, and the other returns
+	// that one instead of its own, so that callers always share a single
+	// tree for each text.
+	q, err := Parse(query)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[query]; ok {
+		c.lru.MoveToFront(el)
+		e := el.Value.(*cacheEntry)
+		return e.q, copyErrors(e.err)
+	}
+	c.entries[query] = c.lru.PushFront(&cacheEntry{text: query, q: q, err: err})
+	for c.lru.Len() > c.size {
+		el := c.lru.Back()
+		c.lru.Remove(el)
+		delete(c.entries, el.Value.(*cacheEntry).text)
+		c.stats.Evictions++
+	}
+	return q, copyErrors(err)
+}
+
+// copyErrors returns a copy of the errors of a parse, which shares nothing
+// with them.
+func copyErrors(err error) error {
+	errs, ok := err.(ErrorList)
+	if !ok {
+		return err
+	}
+	c := make(ErrorList, len(errs))
+	for i, e := range errs {
+		e := *e
+		e.Expected = append([]string(nil), e.Expected...)
+		c[i] = &e
+	}
+	return c
+}
+
+// Len returns the number of queries in the cache.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// Stats returns the cache's counters.
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
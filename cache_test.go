@@ -0,0 +1,110 @@
+package cypher
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/a-poor/cypher/ast"
+)
+
+func TestCacheEviction(t *testing.T) {
+	c := NewCache(2)
+	a1, _ := c.Parse("RETURN 1")
+	c.Parse("RETURN 2")
+	if a2, _ := c.Parse("RETURN 1"); a2 != a1 {
+		t.Error("cached query was parsed again")
+	}
+	c.Parse("RETURN 3") // evicts RETURN 2, the least recently used
+	if a3, _ := c.Parse("RETURN 1"); a3 != a1 {
+		t.Error("most recently used query was evicted")
+	}
+	c.Parse("RETURN 2")
+
+	want := CacheStats{Hits: 2, Misses: 4, Evictions: 2}
+	if got := c.Stats(); got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+	if n := c.Len(); n != 2 {
+		t.Errorf("Len() = %d, want 2", n)
+	}
+}
+
+func TestCacheErrors(t *testing.T) {
+	c := NewCache(1)
+	_, err1 := c.Parse("MACTH (n) RETURN n")
+	_, err2 := c.Parse("MACTH (n) RETURN n")
+	if err1 == nil || err2 == nil {
+		t.Fatalf("errors = %v, %v, want syntax errors", err1, err2)
+	}
+	if err1.Error() != err2.Error() {
+		t.Errorf("cached error %q differs from %q", err2, err1)
+	}
+}
+
+// Callers share cached queries, but rewriting them with ast.Apply or
+// changing a clone leaves the cached query alone, and each caller may
+// change its own errors.
+func TestCacheShared(t *testing.T) {
+	const query = "MATCH (n:Person) RETURN n.name"
+	c := NewCache(1)
+	q, _ := c.Parse(query)
+	want := ast.Clone(q)
+
+	rewritten, err := ast.Apply(q, func(cur *ast.Cursor) bool {
+		if v, ok := cur.Node().(*ast.Variable); ok {
+			cur.Replace(&ast.Variable{Name: v.Name + "2"})
+		}
+		return true
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clone := ast.Clone(q).(*ast.Query)
+	clone.Clauses = clone.Clauses[:1]
+	if got, _ := c.Parse(query); got != q || !ast.Equal(got, want, 0) {
+		t.Errorf("the cached query changed: %s", Format(got))
+	}
+	if got := Format(rewritten); got != "MATCH (n2:Person)\nRETURN n2.name" {
+		t.Errorf("rewritten query = %q", got)
+	}
+
+	_, err1 := c.Parse("MACTH (n) RETURN n")
+	errs := err1.(ErrorList)
+	errs[0].Msg = "changed"
+	errs[0].Expected[0] = "changed"
+	_, err2 := c.Parse("MACTH (n) RETURN n")
+	if e := err2.(ErrorList)[0]; e.Msg == "changed" || e.Expected[0] == "changed" {
+		t.Errorf("changing the errors of one call changed those of the next: %v", err2)
+	}
+}
+
+// Goroutines parsing the same text must all get the same tree.
+func TestCacheConcurrent(t *testing.T) {
+	queries := readCorpus(t)
+	c := NewCache(len(queries))
+	results := make([][]interface{}, 8)
+
+	var wg sync.WaitGroup
+	for g := range results {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for _, q := range queries {
+				tree, _ := c.Parse(q.text)
+				results[g] = append(results[g], tree)
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	for g := 1; g < len(results); g++ {
+		for i, q := range queries {
+			if results[g][i] != results[0][i] {
+				t.Errorf("%s: goroutines got different trees", q.name)
+			}
+		}
+	}
+	if s := c.Stats(); s.Hits+s.Misses != int64(len(results)*len(queries)) || s.Evictions != 0 {
+		t.Errorf("Stats() = %+v", s)
+	}
+}
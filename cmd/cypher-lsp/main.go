@@ -0,0 +1,77 @@
+// Command cypher-lsp is a language server for Cypher, for editors that
+// speak the Language Server Protocol.
+//
+// Usage:
+//
+//	cypher-lsp [-rules list] [-width n] [-schema file]
+//
+// It talks JSON-RPC over its standard input and output, as package lsp
+// describes, and logs nothing but fatal errors to standard error. The
+// -rules flag names the lint rules whose findings are shown as warnings,
+// all of them by default, and -width sets the width documents are
+// formatted to, as cypher.Style.MaxWidth does. The -schema flag names a
+// JSON file with the labels, relationship types and property keys of a
+// graph, to suggest as completions, in the fields of complete.Schema:
+//
+//	{"Labels": ["Person"], "RelationshipTypes": ["KNOWS"], "PropertyKeys": ["name"]}
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/a-poor/cypher"
+	"github.com/a-poor/cypher/complete"
+	"github.com/a-poor/cypher/lint"
+	"github.com/a-poor/cypher/lsp"
+)
+
+func main() {
+	names := flag.String("rules", "", "warn only about the rules in the comma-separated `list`")
+	width := flag.Int("width", 0, "format lines to at most `n` columns")
+	schema := flag.String("schema", "", "suggest the names of the graph described in JSON in `file`")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: cypher-lsp [-rules list] [-width n] [-schema file]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() > 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	opts := &lsp.Options{}
+	if *names != "" {
+		opts.Rules = []*lint.Rule{}
+		for _, name := range strings.Split(*names, ",") {
+			r := lint.Lookup(strings.TrimSpace(name))
+			if r == nil {
+				fmt.Fprintf(os.Stderr, "cypher-lsp: unknown rule %q\n", name)
+				os.Exit(2)
+			}
+			opts.Rules = append(opts.Rules, r)
+		}
+	}
+	if *width > 0 {
+		opts.Style = &cypher.Style{MaxWidth: *width}
+	}
+	if *schema != "" {
+		data, err := os.ReadFile(*schema)
+		if err == nil {
+			opts.Schema = &complete.Schema{}
+			err = json.Unmarshal(data, opts.Schema)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "cypher-lsp:", err)
+			os.Exit(2)
+		}
+	}
+
+	if err := lsp.NewServer(opts).Serve(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "cypher-lsp:", err)
+		os.Exit(1)
+	}
+}
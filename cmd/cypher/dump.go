@@ -0,0 +1,308 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/antlr/antlr4/runtime/Go/antlr"
+
+	"github.com/a-poor/cypher/ast"
+	antlrparser "github.com/a-poor/cypher/parser"
+)
+
+// dumpNode is a node of a tree to dump, taken from an abstract syntax
+// tree or from the parse tree of the ANTLR parser.
+type dumpNode struct {
+	Label    string      `json:"label,omitempty"` // the field of the parent the node is in, if any
+	Type     string      `json:"type"`            // the node's Go type, rule or token name
+	Span     string      `json:"span"`
+	Text     string      `json:"text"`
+	Attrs    []dumpAttr  `json:"attrs,omitempty"` // fields that are not nodes
+	Children []*dumpNode `json:"children,omitempty"`
+	Token    bool        `json:"token,omitempty"` // whether it is a token of the parse tree
+}
+
+type dumpAttr struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+var nodeType = reflect.TypeOf((*ast.Node)(nil)).Elem()
+
+// astDump returns the tree to dump for an AST node. Its children are the
+// nodes ast.Walk visits, in that order, each labelled with the field of
+// its parent that holds it; the attributes of a node are the fields that
+// are set and hold no nodes, such as a Name, Op or Value.
+func astDump(n ast.Node) *dumpNode {
+	type open struct {
+		n ast.Node
+		d *dumpNode
+	}
+	var root *dumpNode
+	var stack []open
+	ast.Inspect(n, func(n ast.Node) bool {
+		if n == nil {
+			stack = stack[:len(stack)-1]
+			return false
+		}
+		d := &dumpNode{Type: reflect.TypeOf(n).Elem().Name(), Span: n.Span().String(), Text: n.Text(), Attrs: astAttrs(n)}
+		if len(stack) == 0 {
+			root = d
+		} else {
+			parent := stack[len(stack)-1]
+			d.Label = fieldOf(parent.n, n)
+			parent.d.Children = append(parent.d.Children, d)
+		}
+		stack = append(stack, open{n, d})
+		return true
+	})
+	return root
+}
+
+// astAttrs returns the attributes of a node: its fields that are set,
+// other than those that hold nodes.
+func astAttrs(n ast.Node) []dumpAttr {
+	var attrs []dumpAttr
+	v := reflect.ValueOf(n).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		f, fv := v.Type().Field(i), v.Field(i)
+		if f.Anonymous || f.PkgPath != "" || holdsNodes(f.Type) || fv.IsZero() {
+			continue
+		}
+		attrs = append(attrs, dumpAttr{f.Name, attrValue(fv)})
+	}
+	return attrs
+}
+
+func holdsNodes(t reflect.Type) bool {
+	return t.Implements(nodeType) || t.Kind() == reflect.Slice && t.Elem().Implements(nodeType)
+}
+
+// fieldOf returns the name of the field of parent that holds child, with
+// its index if the field is a slice, or "" if none does.
+func fieldOf(parent, child ast.Node) string {
+	v := reflect.ValueOf(parent).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		f, fv := v.Type().Field(i), v.Field(i)
+		if f.Anonymous || f.PkgPath != "" {
+			continue
+		}
+		switch {
+		case f.Type.Implements(nodeType):
+			if !fv.IsNil() && fv.Interface() == child {
+				return f.Name
+			}
+		case f.Type.Kind() == reflect.Slice && f.Type.Elem().Implements(nodeType):
+			for j := 0; j < fv.Len(); j++ {
+				if c := fv.Index(j); !c.IsNil() && c.Interface() == child {
+					return fmt.Sprintf("%s[%d]", f.Name, j)
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func attrValue(v reflect.Value) string {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	if s, ok := v.Interface().(fmt.Stringer); ok {
+		return s.String()
+	}
+	if v.Kind() == reflect.String {
+		return strconv.Quote(v.String())
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// antlrDump parses a query with the ANTLR parser, returning the tree to
+// dump for its parse tree, and the syntax errors the parser reports.
+// Tokens of whitespace are left out.
+func antlrDump(query string) (*dumpNode, []string) {
+	errs := &errorCollector{DefaultErrorListener: antlr.NewDefaultErrorListener()}
+	lexer := antlrparser.NewCypherLexer(antlr.NewInputStream(query))
+	lexer.RemoveErrorListeners()
+	lexer.AddErrorListener(errs)
+	p := antlrparser.NewCypherParser(antlr.NewCommonTokenStream(lexer, antlr.TokenDefaultChannel))
+	p.RemoveErrorListeners()
+	p.AddErrorListener(errs)
+	return antlrNode(p, "", p.OC_Cypher()), errs.msgs
+}
+
+func antlrNode(p *antlrparser.CypherParser, label string, t antlr.Tree) *dumpNode {
+	switch t := t.(type) {
+	case antlr.TerminalNode:
+		tok := t.GetSymbol()
+		if tok.GetTokenType() == antlrparser.CypherParserSP {
+			return nil
+		}
+		d := &dumpNode{Label: label, Type: tokenName(p, tok.GetTokenType()), Text: tok.GetText(), Token: true}
+		if _, ok := t.(antlr.ErrorNode); ok {
+			d.Type = "error"
+		}
+		d.Span = tokenSpan(tok, tok)
+		return d
+	case antlr.ParserRuleContext:
+		d := &dumpNode{Label: label, Type: p.RuleNames[t.GetRuleIndex()], Text: t.GetText()}
+		if start, stop := t.GetStart(), t.GetStop(); start != nil && stop != nil {
+			d.Span = tokenSpan(start, stop)
+		}
+		for _, c := range t.GetChildren() {
+			if c := antlrNode(p, "", c); c != nil {
+				d.Children = append(d.Children, c)
+			}
+		}
+		return d
+	}
+	return nil
+}
+
+// tokenName returns the name of a token type of the ANTLR parser: its
+// name in the grammar, or its literal text for the tokens without one.
+func tokenName(p *antlrparser.CypherParser, typ int) string {
+	if typ == antlr.TokenEOF {
+		return "EOF"
+	}
+	if typ >= 0 && typ < len(p.SymbolicNames) && p.SymbolicNames[typ] != "" {
+		return p.SymbolicNames[typ]
+	}
+	if typ >= 0 && typ < len(p.LiteralNames) {
+		return p.LiteralNames[typ]
+	}
+	return strconv.Itoa(typ)
+}
+
+// tokenSpan returns the span from the start of one token to the end of
+// another, as ast.Span.String writes spans.
+func tokenSpan(start, stop antlr.Token) string {
+	text := stop.GetText()
+	if stop.GetTokenType() == antlr.TokenEOF {
+		text = ""
+	}
+	line, col := stop.GetLine(), stop.GetColumn()+1
+	if i := strings.LastIndexByte(text, '\n'); i >= 0 {
+		line += strings.Count(text, "\n")
+		col = 1
+		text = text[i+1:]
+	}
+	return fmt.Sprintf("%d:%d-%d:%d", start.GetLine(), start.GetColumn()+1, line, col+utf8.RuneCountInString(text))
+}
+
+type errorCollector struct {
+	*antlr.DefaultErrorListener
+	msgs []string
+}
+
+func (l *errorCollector) SyntaxError(_ antlr.Recognizer, _ interface{}, line, column int, msg string, _ antlr.RecognitionException) {
+	l.msgs = append(l.msgs, fmt.Sprintf("%d:%d: %s", line, column+1, msg))
+}
+
+// snippet returns text on a single line, shortened to about max
+// characters.
+func snippet(text string, max int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) > max {
+		text = string([]rune(text)[:max-1]) + "…"
+	}
+	return text
+}
+
+// writeTree writes a tree with a node on each line, indented under its
+// parent.
+func writeTree(w io.Writer, d *dumpNode, depth int) {
+	var sb strings.Builder
+	sb.WriteString(strings.Repeat("  ", depth))
+	if d.Label != "" {
+		sb.WriteString(d.Label + ": ")
+	}
+	sb.WriteString(d.Type)
+	for _, a := range d.Attrs {
+		fmt.Fprintf(&sb, " %s=%s", a.Name, a.Value)
+	}
+	fmt.Fprintf(&sb, " %s %s\n", d.Span, strconv.Quote(snippet(d.Text, 40)))
+	io.WriteString(w, sb.String())
+	for _, c := range d.Children {
+		writeTree(w, c, depth+1)
+	}
+}
+
+// writeSexpr writes a tree as an S-expression: "(Type :Attr value child
+// ...)", with each child on a line of its own, and tokens as their quoted
+// text.
+func writeSexpr(w io.Writer, d *dumpNode, depth int) {
+	if d.Token {
+		io.WriteString(w, strconv.Quote(d.Text))
+		return
+	}
+	io.WriteString(w, "("+d.Type)
+	for _, a := range d.Attrs {
+		fmt.Fprintf(w, " :%s %s", a.Name, a.Value)
+	}
+	for _, c := range d.Children {
+		fmt.Fprintf(w, "\n%s", strings.Repeat("  ", depth+1))
+		writeSexpr(w, c, depth+1)
+	}
+	io.WriteString(w, ")")
+	if depth == 0 {
+		io.WriteString(w, "\n")
+	}
+}
+
+// writeDot writes a tree as a Graphviz graph, with each node labelled
+// with its type, attributes, span and text, and each edge with the field
+// of the child.
+func writeDot(w io.Writer, name string, d *dumpNode) {
+	fmt.Fprintf(w, "digraph %s {\n", strconv.Quote(name))
+	fmt.Fprintln(w, "\tnode [shape=box, fontname=\"monospace\"];")
+	n := 0
+	var node func(d *dumpNode) int
+	node = func(d *dumpNode) int {
+		id := n
+		n++
+		label := []string{d.Type}
+		for _, a := range d.Attrs {
+			label = append(label, a.Name+"="+a.Value)
+		}
+		label = append(label, d.Span, snippet(d.Text, 30))
+		shape := ""
+		if d.Token {
+			shape = ", shape=ellipse"
+		}
+		fmt.Fprintf(w, "\tn%d [label=%s%s];\n", id, dotString(strings.Join(label, "\n")), shape)
+		for _, c := range d.Children {
+			cid := node(c)
+			if c.Label != "" {
+				fmt.Fprintf(w, "\tn%d -> n%d [label=%s];\n", id, cid, dotString(c.Label))
+			} else {
+				fmt.Fprintf(w, "\tn%d -> n%d;\n", id, cid)
+			}
+		}
+		return id
+	}
+	node(d)
+	fmt.Fprintln(w, "}")
+}
+
+// dotString quotes s for Graphviz, which takes "\n" for a line break but
+// no other escapes than "\"" and "\\".
+func dotString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + strings.ReplaceAll(s, "\n", `\n`) + `"`
+}
+
+// writeJSON writes a tree as indented JSON.
+func writeJSON(w io.Writer, d *dumpNode) error {
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
@@ -0,0 +1,479 @@
+// Command cypher works with Cypher queries and scripts.
+//
+// Usage:
+//
+//	cypher fmt [-l] [-d] [-w] [style flags] [path ...]
+//	cypher parse [-format tree|sexpr|json|dot] [-antlr] [path ...]
+//	cypher check [path ...]
+//	cypher lint [-rules list] [-list] [path ...]
+//	cypher highlight [-html] [path ...]
+//
+// Each command reads the files it is given, and the files ending in
+// ".cypher" in the directories it is given and those under them, or
+// standard input if it is given none or "-".
+//
+// The fmt command prints scripts as cypher.PrettyScript does. With -l it
+// lists the files whose formatting differs, with -d it prints the
+// differences, and with -w it rewrites the files. The style flags set the
+// fields of cypher.Style.
+//
+// The parse command parses each input as a script, and prints its syntax
+// tree, or with -antlr the parse tree of the ANTLR parser generated from
+// Cypher.g4, which is the reference for the grammar. That grammar has no
+// scripts, so with -antlr each input must be a single query. The -format
+// flag says how: as an indented tree with the type, span and text of each
+// node, as an S-expression, as JSON, or as a Graphviz graph for dot. The
+// JSON of a syntax tree is in the encoding of ast.MarshalJSON, and -json is
+// short for -format json.
+//
+// The check command reports the syntax errors of scripts, and the semantic
+// errors that sema.Check finds in their queries.
+//
+// The lint command reports what the rules of package lint find in the
+// queries of scripts, using all the rules or those named by -rules. With
+// -list it lists the rules.
+//
+// The highlight command prints scripts with their tokens coloured for a
+// terminal, as cypher.HighlightANSI does, or with -html as HTML, as
+// cypher.HighlightHTML does.
+//
+// Errors are printed to standard error. The exit status is 1 if there are
+// syntax or semantic errors, if lint reports anything, or if fmt -l or -d
+// finds a file that is not formatted, so that the commands can be used in
+// pre-commit hooks. It is 2 for bad arguments and files that cannot be
+// read or written.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/a-poor/cypher"
+	"github.com/a-poor/cypher/ast"
+	"github.com/a-poor/cypher/lint"
+	"github.com/a-poor/cypher/sema"
+)
+
+const usage = `usage: cypher <command> [arguments]
+
+Commands:
+	fmt        format scripts
+	parse      print the syntax tree of a script
+	check      report syntax and semantic errors
+	lint       report likely mistakes and slow patterns
+	highlight  print scripts with syntax highlighting
+
+Run "cypher <command> -h" for the arguments of a command.
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs the command line args, returning the exit status.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "--help" {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	c := &command{stdin: stdin, stdout: stdout, stderr: stderr}
+	switch cmd, args := args[0], args[1:]; cmd {
+	case "fmt":
+		return c.fmt(args)
+	case "parse":
+		return c.parse(args)
+	case "check":
+		return c.check(args)
+	case "lint":
+		return c.lint(args)
+	case "highlight":
+		return c.highlight(args)
+	default:
+		fmt.Fprintf(stderr, "cypher: unknown command %q\n", cmd)
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+}
+
+// command holds what a command reads from and writes to.
+type command struct {
+	stdin          io.Reader
+	stdout, stderr io.Writer
+
+	reported int // number of diagnostics written to stderr
+}
+
+// flags returns a flag set for the named command, with its usage line.
+func (c *command) flags(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(c.stderr, "usage: cypher %s %s\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// fmt runs the fmt command.
+func (c *command) fmt(args []string) int {
+	fs := c.flags("fmt", "[-l] [-d] [-w] [style flags] [path ...]")
+	list := fs.Bool("l", false, "list files whose formatting differs")
+	showDiff := fs.Bool("d", false, "print the differences made by formatting")
+	write := fs.Bool("w", false, "write the result to the files, rather than to standard output")
+	var style cypher.Style
+	fs.IntVar(&style.MaxWidth, "width", 0, "break clauses wider than `n` columns; 0 for no limit")
+	fs.IntVar(&style.Indent, "indent", 2, "indent by `n` spaces")
+	fs.BoolVar(&style.LowerKeywords, "lower", false, "write keywords in lower case")
+	quote := fs.String("quote", "", "write strings in `quote`: single, double, or as written if empty")
+	fs.BoolVar(&style.MapSpaces, "mapspaces", false, "put spaces inside the braces of maps")
+	fs.BoolVar(&style.ArrowSpaces, "arrowspaces", false, "put spaces around the relationships of paths")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	switch *quote {
+	case "":
+	case "single":
+		style.Quote = cypher.QuoteSingle
+	case "double":
+		style.Quote = cypher.QuoteDouble
+	default:
+		fmt.Fprintf(c.stderr, "cypher: bad -quote %q, want single or double\n", *quote)
+		return 2
+	}
+
+	inputs, err := c.inputs(fs.Args())
+	if err != nil {
+		fmt.Fprintln(c.stderr, "cypher:", err)
+		return 2
+	}
+	status := 0
+	for _, in := range inputs {
+		if *write && in.stdin {
+			fmt.Fprintln(c.stderr, "cypher: cannot use -w with standard input")
+			return 2
+		}
+		out, err := cypher.PrettyScript(in.text, &style)
+		if err != nil {
+			c.syntaxErrors(in, err)
+			status = 1
+			continue
+		}
+		if !*list && !*showDiff && !*write {
+			io.WriteString(c.stdout, out)
+			continue
+		}
+		if out == in.text {
+			continue
+		}
+		if *list {
+			fmt.Fprintln(c.stdout, in.name)
+		}
+		if *showDiff {
+			io.WriteString(c.stdout, diff(in.name, in.text, out))
+		}
+		if *write {
+			if err := writeFile(in.name, out); err != nil {
+				fmt.Fprintln(c.stderr, "cypher:", err)
+				return 2
+			}
+		} else {
+			status = 1
+		}
+	}
+	return status
+}
+
+// writeFile replaces the contents of a file, keeping its permissions.
+func writeFile(name, text string) error {
+	info, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(name, []byte(text), info.Mode().Perm())
+}
+
+// parse runs the parse command.
+func (c *command) parse(args []string) int {
+	fs := c.flags("parse", "[-format tree|sexpr|json|dot] [-antlr] [path ...]")
+	format := fs.String("format", "tree", "print the tree as a `format`: tree, sexpr, json or dot")
+	asJSON := fs.Bool("json", false, "print the tree as JSON, as -format json does")
+	useANTLR := fs.Bool("antlr", false, "print the parse tree of the ANTLR parser, rather than the syntax tree")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *asJSON {
+		*format = "json"
+	}
+	switch *format {
+	case "tree", "sexpr", "json", "dot":
+	default:
+		fmt.Fprintf(c.stderr, "cypher: bad -format %q, want tree, sexpr, json or dot\n", *format)
+		return 2
+	}
+	inputs, err := c.inputs(fs.Args())
+	if err != nil {
+		fmt.Fprintln(c.stderr, "cypher:", err)
+		return 2
+	}
+	status := 0
+	for _, in := range inputs {
+		var d *dumpNode
+		var script *ast.Script
+		if *useANTLR {
+			var errs []string
+			d, errs = antlrDump(in.text)
+			for _, e := range errs {
+				fmt.Fprintf(c.stderr, "%s:%s\n", in.name, e)
+				status = 1
+			}
+		} else {
+			tree, err := cypher.ParseScriptCST(in.text)
+			if err != nil {
+				c.syntaxErrors(in, err)
+				status = 1
+			}
+			script = tree.AST().(*ast.Script)
+			d = astDump(script)
+		}
+
+		if len(inputs) > 1 && (*format == "tree" || *format == "sexpr") {
+			fmt.Fprintf(c.stdout, "%s:\n", in.name)
+		}
+		switch *format {
+		case "tree":
+			writeTree(c.stdout, d, 0)
+		case "sexpr":
+			writeSexpr(c.stdout, d, 0)
+		case "dot":
+			writeDot(c.stdout, in.name, d)
+		case "json":
+			if script != nil {
+				// The syntax tree has an encoding of its own.
+				err = writeASTJSON(c.stdout, script)
+			} else {
+				err = writeJSON(c.stdout, d)
+			}
+			if err != nil {
+				fmt.Fprintln(c.stderr, "cypher:", err)
+				return 2
+			}
+		}
+	}
+	return status
+}
+
+// writeASTJSON writes a syntax tree as indented JSON, in the encoding of
+// ast.MarshalJSON.
+func writeASTJSON(w io.Writer, root ast.Node) error {
+	data, err := ast.MarshalJSON(root)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	json.Indent(&buf, data, "", "  ")
+	buf.WriteByte('\n')
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// check runs the check command.
+func (c *command) check(args []string) int {
+	fs := c.flags("check", "[path ...]")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	return c.eachQuery(fs.Args(), func(in input, q *ast.Query) {
+		for _, e := range sema.Check(q).Errors {
+			c.report(in, e.Span, e.Msg)
+		}
+	})
+}
+
+// lint runs the lint command.
+func (c *command) lint(args []string) int {
+	fs := c.flags("lint", "[-rules list] [-list] [path ...]")
+	names := fs.String("rules", "", "run only the rules in the comma-separated `list`")
+	listRules := fs.Bool("list", false, "list the rules and what they report")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *listRules {
+		for _, r := range lint.Rules {
+			fmt.Fprintf(c.stdout, "%s\n\t%s\n", r.Name, r.Doc)
+		}
+		return 0
+	}
+	rules := lint.Rules
+	if *names != "" {
+		rules = nil
+		for _, name := range strings.Split(*names, ",") {
+			r := lint.Lookup(strings.TrimSpace(name))
+			if r == nil {
+				fmt.Fprintf(c.stderr, "cypher: unknown rule %q; run \"cypher lint -list\" for the rules\n", name)
+				return 2
+			}
+			rules = append(rules, r)
+		}
+	}
+	return c.eachQuery(fs.Args(), func(in input, q *ast.Query) {
+		for _, d := range lint.Run(q, nil, rules) {
+			c.report(in, d.Span, fmt.Sprintf("%s (%s)", d.Msg, d.Rule))
+		}
+	})
+}
+
+// highlight runs the highlight command.
+func (c *command) highlight(args []string) int {
+	fs := c.flags("highlight", "[-html] [path ...]")
+	asHTML := fs.Bool("html", false, "print HTML with a CSS class for each kind of token, rather than ANSI colours")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	inputs, err := c.inputs(fs.Args())
+	if err != nil {
+		fmt.Fprintln(c.stderr, "cypher:", err)
+		return 2
+	}
+	for _, in := range inputs {
+		if *asHTML {
+			io.WriteString(c.stdout, cypher.HighlightHTML(in.text))
+		} else {
+			io.WriteString(c.stdout, cypher.HighlightANSI(in.text))
+		}
+	}
+	return 0
+}
+
+// eachQuery parses the scripts of the paths, and calls f for each of
+// their queries, unless they have syntax errors, which it reports. It
+// returns the exit status: 1 if anything was reported.
+func (c *command) eachQuery(paths []string, f func(input, *ast.Query)) int {
+	inputs, err := c.inputs(paths)
+	if err != nil {
+		fmt.Fprintln(c.stderr, "cypher:", err)
+		return 2
+	}
+	for _, in := range inputs {
+		script, err := cypher.ParseScript(in.text)
+		if err != nil {
+			c.syntaxErrors(in, err)
+			continue
+		}
+		for _, stmt := range script.Statements {
+			if q, ok := stmt.(*ast.Query); ok {
+				f(in, q)
+			}
+		}
+	}
+	if c.reported > 0 {
+		return 1
+	}
+	return 0
+}
+
+// syntaxErrors reports the errors returned by parsing an input.
+func (c *command) syntaxErrors(in input, err error) {
+	var errs cypher.ErrorList
+	if !errors.As(err, &errs) {
+		fmt.Fprintf(c.stderr, "%s: %v\n", in.name, err)
+		c.reported++
+		return
+	}
+	for _, e := range errs {
+		c.diagnostic(in, e)
+	}
+}
+
+// report reports a problem at a span of an input.
+func (c *command) report(in input, span ast.Span, msg string) {
+	start, end := span.Start.Offset, span.End.Offset
+	c.diagnostic(in, &cypher.ParseError{
+		Line:        span.Start.Line,
+		Column:      span.Start.Column,
+		UTF16Column: span.Start.UTF16Column,
+		Offset:      start,
+		Token:       in.text[start:end],
+		Msg:         msg,
+	})
+}
+
+// diagnostic writes an error as ParseError.Render does, prefixed with the
+// name of its input, and separated from the one before by a blank line.
+func (c *command) diagnostic(in input, e *cypher.ParseError) {
+	if c.reported > 0 {
+		fmt.Fprintln(c.stderr)
+	}
+	fmt.Fprintf(c.stderr, "%s:%s", in.name, e.Render(in.text))
+	c.reported++
+}
+
+// input is a script to work on.
+type input struct {
+	name  string
+	text  string
+	stdin bool
+}
+
+// inputs reads the files of paths, with the directories among them
+// replaced by the ".cypher" files in and under them. It reads standard
+// input for a path of "-", or if there are none.
+func (c *command) inputs(paths []string) ([]input, error) {
+	if len(paths) == 0 {
+		paths = []string{"-"}
+	}
+	var inputs []input
+	for _, path := range paths {
+		if path == "-" {
+			b, err := io.ReadAll(c.stdin)
+			if err != nil {
+				return nil, err
+			}
+			inputs = append(inputs, input{name: "<stdin>", text: string(b), stdin: true})
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		files := []string{path}
+		if info.IsDir() {
+			if files, err = cypherFiles(path); err != nil {
+				return nil, err
+			}
+		}
+		for _, name := range files {
+			b, err := os.ReadFile(name)
+			if err != nil {
+				return nil, err
+			}
+			inputs = append(inputs, input{name: name, text: string(b)})
+		}
+	}
+	return inputs, nil
+}
+
+// cypherFiles returns the names of the ".cypher" files in and under a
+// directory, in lexical order.
+func cypherFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.HasSuffix(path, ".cypher") {
+			files = append(files, path)
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFiles writes files to a new directory, returning its name.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, text := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func runCmd(stdin string, args ...string) (status int, stdout, stderr string) {
+	var out, errOut bytes.Buffer
+	status = run(args, strings.NewReader(stdin), &out, &errOut)
+	return status, out.String(), errOut.String()
+}
+
+func TestFmt(t *testing.T) {
+	status, out, errOut := runCmd("match (n)  return n;\nreturn 1", "fmt", "-lower")
+	if want := "match (n)\nreturn n;\n\nreturn 1\n"; status != 0 || out != want {
+		t.Errorf("fmt = %d, %q, %q; want 0, %q", status, out, errOut, want)
+	}
+
+	dir := writeFiles(t, map[string]string{
+		"good.cypher":    "MATCH (n)\nRETURN n\n",
+		"sub/bad.cypher": "MATCH (n) RETURN n\n",
+		"other.txt":      "MATCH (n) RETURN n\n",
+	})
+	bad := filepath.Join(dir, "sub", "bad.cypher")
+
+	status, out, _ = runCmd("", "fmt", "-l", dir)
+	if status != 1 || out != bad+"\n" {
+		t.Errorf("fmt -l = %d, %q; want 1, %q", status, out, bad+"\n")
+	}
+
+	status, out, _ = runCmd("", "fmt", "-d", dir)
+	want := "--- " + bad + "\n+++ " + bad + "\n@@ -1,1 +1,2 @@\n-MATCH (n) RETURN n\n+MATCH (n)\n+RETURN n\n"
+	if status != 1 || out != want {
+		t.Errorf("fmt -d = %d, %q; want 1, %q", status, out, want)
+	}
+
+	if status, _, errOut := runCmd("", "fmt", "-w", dir); status != 0 {
+		t.Fatalf("fmt -w = %d: %s", status, errOut)
+	}
+	if b, _ := os.ReadFile(bad); string(b) != "MATCH (n)\nRETURN n\n" {
+		t.Errorf("fmt -w wrote %q", b)
+	}
+	if status, out, _ := runCmd("", "fmt", "-l", dir); status != 0 || out != "" {
+		t.Errorf("fmt -l after -w = %d, %q; want 0, nothing", status, out)
+	}
+
+	if status, _, _ := runCmd("", "fmt", "-w"); status != 2 {
+		t.Errorf("fmt -w of standard input = %d, want 2", status)
+	}
+	if status, _, errOut := runCmd("MATCH (n RETURN n", "fmt"); status != 1 || !strings.Contains(errOut, `<stdin>:1:10: unexpected "RETURN"`) {
+		t.Errorf("fmt of a syntax error = %d, %q", status, errOut)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	if status, _, errOut := runCmd("MATCH (n) RETURN n;\n:param x => 1\nRETURN $x", "check"); status != 0 {
+		t.Errorf("check of a good script = %d: %s", status, errOut)
+	}
+
+	status, _, errOut := runCmd("RETURN 1;\nMATCH (n) RETURN m", "check")
+	want := "<stdin>:2:18: variable `m` is not declared\n" +
+		"  |\n" +
+		"2 | MATCH (n) RETURN m\n" +
+		"  |                  ^\n"
+	if status != 1 || errOut != want {
+		t.Errorf("check = %d, %q; want 1, %q", status, errOut, want)
+	}
+
+	if status, _, _ := runCmd("MATCH (n RETURN n", "check"); status != 1 {
+		t.Errorf("check of a syntax error = %d, want 1", status)
+	}
+	if status, _, _ := runCmd("", "check", "no/such/file.cypher"); status != 2 {
+		t.Errorf("check of a missing file = %d, want 2", status)
+	}
+}
+
+func TestLint(t *testing.T) {
+	query := "MATCH (a:A)-[r]->(b:B) WHERE a.x = null RETURN a, b"
+	status, _, errOut := runCmd(query, "lint")
+	for _, want := range []string{
+		"<stdin>:1:14: variable `r` is declared but never used (unused-variable)",
+		"<stdin>:1:30: comparison with null using = is always null; use IS NULL (null-comparison)",
+	} {
+		if !strings.Contains(errOut, want) {
+			t.Errorf("lint output does not contain %q:\n%s", want, errOut)
+		}
+	}
+	if status != 1 {
+		t.Errorf("lint = %d, want 1", status)
+	}
+
+	status, _, errOut = runCmd(query, "lint", "-rules", "unused-variable")
+	if status != 1 || strings.Contains(errOut, "null-comparison") {
+		t.Errorf("lint -rules unused-variable = %d:\n%s", status, errOut)
+	}
+	if status, _, _ := runCmd(query, "lint", "-rules", "no-such-rule"); status != 2 {
+		t.Errorf("lint of an unknown rule = %d, want 2", status)
+	}
+	if status, _, errOut := runCmd("MATCH (a:A)-->(b:B) RETURN a, b", "lint"); status != 0 {
+		t.Errorf("lint of a good query = %d:\n%s", status, errOut)
+	}
+
+	status, out, _ := runCmd("", "lint", "-list")
+	if status != 0 || !strings.Contains(out, "cartesian-product\n") {
+		t.Errorf("lint -list = %d, %q", status, out)
+	}
+}
+
+func TestParse(t *testing.T) {
+	const query = "MATCH (n:Person) RETURN n.name"
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"parse"}, `Script 1:1-1:31 "MATCH (n:Person) RETURN n.name"
+  Statements[0]: Query 1:1-1:31 "MATCH (n:Person) RETURN n.name"
+    Clauses[0]: Match 1:1-1:17 "MATCH (n:Person)"
+      Pattern: Pattern 1:7-1:17 "(n:Person)"
+        Paths[0]: PathPattern 1:7-1:17 "(n:Person)"
+          Nodes[0]: NodePattern Labels=[Person] 1:7-1:17 "(n:Person)"
+            Variable: Variable Name="n" 1:8-1:9 "n"
+    Clauses[1]: Return 1:18-1:31 "RETURN n.name"
+      Projection: Projection 1:25-1:31 "n.name"
+        Items[0]: ProjectionItem 1:25-1:31 "n.name"
+          Expr: PropertyAccess Key="name" 1:25-1:31 "n.name"
+            X: Variable Name="n" 1:25-1:26 "n"
+`},
+		{[]string{"parse", "-format", "sexpr"}, `(Script
+  (Query
+    (Match
+      (Pattern
+        (PathPattern
+          (NodePattern :Labels [Person]
+            (Variable :Name "n")))))
+    (Return
+      (Projection
+        (ProjectionItem
+          (PropertyAccess :Key "name"
+            (Variable :Name "n")))))))
+`},
+	}
+	for _, tt := range tests {
+		status, out, errOut := runCmd(query, tt.args...)
+		if status != 0 || out != tt.want {
+			t.Errorf("cypher %s = %d, %s\n%s\nwant\n%s", strings.Join(tt.args, " "), status, errOut, out, tt.want)
+		}
+	}
+
+	// The ANTLR parse tree has a node for each rule, and the tokens
+	// other than whitespace.
+	status, out, _ := runCmd(query, "parse", "-antlr", "-format", "sexpr")
+	for _, want := range []string{
+		"(oC_Cypher\n  (oC_Statement\n",
+		"(oC_Match\n                \"MATCH\"\n                (oC_Pattern\n",
+		"(oC_SymbolicName\n                                    \"Person\")",
+		"\n  \"<EOF>\")\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("parse -antlr -format sexpr does not contain %q:\n%s", want, out)
+		}
+	}
+	if status != 0 {
+		t.Errorf("parse -antlr -format sexpr = %d", status)
+	}
+
+	// Children come in the order ast.Walk visits them, which is the order
+	// of the source text, not that of the fields that hold them.
+	status, out, _ = runCmd("MATCH p = (a)-[r]->(b) RETURN p", "parse")
+	want := `        Paths[0]: PathPattern 1:7-1:23 "p = (a)-[r]->(b)"
+          Variable: Variable Name="p" 1:7-1:8 "p"
+          Nodes[0]: NodePattern 1:11-1:14 "(a)"
+            Variable: Variable Name="a" 1:12-1:13 "a"
+          Relationships[0]: RelationshipPattern Direction=--> 1:14-1:20 "-[r]->"
+            Variable: Variable Name="r" 1:16-1:17 "r"
+          Nodes[1]: NodePattern 1:20-1:23 "(b)"
+`
+	if status != 0 || !strings.Contains(out, want) {
+		t.Errorf("parse of a path = %d, %s\nwant it to contain\n%s", status, out, want)
+	}
+
+	status, out, _ = runCmd(query, "parse", "-json")
+	if status != 0 || !strings.Contains(out, `"root": {
+    "type": "Script",`) {
+		t.Errorf("parse -json = %d, %q", status, out)
+	}
+	status, out, _ = runCmd(query, "parse", "-antlr", "-format", "json")
+	if status != 0 || !strings.HasPrefix(out, `{
+  "type": "oC_Cypher"`) {
+		t.Errorf("parse -antlr -format json = %d, %q", status, out)
+	}
+	status, out, _ = runCmd(query, "parse", "-format", "dot")
+	for _, want := range []string{
+		"digraph \"<stdin>\" {\n",
+		`n5 [label="NodePattern\nLabels=[Person]\n1:7-1:17\n(n:Person)"];`,
+		`n5 -> n6 [label="Variable"];`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("parse -format dot does not contain %q:\n%s", want, out)
+		}
+	}
+	if status != 0 {
+		t.Errorf("parse -format dot = %d", status)
+	}
+
+	// Inputs are scripts, which may have several statements.
+	status, out, errOut := runCmd("MATCH (n) RETURN n;\n:param x => 1\nRETURN $x\n", "parse", "-format", "sexpr")
+	for _, want := range []string{"\n  (Query\n", "\n  (ParamCommand :Name \"x\" :Value \"1\")\n", "\n  (Query\n    (Return\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("parse of a script does not contain %q:\n%s", want, out)
+		}
+	}
+	if status != 0 {
+		t.Errorf("parse of a script = %d, %s", status, errOut)
+	}
+
+	if status, _, _ := runCmd("MATCH (n RETURN n", "parse"); status != 1 {
+		t.Errorf("parse of a syntax error = %d, want 1", status)
+	}
+	if status, _, errOut := runCmd("MATCH (n RETURN n", "parse", "-antlr"); status != 1 || !strings.HasPrefix(errOut, "<stdin>:1:") {
+		t.Errorf("parse -antlr of a syntax error = %d, %q", status, errOut)
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	const query = "MATCH (n:Person) RETURN n"
+	status, out, _ := runCmd(query, "highlight")
+	if want := "\x1b[1;34mMATCH\x1b[0m (n:\x1b[33mPerson\x1b[0m) \x1b[1;34mRETURN\x1b[0m n"; status != 0 || out != want {
+		t.Errorf("highlight = %d, %q; want %q", status, out, want)
+	}
+	status, out, _ = runCmd(query, "highlight", "-html")
+	if want := `<span class="cypher-label">Person</span>`; status != 0 || !strings.Contains(out, want) {
+		t.Errorf("highlight -html = %d, %q; want it to contain %q", status, out, want)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	for _, args := range [][]string{nil, {"bogus"}, {"fmt", "-bogus"}, {"fmt", "-quote", "backtick"}, {"parse", "-format", "xml"}} {
+		if status, _, _ := runCmd("", args...); status != 2 {
+			t.Errorf("cypher %s = %d, want 2", strings.Join(args, " "), status)
+		}
+	}
+}
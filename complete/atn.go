@@ -0,0 +1,377 @@
+package complete
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"unicode/utf16"
+
+	"github.com/antlr/antlr4/runtime/Go/antlr"
+)
+
+// atn is the augmented transition network of a parser generated by ANTLR:
+// a state machine for each rule of the grammar, whose transitions read a
+// token, call another rule or read nothing. The ANTLR runtime does not
+// export the transitions of its own copy, so it is read here from the
+// serialized form the generated parser is built from.
+type atn struct {
+	states []atnState
+	rules  []int // the start state of each rule
+}
+
+type atnState struct {
+	stop  bool // whether it is the state its rule ends in
+	trans []transition
+	reach reach
+}
+
+// reach is where a state leads without reading a token, worked out once
+// for each state, since most of the work of walking an ATN is in the long
+// chains of calls to the rules that an expression may start with.
+type reach struct {
+	reads []target // the states that read a token
+	ends  bool     // whether the rule of the state may end
+	stop  int      // if so, the state it ends in
+}
+
+// target is a state that reads a token, with the states to return to
+// from the rules called on the way to it, outermost first.
+type target struct {
+	state int
+	calls []int
+}
+
+// transition is an edge of an ATN.
+type transition struct {
+	kind   transitionKind
+	target int
+	follow int        // for a call, the state to return to
+	types  []interval // for a read, the token types it reads
+	not    bool       // for a read, whether it reads the types not in types
+}
+
+type transitionKind int
+
+const (
+	readsNothing transitionKind = iota
+	callsRule
+	readsToken
+)
+
+// interval is a range of token types, including both ends.
+type interval struct{ lo, hi int }
+
+// reads reports whether t reads a token of type typ.
+func (t *transition) reads(typ int) bool {
+	if t.kind != readsToken {
+		return false
+	}
+	for _, iv := range t.types {
+		if iv.lo <= typ && typ <= iv.hi {
+			return !t.not
+		}
+	}
+	return t.not
+}
+
+// readATN reads an ATN serialized by ANTLR 4.7, which is the format of
+// its version 3, as the Go runtime's ATNDeserializer does. Only what a
+// parser's ATN needs for walking is kept: predicates and actions, which
+// Cypher.g4 has none of, are read as reading nothing.
+func readATN(serialized []uint16) *atn {
+	data := utf16.Decode(serialized)
+	if data[0] != rune(antlr.SerializedVersion) {
+		panic(fmt.Sprintf("cannot read an ATN of version %d", data[0]))
+	}
+	// All but the version are stored shifted by 2.
+	for i := 1; i < len(data); i++ {
+		if data[i] > 1 {
+			data[i] -= 2
+		} else {
+			data[i] += 65533
+		}
+	}
+	pos := 1
+	next := func() int {
+		pos++
+		return int(data[pos-1])
+	}
+	skip := func(n int) { pos += n }
+
+	var uuid [8]int
+	for i := 7; i >= 0; i-- {
+		uuid[i] = next()
+	}
+	smp := fmt.Sprintf("%04X%04X-%04X-%04X-%04X-%04X%04X%04X", uuid[0], uuid[1], uuid[2], uuid[3], uuid[4], uuid[5], uuid[6], uuid[7]) == antlr.AddedUnicodeSMP
+	skip(2) // the grammar type and the largest token type
+
+	a := &atn{}
+	a.states = make([]atnState, next())
+	for i := range a.states {
+		typ := next()
+		if typ == antlr.ATNStateInvalidType {
+			continue
+		}
+		skip(1) // the rule
+		switch typ {
+		case antlr.ATNStateLoopEnd, antlr.ATNStateBlockStart, antlr.ATNStatePlusBlockStart, antlr.ATNStateStarBlockStart:
+			skip(1) // the state that loops back, or ends the block
+		case antlr.ATNStateRuleStop:
+			a.states[i].stop = true
+		}
+	}
+	skip(next()) // the non-greedy states
+	skip(next()) // the states of precedence rules
+
+	a.rules = make([]int, next())
+	for i := range a.rules {
+		a.rules[i] = next()
+	}
+	skip(next()) // the lexer's modes
+
+	var sets [][]interval
+	readSets := func(read func() int) {
+		for n := next(); n > 0; n-- {
+			var set []interval
+			size := next()
+			if eof := next(); eof != 0 {
+				set = append(set, interval{antlr.TokenEOF, antlr.TokenEOF})
+			}
+			for ; size > 0; size-- {
+				set = append(set, interval{read(), read()})
+			}
+			sets = append(sets, set)
+		}
+	}
+	readSets(next)
+	if smp {
+		readSets(func() int { return next() | next()<<16 })
+	}
+
+	for n := next(); n > 0; n-- {
+		src, trg, typ, arg1, arg2, arg3 := next(), next(), next(), next(), next(), next()
+		t := transition{kind: readsToken, target: trg}
+		switch typ {
+		case antlr.TransitionEPSILON, antlr.TransitionPREDICATE, antlr.TransitionACTION, antlr.TransitionPRECEDENCE:
+			t.kind = readsNothing
+		case antlr.TransitionRULE:
+			t = transition{kind: callsRule, target: arg1, follow: trg}
+		case antlr.TransitionATOM:
+			if arg3 != 0 {
+				arg1 = antlr.TokenEOF
+			}
+			t.types = []interval{{arg1, arg1}}
+		case antlr.TransitionRANGE:
+			if arg3 != 0 {
+				arg1 = antlr.TokenEOF
+			}
+			t.types = []interval{{arg1, arg2}}
+		case antlr.TransitionSET:
+			t.types = sets[arg1]
+		case antlr.TransitionNOTSET:
+			t.types, t.not = sets[arg1], true
+		case antlr.TransitionWILDCARD:
+			t.not = true
+		default:
+			panic(fmt.Sprintf("cannot read an ATN transition of type %d", typ))
+		}
+		a.states[src].trans = append(a.states[src].trans, t)
+	}
+
+	for i := range a.states {
+		a.states[i].reach = a.reachFrom(i)
+	}
+	return a
+}
+
+// reachFrom works out the reach of a state.
+func (a *atn) reachFrom(state int) reach {
+	var r reach
+	w := newWalk(a)
+	seen := map[config]bool{}
+	var visit func(c config)
+	visit = func(c config) {
+		if seen[c] {
+			return
+		}
+		seen[c] = true
+		s := &a.states[c.state]
+		if s.stop {
+			if c.stack == nil {
+				r.ends, r.stop = true, c.state
+			} else {
+				visit(config{c.stack.follow, c.stack.caller})
+			}
+			return
+		}
+		reads := false
+		for _, t := range s.trans {
+			switch t.kind {
+			case readsNothing:
+				visit(config{t.target, c.stack})
+			case callsRule:
+				visit(config{t.target, w.call(t.follow, c.stack)})
+			case readsToken:
+				reads = true
+			}
+		}
+		if reads {
+			var calls []int
+			for f := c.stack; f != nil; f = f.caller {
+				calls = append([]int{f.follow}, calls...)
+			}
+			r.reads = append(r.reads, target{c.state, calls})
+		}
+	}
+	visit(config{state: state})
+	return r
+}
+
+// A walk follows every path through an ATN that reads the same tokens at
+// once, like a nondeterministic automaton, so that after reading a prefix
+// of a query it knows what tokens may come next, without choosing
+// between alternatives as ANTLR's parser must.
+//
+// Queries repeat themselves, so the same places come up again and again.
+// A walk keeps each set of places it has been at, with where each token
+// led from it, and so becomes a deterministic automaton as it goes.
+type walk struct {
+	atn      *atn
+	frames   map[frameKey]*frame
+	closures map[config][]config
+	sets     map[string]*places
+}
+
+func newWalk(a *atn) *walk {
+	return &walk{
+		atn:      a,
+		frames:   map[frameKey]*frame{},
+		closures: map[config][]config{},
+		sets:     map[string]*places{},
+	}
+}
+
+// frame is a rule call that has not returned, with the state to return
+// to and the frame of its caller. Frames are shared, so that the same
+// stack of calls is always the same pointer.
+type frame struct {
+	follow int
+	caller *frame
+	id     int // for ordering frames, from 1
+}
+
+type frameKey struct {
+	follow int
+	caller *frame
+}
+
+// config is a place a walk may be at: a state, and the calls it is in.
+type config struct {
+	state int
+	stack *frame
+}
+
+// places is a set of places a walk may be at, with the sets that reading
+// each type of token leads to.
+type places struct {
+	configs []config
+	after   map[int]*places
+}
+
+// start returns the places a walk may be at before reading anything in
+// rule.
+func (w *walk) start(rule int) *places {
+	return w.set(w.closure(config{state: w.atn.rules[rule]}))
+}
+
+// next returns the places a walk at p may be at after reading a token of
+// type typ. There are none if it cannot be read.
+func (w *walk) next(p *places, typ int) *places {
+	if q, ok := p.after[typ]; ok {
+		return q
+	}
+	var out []config
+	seen := map[config]bool{}
+	for _, c := range p.configs {
+		for _, t := range w.atn.states[c.state].trans {
+			if !t.reads(typ) {
+				continue
+			}
+			for _, to := range w.closure(config{t.target, c.stack}) {
+				if !seen[to] {
+					seen[to] = true
+					out = append(out, to)
+				}
+			}
+		}
+	}
+	q := w.set(out)
+	p.after[typ] = q
+	return q
+}
+
+// set returns the places for configs, which it may reorder.
+func (w *walk) set(configs []config) *places {
+	id := func(f *frame) int {
+		if f == nil {
+			return 0
+		}
+		return f.id
+	}
+	sort.Slice(configs, func(i, j int) bool {
+		a, b := configs[i], configs[j]
+		return a.state < b.state || a.state == b.state && id(a.stack) < id(b.stack)
+	})
+	var key []byte
+	for _, c := range configs {
+		key = strconv.AppendInt(key, int64(c.state), 10)
+		key = append(key, ' ')
+		key = strconv.AppendInt(key, int64(id(c.stack)), 10)
+		key = append(key, ',')
+	}
+	if p, ok := w.sets[string(key)]; ok {
+		return p
+	}
+	p := &places{configs: configs, after: map[int]*places{}}
+	w.sets[string(key)] = p
+	return p
+}
+
+// closure returns the places reached from c without reading a token,
+// where a token may be read, or where the rule the walk started in ends.
+func (w *walk) closure(c config) []config {
+	if out, ok := w.closures[c]; ok {
+		return out
+	}
+	var out []config
+	for at := c; ; at = (config{at.stack.follow, at.stack.caller}) {
+		r := &w.atn.states[at.state].reach
+		for _, t := range r.reads {
+			stack := at.stack
+			for _, follow := range t.calls {
+				stack = w.call(follow, stack)
+			}
+			out = append(out, config{t.state, stack})
+		}
+		if !r.ends {
+			break
+		}
+		if at.stack == nil {
+			out = append(out, config{state: r.stop})
+			break
+		}
+	}
+	w.closures[c] = out
+	return out
+}
+
+// call returns the frame for a call returning to follow, from caller.
+func (w *walk) call(follow int, caller *frame) *frame {
+	k := frameKey{follow, caller}
+	if f, ok := w.frames[k]; ok {
+		return f
+	}
+	f := &frame{follow: follow, caller: caller, id: len(w.frames) + 1}
+	w.frames[k] = f
+	return f
+}
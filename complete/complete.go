@@ -0,0 +1,438 @@
+// Package complete suggests how to complete the word at a point of a Cypher
+// query, for editors and query consoles.
+//
+// At finds where the point is: after "$", after a variable and a dot, after
+// a colon in a node pattern or in the brackets of a relationship, or
+// anywhere else. It suggests what fits there:
+//
+//   - the parameters the query already uses, after "$",
+//   - property keys, labels and relationship types, from the query itself
+//     and from an optional Schema, after a dot or colon,
+//   - and elsewhere, the variables in scope, the keywords the grammar
+//     allows there and the built-in functions, in that order.
+//
+// The keywords are those the parser generated from Cypher.g4 accepts at the
+// point, so they do not depend on how the hand-written parser recovers from
+// the errors of an unfinished query.
+package complete
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/a-poor/cypher"
+	"github.com/a-poor/cypher/ast"
+	"github.com/a-poor/cypher/cst"
+	"github.com/a-poor/cypher/sema"
+)
+
+// Schema describes a graph, for suggesting the names the query does not
+// use yet. Any of its fields may be empty.
+type Schema struct {
+	Labels            []string
+	RelationshipTypes []string
+	PropertyKeys      []string
+}
+
+// Kind is what a candidate is.
+type Kind int
+
+const (
+	Keyword Kind = iota
+	Variable
+	Function
+	Label
+	RelationshipType
+	PropertyKey
+	Parameter
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Keyword:
+		return "keyword"
+	case Variable:
+		return "variable"
+	case Function:
+		return "function"
+	case Label:
+		return "label"
+	case RelationshipType:
+		return "relationship type"
+	case PropertyKey:
+		return "property key"
+	case Parameter:
+		return "parameter"
+	}
+	return "unknown"
+}
+
+// Candidate is a completion of the word at a point of a query.
+type Candidate struct {
+	// Text replaces the query from Start up to the point. Names that are
+	// not plain identifiers are escaped in backticks, and parameters
+	// come without their "$", which is before Start.
+	Text  string
+	Start int // byte offset
+	Kind  Kind
+
+	// Detail is the kind of value of a variable, as sema.Kind names it,
+	// or the signature of a function.
+	Detail string
+}
+
+// At returns the candidates for completing the word of query that ends at
+// offset, a byte offset, best first. Candidates that start with the word
+// as typed come before those that only start with it ignoring case, and
+// those that do not start with it are left out. The schema may be nil.
+//
+// The query is a single statement, which need not be finished, nor
+// correct after offset. There are no candidates inside string literals
+// and comments.
+func At(query string, offset int, schema *Schema) []Candidate {
+	if offset < 0 || offset > len(query) {
+		return nil
+	}
+	if schema == nil {
+		schema = &Schema{}
+	}
+	tree, _ := cypher.ParseCST(query)
+	c := &completer{query: query, offset: offset, toks: tree.Root.Tokens()}
+	if !c.findWord(tree) {
+		return nil
+	}
+
+	// The names the query uses, other than the word itself.
+	used := map[place][]string{}
+	for i, tok := range c.toks {
+		if i == c.wordTok {
+			continue
+		}
+		if p := placeAfter(c.toks[:i]); p == inParameter || p != inExpr && tok.IsName() && !c.call(i) {
+			used[p] = append(used[p], unescape(tok.Text))
+		}
+		if tok.IsName() && i+1 < len(c.toks) && c.toks[i+1].Text == ":" && innermost(c.toks[:i]) == "{" {
+			used[inProperty] = append(used[inProperty], unescape(tok.Text)) // a key of a map
+		}
+	}
+
+	switch p := placeAfter(c.toks[:c.before()]); p {
+	case inLabel:
+		c.addNames(Label, used[inLabel], schema.Labels)
+	case inRelationshipType:
+		c.addNames(RelationshipType, used[inRelationshipType], schema.RelationshipTypes)
+	case inProperty:
+		c.addNames(PropertyKey, used[inProperty], schema.PropertyKeys)
+	case inParameter:
+		c.addNames(Parameter, used[inParameter], nil)
+	default:
+		// Each candidate is tried as the token after the prefix, followed
+		// by whitespace, or by "(" for a function.
+		g := grammarAfter(query[:c.start])
+		if g.accepts(nameType, spaceType) {
+			c.addVariables()
+		}
+		for i, kw := range keywords {
+			if c.matches(kw) && g.accepts(keywordType(i), spaceType) {
+				c.add(Candidate{Text: c.keywordCase(kw), Kind: Keyword})
+			}
+		}
+		if g.accepts(nameType, lparenType) {
+			for _, f := range sema.Functions() {
+				c.add(Candidate{Text: f.Name, Kind: Function, Detail: f.Signature})
+			}
+		}
+	}
+
+	// Sorting is stable, so that the order the candidates were added in
+	// ranks those that match the word equally well.
+	sort.SliceStable(c.cands, func(i, j int) bool {
+		return c.exact(c.cands[i].Text) && !c.exact(c.cands[j].Text)
+	})
+	return c.cands
+}
+
+// completer collects the candidates for a point of a query.
+type completer struct {
+	query  string
+	offset int
+	toks   []*cst.Token
+
+	start   int    // of the word
+	word    string // from start up to offset, without backticks
+	wordTok int    // the index of the token of the word, or -1
+
+	cands []Candidate
+	seen  map[Kind]map[string]bool
+}
+
+// findWord finds the word that ends at the point: the part of a name up to
+// it, or else nothing. It returns false if the point is inside a string
+// literal or comment.
+func (c *completer) findWord(tree *cst.Tree) bool {
+	c.start, c.wordTok = c.offset, -1
+	inside := func(sp ast.Span) bool { return sp.Start.Offset < c.offset && c.offset < sp.End.Offset }
+	for _, tok := range append(c.toks, tree.EOF) {
+		for _, tr := range append(tok.Leading, tok.Trailing...) {
+			if tr.IsComment() && (inside(tr.Loc) || tr.Kind == cst.LineComment && tr.Loc.End.Offset == c.offset) {
+				return false
+			}
+		}
+	}
+	for i, tok := range c.toks {
+		if tok.Loc.Start.Offset >= c.offset || tok.Loc.End.Offset < c.offset {
+			continue
+		}
+		switch {
+		case tok.IsName():
+			c.start, c.wordTok = tok.Loc.Start.Offset, i
+			c.word = unescape(c.query[c.start:c.offset])
+		case strings.HasPrefix(tok.Text, "'") || strings.HasPrefix(tok.Text, `"`):
+			if tok.Loc.End.Offset > c.offset || !closed(tok.Text) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// before returns the number of tokens before the word.
+func (c *completer) before() int {
+	return sort.Search(len(c.toks), func(i int) bool { return c.toks[i].Loc.End.Offset > c.start })
+}
+
+// call reports whether the token at an index is followed by "(", as the
+// name of a function or procedure is.
+func (c *completer) call(i int) bool {
+	return i+1 < len(c.toks) && c.toks[i+1].Text == "("
+}
+
+// closed reports whether a string literal has its closing quote.
+func closed(lit string) bool {
+	q := lit[0]
+	if len(lit) < 2 || lit[len(lit)-1] != q {
+		return false
+	}
+	// A backslash before the last quote may escape it.
+	n := 0
+	for i := len(lit) - 2; i > 0 && lit[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 0
+}
+
+// matches reports whether a candidate starts with the word, ignoring case.
+func (c *completer) matches(text string) bool {
+	text = unescape(text)
+	return len(text) >= len(c.word) && strings.EqualFold(text[:len(c.word)], c.word)
+}
+
+// exact reports whether a candidate starts with the word as typed.
+func (c *completer) exact(text string) bool {
+	return strings.HasPrefix(unescape(text), c.word)
+}
+
+// add adds a candidate if it matches the word and has not been added.
+func (c *completer) add(cand Candidate) {
+	if !c.matches(cand.Text) || c.seen[cand.Kind][cand.Text] {
+		return
+	}
+	if c.seen == nil {
+		c.seen = map[Kind]map[string]bool{}
+	}
+	if c.seen[cand.Kind] == nil {
+		c.seen[cand.Kind] = map[string]bool{}
+	}
+	c.seen[cand.Kind][cand.Text] = true
+	cand.Start = c.start
+	c.cands = append(c.cands, cand)
+}
+
+// addNames adds the names the query uses, in the order it first uses
+// them, and then the other names of the schema, sorted.
+func (c *completer) addNames(k Kind, used, schema []string) {
+	for _, name := range used {
+		c.add(Candidate{Text: quote(name), Kind: k})
+	}
+	schema = append([]string(nil), schema...)
+	sort.Strings(schema)
+	for _, name := range schema {
+		c.add(Candidate{Text: quote(name), Kind: k})
+	}
+}
+
+// keywordCase writes a keyword in lower case if the word is.
+func (c *completer) keywordCase(kw string) string {
+	if c.word != "" && c.word == strings.ToLower(c.word) {
+		return strings.ToLower(kw)
+	}
+	return kw
+}
+
+// addVariables adds the variables in scope at the word, those declared
+// last first.
+func (c *completer) addVariables() {
+	// The clause of the word is likely unfinished, so the variables are
+	// those of the query up to the word, finished with a placeholder for
+	// it and the brackets left open.
+	text := c.query[:c.start] + "x"
+	before := c.toks[:c.before()]
+	for i := openBracket(before); i >= 0; i = openBracket(before[:i]) {
+		text += closing[before[i].Text]
+	}
+	q, _ := cypher.Parse(text)
+	if q == nil {
+		return
+	}
+	syms := sema.Check(q).Symbols
+	for i := len(syms) - 1; i >= 0; i-- {
+		if sym := syms[i]; inScope(q, sym, c.start) {
+			c.add(Candidate{Text: quote(sym.Name), Kind: Variable, Detail: sym.Kind.String()})
+		}
+	}
+}
+
+// inScope reports whether a symbol is in scope at an offset: whether it is
+// declared before it, and not hidden by a UNION or the projection of a WITH
+// in between. Variables local to expressions are left out, since their
+// scope is not known.
+func inScope(q *ast.Query, sym *sema.Symbol, offset int) bool {
+	decl := sym.Decl.Span()
+	if sym.Clause == nil || decl.End.Offset > offset {
+		return false
+	}
+	for _, cl := range q.Clauses {
+		if start := cl.Span().Start.Offset; start < decl.End.Offset || start >= offset {
+			continue
+		}
+		switch cl := cl.(type) {
+		case *ast.Union:
+			return false
+		case *ast.With:
+			// The WHERE of a WITH, and what follows it, only see what
+			// the WITH projects.
+			if cl.Projection.Span().End.Offset <= offset && !passes(cl.Projection, sym) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// passes reports whether a WITH passes a symbol on, by * or by name.
+func passes(proj *ast.Projection, sym *sema.Symbol) bool {
+	if proj.Star {
+		return true
+	}
+	for _, item := range proj.Items {
+		if item.Alias == nil {
+			for _, ref := range sym.Refs {
+				if item.Expr == ast.Expr(ref) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// A place is where in a query a word is, which decides what may complete
+// it.
+type place int
+
+const (
+	inExpr             place = iota // anywhere else
+	inLabel                         // after a colon in a node pattern or predicate
+	inRelationshipType              // after a colon or bar in a relationship
+	inProperty                      // after a name and a dot
+	inParameter                     // after "$"
+)
+
+// placeAfter returns the place of a word after the given tokens.
+func placeAfter(toks []*cst.Token) place {
+	n := len(toks)
+	if n == 0 {
+		return inExpr
+	}
+	switch toks[n-1].Text {
+	case "$":
+		return inParameter
+	case ".":
+		if n > 1 && toks[n-2].IsName() {
+			return inProperty
+		}
+	case ":", "|":
+		open := innermost(toks[:n-1])
+		switch {
+		case open == "[" && relationship(toks[:n-1]):
+			return inRelationshipType
+		case open == "{" || toks[n-1].Text == "|":
+			// The value of a map entry, or what a list comprehension
+			// makes of its elements.
+			return inExpr
+		}
+		return inLabel
+	}
+	return inExpr
+}
+
+// innermost returns the bracket that the innermost of the brackets left
+// open by the tokens opens, or "" if there is none.
+func innermost(toks []*cst.Token) string {
+	if i := openBracket(toks); i >= 0 {
+		return toks[i].Text
+	}
+	return ""
+}
+
+// openBracket returns the index of the innermost bracket that the tokens
+// leave open, or -1.
+func openBracket(toks []*cst.Token) int {
+	depth := 0
+	for i := len(toks) - 1; i >= 0; i-- {
+		switch toks[i].Text {
+		case ")", "]", "}":
+			depth++
+		case "(", "[", "{":
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+var closing = map[string]string{"(": ")", "[": "]", "{": "}"}
+
+// relationship reports whether the innermost open bracket of the tokens is
+// the bracket of a relationship, which follows a dash.
+func relationship(toks []*cst.Token) bool {
+	i := openBracket(toks)
+	return i > 0 && toks[i].Text == "[" && toks[i-1].Text == "-"
+}
+
+// unescape returns a name without the backticks it may be escaped in.
+func unescape(name string) string {
+	if !strings.HasPrefix(name, "`") {
+		return name
+	}
+	name = strings.TrimPrefix(name, "`")
+	name = strings.TrimSuffix(name, "`")
+	return strings.ReplaceAll(name, "``", "`")
+}
+
+// quote returns a name as it must be written: as it is if it is a plain
+// identifier, and in backticks otherwise.
+func quote(name string) string {
+	for i, r := range name {
+		if !(r == '_' || unicode.IsLetter(r) || i > 0 && unicode.IsDigit(r)) {
+			return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+		}
+	}
+	if name == "" {
+		return "``"
+	}
+	return name
+}
@@ -0,0 +1,121 @@
+package complete
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/antlr/antlr4/runtime/Go/antlr"
+)
+
+var schema = &Schema{
+	Labels:            []string{"Person", "Movie", "My Label"},
+	RelationshipTypes: []string{"ACTED_IN"},
+	PropertyKeys:      []string{"title", "born"},
+}
+
+func TestAt(t *testing.T) {
+	tests := []struct {
+		query string // with ‸ at the point
+		want  []string
+		all   bool // whether want is all of the candidates, or the first
+	}{
+		// Keywords
+		{"MATCH (n) W‸", []string{"WITH", "WHERE"}, true},
+		{"match (n) w‸", []string{"with", "where"}, true},
+		{"MATCH (n) RETURN n ORDER ‸", []string{"BY"}, true},
+		{"MATCH (n) RETURN n S‸", []string{"SKIP", "STARTS"}, true},
+		{"‸", []string{"OPTIONAL", "MATCH", "UNWIND"}, false},
+		{"MATCH (a) RETURN a AS b, a ‸", []string{"UNION", "AS"}, false},
+
+		// Names of the schema and the query
+		{"MATCH (n:‸", []string{"Movie", "`My Label`", "Person"}, true},
+		{"MATCH (m:Person), (n:‸", []string{"Person", "Movie", "`My Label`"}, true},
+		{"MATCH (n:`My‸", []string{"`My Label`"}, true},
+		{"MATCH (n) WHERE n:P‸", []string{"Person"}, true},
+		{"MATCH (a)-[:‸", []string{"ACTED_IN"}, true},
+		{"MATCH (a)-[:KNOWS]->(b)<-[r:ACTED_IN|‸", []string{"KNOWS", "ACTED_IN"}, true},
+		{"MATCH (n {name: 'x'}) WHERE n.‸", []string{"name", "born", "title"}, true},
+		{"MATCH (n) SET n.age = 1 RETURN n.‸ + 1", []string{"age", "born", "title"}, true},
+		{"MATCH (n) WHERE n.age > $min AND n.age < $‸", []string{"min"}, true},
+		{"MATCH (n {id: $id}) RETURN $i‸d", []string{"id"}, true},
+
+		// Variables and functions
+		{"MATCH (abc)-->(abd) WITH abc WHERE ab‸", []string{"abc", "abs"}, true},
+		{"MATCH (abc)-->(abd) RETURN ab‸", []string{"abd", "abc", "abs"}, true},
+		{"MATCH (abc)-->(abd) RETURN abd UNION MATCH (abe) RETURN ab‸", []string{"abe", "abs"}, true},
+		{"UNWIND [1] AS num RETURN num, count(*) AS n ORDER BY n‸", []string{"n", "num", "not"}, false},
+		{"MATCH (node)-->(other), (n‸", []string{"node", "none"}, true},
+		{"MATCH (a) RETURN toU‸", []string{"toUpper"}, true},
+		{"MATCH (Abc) RETURN a‸", []string{"all"}, false},
+		{"MATCH (`a b`) RETURN `a‸", []string{"`a b`"}, false},
+
+		// Nothing
+		{"MATCH (a) RETURN 'ab‸", nil, true},
+		{"MATCH (a) RETURN 'ab‸'", nil, true},
+		{"MATCH (a) // ma‸", nil, true},
+		{"MATCH (a) /* ma‸ */", nil, true},
+	}
+	for _, tt := range tests {
+		offset := strings.Index(tt.query, "‸")
+		query := tt.query[:offset] + tt.query[offset+len("‸"):]
+		var got []string
+		for _, c := range At(query, offset, schema) {
+			got = append(got, c.Text)
+		}
+		if !tt.all && len(got) > len(tt.want) {
+			got = got[:len(tt.want)]
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("At(%q) = %q; want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestAtCandidate(t *testing.T) {
+	got := At("MATCH (person:Person) WHERE pe", 30, nil)
+	want := Candidate{Text: "person", Start: 28, Kind: Variable, Detail: "node"}
+	if len(got) == 0 || got[0] != want {
+		t.Errorf("At = %+v; want %+v first", got, want)
+	}
+	if fs := At("RETURN toUp", 11, nil); len(fs) != 1 || fs[0].Kind != Function || !strings.HasPrefix(fs[0].Detail, "toUpper(") {
+		t.Errorf("At = %+v; want the function toUpper", fs)
+	}
+	if got := At("RETURN 1", 9, nil); got != nil {
+		t.Errorf("At past the end = %+v; want none", got)
+	}
+}
+
+// The grammar read from the generated parser reads the whole of each query
+// in the corpus, and none of a query after a syntax error.
+func TestGrammarAfter(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "testdata", "corpus", "*.cypher"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no queries in testdata/corpus: %v", err)
+	}
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !grammarAfter(string(b)).accepts(antlr.TokenEOF) {
+			t.Errorf("%s: not read", f)
+		}
+	}
+	for _, query := range []string{"MATCH (n) RETRUN", "RETURN 'abc", "MATCH (n RETURN n"} {
+		if grammarAfter(query).accepts() {
+			t.Errorf("%q: read", query)
+		}
+	}
+}
+
+func BenchmarkAt(b *testing.B) {
+	query := strings.Repeat("MATCH (n:Person {name: 'x'})-[:KNOWS]->(m) WHERE n.age > 30 AND m.name STARTS WITH 'A'\n", 160) + "RETURN coun"
+	b.SetBytes(int64(len(query)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		At(query, len(query), nil)
+	}
+}
@@ -0,0 +1,93 @@
+package complete
+
+import (
+	"strings"
+
+	"github.com/antlr/antlr4/runtime/Go/antlr"
+
+	antlrparser "github.com/a-poor/cypher/parser"
+)
+
+var (
+	names = antlrparser.NewCypherParser(nil)
+
+	// keywords are the keywords of the grammar in Cypher.g4, in upper
+	// case, in the order of their token types.
+	keywords = func() []string {
+		var kws []string
+		for t := antlrparser.CypherParserUNION; t <= antlrparser.CypherParserTHEN; t++ {
+			// SKIP is named L_SKIP, to keep clear of a name the ANTLR
+			// runtime uses.
+			kws = append(kws, strings.TrimPrefix(names.SymbolicNames[t], "L_"))
+		}
+		return kws
+	}()
+
+	// The token types of a name, whitespace and "(".
+	nameType   = antlrparser.CypherParserUnescapedSymbolicName
+	spaceType  = antlrparser.CypherParserSP
+	lparenType = func() int {
+		for t, name := range names.LiteralNames {
+			if name == "'('" {
+				return t
+			}
+		}
+		panic("Cypher.g4 has no '('")
+	}()
+
+	cypherATN = readATN(antlrparser.SerializedATN())
+)
+
+// keywordType returns the token type of the ith keyword.
+func keywordType(i int) int {
+	return antlrparser.CypherParserUNION + i
+}
+
+// grammarAfter walks the ATN of the parser generated from Cypher.g4, the
+// reference for the grammar, over the tokens of text, the start of a
+// query. It returns the places the parser may be at after reading them,
+// from which the tokens that may come next can be tried one by one without
+// reading text again. There are none if text has a syntax error, other
+// than ending before the query does.
+func grammarAfter(text string) *grammarPlaces {
+	g := &grammarPlaces{walk: newWalk(cypherATN)}
+	g.at = g.walk.start(antlrparser.CypherParserRULE_oC_Cypher)
+
+	errs := &lexErrors{DefaultErrorListener: antlr.NewDefaultErrorListener()}
+	lexer := antlrparser.NewCypherLexer(antlr.NewInputStream(text))
+	lexer.RemoveErrorListeners()
+	lexer.AddErrorListener(errs)
+	for tok := lexer.NextToken(); tok.GetTokenType() != antlr.TokenEOF; tok = lexer.NextToken() {
+		g.at = g.walk.next(g.at, tok.GetTokenType())
+	}
+	if errs.failed {
+		g.at = g.walk.set(nil)
+	}
+	return g
+}
+
+// grammarPlaces are the places the parser for Cypher.g4 may be at after
+// reading some text.
+type grammarPlaces struct {
+	walk *walk
+	at   *places
+}
+
+// accepts reports whether tokens of the given types may come next.
+func (g *grammarPlaces) accepts(types ...int) bool {
+	p := g.at
+	for _, typ := range types {
+		p = g.walk.next(p, typ)
+	}
+	return len(p.configs) > 0
+}
+
+// lexErrors records whether the lexer found an error.
+type lexErrors struct {
+	*antlr.DefaultErrorListener
+	failed bool
+}
+
+func (l *lexErrors) SyntaxError(antlr.Recognizer, interface{}, int, int, string, antlr.RecognitionException) {
+	l.failed = true
+}
@@ -0,0 +1,250 @@
+package cypher
+
+import (
+	"context"
+	"reflect"
+	"strings"
+
+	"github.com/a-poor/cypher/ast"
+	"github.com/a-poor/cypher/cst"
+)
+
+// ParseCST parses a single Cypher statement into a lossless concrete syntax
+// tree, which keeps the whitespace and comments that Parse throws away.
+//
+// The tree's AST method returns the same *ast.Query as Parse, with the
+// comments attached to its nodes. Syntax errors are reported as by Parse,
+// and the tree is returned even then.
+func ParseCST(query string) (*cst.Tree, error) {
+	src := newSource(query)
+	q, errs := parse(context.Background(), src)
+	return buildCST(src, q, nil), errs.Err()
+}
+
+// ParseScriptCST is like ParseCST, but parses a script of statements and
+// commands as ParseScript does. The tree's AST method returns an
+// *ast.Script.
+func ParseScriptCST(script string) (*cst.Tree, error) {
+	s, err := ParseScript(script)
+
+	// Commands are kept as single tokens, since their arguments need not
+	// be valid Cypher.
+	var commands []ast.Span
+	for _, stmt := range s.Statements {
+		if _, ok := stmt.(*ast.Query); !ok {
+			commands = append(commands, stmt.Span())
+		}
+	}
+	return buildCST(newSource(script), s, commands), err
+}
+
+// buildCST builds the tree for root, which was parsed from the text of src.
+// The text covered by each of the opaque spans becomes a single token.
+func buildCST(src *source, root ast.Node, opaque []ast.Span) *cst.Tree {
+	toks, eof := cstTokens(src, opaque)
+	t := &cst.Tree{Root: cstNode(root, toks), EOF: eof}
+	attachComments(t)
+	return t
+}
+
+// cstTokens splits the text of src into tokens, with the whitespace and
+// comments between them as trivia. Characters the lexer cannot make sense
+// of are kept as tokens of their own, so that no text is lost.
+func cstTokens(src *source, opaque []ast.Span) ([]*cst.Token, *cst.Token) {
+	var toks []*cst.Token
+	var pending []cst.Trivia // trivia not yet attached to a token
+	afterToken := false      // whether pending follows a token on its line
+
+	addToken := func(start, end int) {
+		toks = append(toks, &cst.Token{
+			Text:    src.text[start:end],
+			Loc:     src.span(start, end),
+			Leading: pending,
+		})
+		pending, afterToken = nil, true
+	}
+	addTrivia := func(kind cst.TriviaKind, start, end int) {
+		tr := cst.Trivia{Kind: kind, Text: src.text[start:end], Loc: src.span(start, end)}
+		if afterToken {
+			prev := toks[len(toks)-1]
+			prev.Trailing = append(prev.Trailing, tr)
+			afterToken = !strings.HasSuffix(tr.Text, "\n")
+			return
+		}
+		pending = append(pending, tr)
+	}
+	// Whitespace is split after each line break, so that a token's
+	// trailing trivia can stop at the end of its line.
+	addWhitespace := func(start, end int) {
+		for start < end {
+			i := strings.IndexByte(src.text[start:end], '\n')
+			if i < 0 {
+				i = end - start
+			} else {
+				i++
+			}
+			addTrivia(cst.Whitespace, start, start+i)
+			start += i
+		}
+	}
+	lex := func(start, end int) {
+		l := newLexer(src.text, start, end, nil)
+		for {
+			tok := l.next()
+			switch tok.kind {
+			case tokEOF:
+				return
+			case tokSpace:
+				addWhitespace(tok.start, tok.end)
+			case tokLineComment:
+				addTrivia(cst.LineComment, tok.start, tok.end)
+			case tokBlockComment:
+				addTrivia(cst.BlockComment, tok.start, tok.end)
+			default:
+				addToken(tok.start, tok.end)
+			}
+		}
+	}
+
+	pos := 0
+	for _, sp := range opaque {
+		lex(pos, sp.Start.Offset)
+		addToken(sp.Start.Offset, sp.End.Offset)
+		pos = sp.End.Offset
+	}
+	lex(pos, len(src.text))
+
+	eof := &cst.Token{Loc: src.span(len(src.text), len(src.text)), Leading: pending}
+	return toks, eof
+}
+
+// cstNode builds the node for n, given the tokens within its span.
+func cstNode(n ast.Node, toks []*cst.Token) *cst.Node {
+	node := &cst.Node{AST: n}
+	j := 0
+	for _, child := range children(n) {
+		sp := child.Span()
+		for j < len(toks) && toks[j].Loc.Start.Offset < sp.Start.Offset {
+			node.Children = append(node.Children, toks[j])
+			j++
+		}
+		k := j
+		for k < len(toks) && toks[k].Loc.End.Offset <= sp.End.Offset {
+			k++
+		}
+		node.Children = append(node.Children, cstNode(child, toks[j:k]))
+		j = k
+	}
+	for _, tok := range toks[j:] {
+		node.Children = append(node.Children, tok)
+	}
+	return node
+}
+
+// children returns the nodes directly below n, in source order.
+func children(n ast.Node) []ast.Node {
+	var nodes []ast.Node
+	ast.Inspect(n, func(c ast.Node) bool {
+		if c == n {
+			return true
+		}
+		if c != nil {
+			nodes = append(nodes, c)
+		}
+		return false
+	})
+	return nodes
+}
+
+// attachComments records the comments of the tree in the NodeInfo of the
+// AST nodes next to them.
+//
+// A comment before a token belongs to the outermost node that starts with
+// the token, and a comment after one to the outermost node that ends with
+// it. The root only gets the comments that no other node starts or ends
+// next to, and those at the end of the text. Opening parentheses are
+// skipped over in looking for the node a token starts.
+//
+// When no node starts with the token, a comment before it belongs after
+// the node ending with the token before. When no node ends with the token,
+// a comment after it belongs after the node before it if the token is a
+// comma, closing parenthesis or semicolon, and otherwise before the node
+// starting with the next token. Failing those, the comment belongs to the node
+// holding the token.
+func attachComments(t *cst.Tree) {
+	starts := map[*cst.Token]*cst.Node{}
+	ends := map[*cst.Token]*cst.Node{}
+	parents := map[*cst.Token]*cst.Node{}
+
+	var visit func(n *cst.Node)
+	visit = func(n *cst.Node) {
+		if toks := n.Tokens(); len(toks) > 0 && n != t.Root {
+			if starts[toks[0]] == nil {
+				starts[toks[0]] = n
+			}
+			if ends[toks[len(toks)-1]] == nil {
+				ends[toks[len(toks)-1]] = n
+			}
+		}
+		for _, c := range n.Children {
+			switch c := c.(type) {
+			case *cst.Token:
+				parents[c] = n
+			case *cst.Node:
+				visit(c)
+			}
+		}
+	}
+	visit(t.Root)
+
+	attach := func(n *cst.Node, trivia []cst.Trivia, trailing bool) {
+		info := nodeInfo(n.AST)
+		for _, tr := range trivia {
+			if tr.IsComment() {
+				info.Comments = append(info.Comments, &ast.Comment{
+					Raw:      tr.Text,
+					Loc:      tr.Loc,
+					Trailing: trailing,
+				})
+			}
+		}
+	}
+	// startAt returns the node starting with toks[i], or after the
+	// parentheses there.
+	toks := t.Root.Tokens()
+	startAt := func(i int) *cst.Node {
+		for i < len(toks)-1 && toks[i].Text == "(" && starts[toks[i]] == nil {
+			i++
+		}
+		if i < len(toks) {
+			return starts[toks[i]]
+		}
+		return nil
+	}
+	for i, tok := range toks {
+		switch {
+		case startAt(i) != nil:
+			attach(startAt(i), tok.Leading, false)
+		case i > 0 && ends[toks[i-1]] != nil:
+			attach(ends[toks[i-1]], tok.Leading, true)
+		default:
+			attach(parents[tok], tok.Leading, false)
+		}
+		switch {
+		case ends[tok] != nil:
+			attach(ends[tok], tok.Trailing, true)
+		case (tok.Text == "," || tok.Text == ")" || tok.Text == ";") && i > 0 && ends[toks[i-1]] != nil:
+			attach(ends[toks[i-1]], tok.Trailing, true)
+		case startAt(i+1) != nil:
+			attach(startAt(i+1), tok.Trailing, false)
+		default:
+			attach(parents[tok], tok.Trailing, true)
+		}
+	}
+	attach(t.Root, t.EOF.Leading, true)
+}
+
+// nodeInfo returns the NodeInfo embedded in a node.
+func nodeInfo(n ast.Node) *ast.NodeInfo {
+	return reflect.ValueOf(n).Elem().FieldByName("NodeInfo").Addr().Interface().(*ast.NodeInfo)
+}
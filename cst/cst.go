@@ -0,0 +1,164 @@
+// Package cst defines a lossless concrete syntax tree for Cypher.
+//
+// Unlike the ast package, which keeps only the meaning of a query, a
+// concrete syntax tree keeps every character of the source text: each token
+// carries the whitespace and comments around it as trivia, so the tree can
+// be written back out exactly as it was read. Its nodes follow the nodes of
+// the abstract syntax tree, and each of them refers to the ast.Node it was
+// built from.
+package cst
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/a-poor/cypher/ast"
+)
+
+// Tree is the concrete syntax tree of a query or script.
+type Tree struct {
+	// Root is the node of the *ast.Query or *ast.Script that was parsed.
+	// Its children cover the whole of the source text, including any
+	// tokens before or after the statement itself.
+	Root *Node
+
+	// EOF is an empty token at the end of the source text. Its leading
+	// trivia holds whatever whitespace and comments end the text.
+	EOF *Token
+}
+
+// AST returns the abstract syntax tree the concrete one was built from,
+// either an *ast.Query or an *ast.Script. Comments are attached to the
+// nodes next to them, in the Comments field of each node's NodeInfo.
+func (t *Tree) AST() ast.Node {
+	return t.Root.AST
+}
+
+// String returns the source text of the tree, which is exactly the text
+// it was parsed from.
+func (t *Tree) String() string {
+	var sb strings.Builder
+	t.Root.write(&sb)
+	t.EOF.write(&sb)
+	return sb.String()
+}
+
+// Element is a child of a Node: either a *Node or a *Token.
+type Element interface {
+	// Span returns the range of source text of the element, leaving out
+	// the leading trivia of its first token and the trailing trivia of its
+	// last.
+	Span() ast.Span
+
+	write(sb *strings.Builder)
+}
+
+// Node is an inner node of the tree.
+type Node struct {
+	// AST is the node of the abstract syntax tree that covers the same
+	// text.
+	AST ast.Node
+
+	// Children holds the node's tokens, and the nodes for its children in
+	// the abstract syntax tree, in source order.
+	Children []Element
+}
+
+// Span returns the span of the AST node.
+func (n *Node) Span() ast.Span { return n.AST.Span() }
+
+// String returns the source text of the node, with the trivia of all of its
+// tokens.
+func (n *Node) String() string {
+	var sb strings.Builder
+	n.write(&sb)
+	return sb.String()
+}
+
+func (n *Node) write(sb *strings.Builder) {
+	for _, c := range n.Children {
+		c.write(sb)
+	}
+}
+
+// Tokens returns the tokens below the node, in source order.
+func (n *Node) Tokens() []*Token {
+	var toks []*Token
+	for _, c := range n.Children {
+		switch c := c.(type) {
+		case *Token:
+			toks = append(toks, c)
+		case *Node:
+			toks = append(toks, c.Tokens()...)
+		}
+	}
+	return toks
+}
+
+// Token is a single token of the source text, such as a keyword, a name or
+// a punctuation mark.
+type Token struct {
+	Text string
+	Loc  ast.Span
+
+	// Leading holds the trivia between the previous token's trailing
+	// trivia and this token.
+	Leading []Trivia
+
+	// Trailing holds the trivia after the token, up to and including the
+	// end of its line.
+	Trailing []Trivia
+}
+
+// Span returns the span of the token, without its trivia.
+func (t *Token) Span() ast.Span { return t.Loc }
+
+// IsName reports whether the token is a name or a keyword, possibly escaped
+// in backticks, rather than a literal, a parameter or punctuation.
+func (t *Token) IsName() bool {
+	r, _ := utf8.DecodeRuneInString(t.Text)
+	return r == '`' || unicode.In(r, unicode.L, unicode.Nl, unicode.Pc, unicode.Other_ID_Start)
+}
+
+func (t *Token) write(sb *strings.Builder) {
+	for _, tr := range t.Leading {
+		sb.WriteString(tr.Text)
+	}
+	sb.WriteString(t.Text)
+	for _, tr := range t.Trailing {
+		sb.WriteString(tr.Text)
+	}
+}
+
+// TriviaKind is the kind of a piece of trivia.
+type TriviaKind int
+
+const (
+	Whitespace   TriviaKind = iota // spaces, tabs and line breaks
+	LineComment                    // a "//" comment, without its line break
+	BlockComment                   // a "/* */" comment
+)
+
+func (k TriviaKind) String() string {
+	switch k {
+	case Whitespace:
+		return "Whitespace"
+	case LineComment:
+		return "LineComment"
+	case BlockComment:
+		return "BlockComment"
+	}
+	return "TriviaKind(?)"
+}
+
+// Trivia is text between tokens that has no meaning to the parser:
+// whitespace and comments.
+type Trivia struct {
+	Kind TriviaKind
+	Text string
+	Loc  ast.Span
+}
+
+// IsComment reports whether the trivia is a comment.
+func (t Trivia) IsComment() bool { return t.Kind != Whitespace }
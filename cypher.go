@@ -1,73 +1,41 @@
 package cypher
 
 import (
-	"fmt"
+	"context"
 
-	"github.com/antlr/antlr4/runtime/Go/antlr"
-	"github.com/olekukonko/tablewriter"
-
-	"github.com/a-poor/cypher/parser"
+	"github.com/a-poor/cypher/ast"
 )
 
-func Parse(query string) *parser.CypherParser {
-	// Setup the input
-	is := antlr.NewInputStream(query)
-
-	// Create the Lexer
-	lexer := parser.NewCypherLexer(is)
-	stream := antlr.NewCommonTokenStream(lexer, antlr.TokenDefaultChannel)
-
-	// Create the Parser
-	p := parser.NewCypherParser(stream)
-
-	return p
-}
-
-type cypherListener struct {
-	*parser.BaseCypherListener
-
-	tw *tablewriter.Table
-}
-
-func (l *cypherListener) EnterEveryRule(ctx antlr.ParserRuleContext) {
-	l.tw.Append([]string{
-		fmt.Sprintf("%T", ctx),
-		ctx.GetText(),
-	})
-
-	// fmt.Printf("Entering %q | %T | %+v\n", ctx.GetText(), ctx, ctx)
+// DefaultMaxDepth is how deeply expressions and patterns may be nested in
+// a statement, unless Options.MaxDepth says otherwise. The parser recurses
+// once for each level, and the limit keeps it from running out of stack.
+const DefaultMaxDepth = 1000
+
+// Parse parses a single Cypher statement.
+//
+// If the statement contains syntax errors, the returned error is an
+// ErrorList holding a *ParseError for each of them. The query is returned
+// even then: the parser recovers from errors clause by clause, and text it
+// cannot make sense of is kept as ast.BadClause and ast.BadExpr nodes.
+// A statement nested more than DefaultMaxDepth levels deep is not parsed
+// at all, and is kept as a single BadClause.
+func Parse(query string) (*ast.Query, error) {
+	q, errs := parse(context.Background(), newSource(query))
+	return q, errs.Err()
 }
 
-func (l *cypherListener) VisitErrorNode(node antlr.ErrorNode) {
-	panic(fmt.Errorf("%q, %T, %+v", node.GetText(), node, node))
+// parse parses the input of src as a single statement.
+func parse(ctx context.Context, src *source) (q *ast.Query, errs ErrorList) {
+	p := newParser(ctx, src)
+	defer func() {
+		if r := recover(); r != nil {
+			d, ok := r.(tooDeep)
+			if !ok {
+				panic(r)
+			}
+			q, errs = p.abandon(d)
+		}
+	}()
+	q = p.query()
+	return q, p.errors()
 }
-
-/*
-func main() {
-	// q := `MATCH (:Person {name: "Alice"})-[:KNOWS]->(:Person {name: "Bob"}) RETURN count(*)`
-	// q := `CREATE (n:Person {name: 'Andy', title: 'Developer'})`
-	q := `MATCH (p1:Person {name: "Alice", IsCool: $isCool})-[:KNOWS]->(p2:Person) WHERE p2.name != "Tom" RETURN count(*)`
-
-	// Setup the input
-	is := antlr.NewInputStream(q)
-
-	// Create the Lexer
-	lexer := parser.NewCypherLexer(is)
-	stream := antlr.NewCommonTokenStream(lexer, antlr.TokenDefaultChannel)
-
-	// Create the Parser
-	p := parser.NewCypherParser(stream)
-
-	tw := tablewriter.NewWriter(os.Stdout)
-	tw.SetColWidth(60)
-	tw.SetHeader([]string{"Type", "Text"})
-
-	// Finally parse the expression (by walking the tree)
-	// The "listener" is the action triggered when visiting each node
-	listener := &cypherListener{tw: tw}
-	antlr.ParseTreeWalkerDefault.Walk(listener, p.OC_Cypher())
-
-	tw.Render()
-}
-
-*/
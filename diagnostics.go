@@ -0,0 +1,191 @@
+package cypher
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// Render formats the error like a compiler diagnostic: the message, the
+// line of text it is on, and a caret under the offending token, e.g.
+//
+//	1:11: unexpected "RETRUN"
+//	  |
+//	1 | MATCH (n) RETRUN n
+//	  |           ^^^^^^
+//	  = did you mean RETURN?
+//
+// text must be the query or script that was parsed.
+func (e *ParseError) Render(text string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d:%d: %s\n", e.Line, e.Column, e.Msg)
+
+	offset := e.Offset
+	if offset > len(text) {
+		offset = len(text)
+	}
+	start := strings.LastIndexByte(text[:offset], '\n') + 1
+	end := strings.IndexByte(text[offset:], '\n')
+	if end < 0 {
+		end = len(text)
+	} else {
+		end += offset
+	}
+	line := strings.TrimSuffix(text[start:end], "\r")
+
+	num := strconv.Itoa(e.Line)
+	gutter := strings.Repeat(" ", len(num)) + " |"
+	fmt.Fprintf(&sb, "%s\n%s | %s\n", gutter, num, line)
+	fmt.Fprintf(&sb, "%s %s%s\n", gutter, indent(text[start:offset]), caret(text[offset:end], e.Token))
+	if e.Hint != "" {
+		fmt.Fprintf(&sb, "%s = %s\n", strings.Repeat(" ", len(num)), e.Hint)
+	}
+	return sb.String()
+}
+
+// Render formats every error in the list as ParseError.Render does,
+// separated by blank lines.
+func (l ErrorList) Render(text string) string {
+	parts := make([]string, len(l))
+	for i, e := range l {
+		parts[i] = e.Render(text)
+	}
+	return strings.Join(parts, "\n")
+}
+
+// indent returns the blank space that lines a caret up under the end of s.
+// Tabs are kept, so that the caret is in the right place whatever the tab
+// width, and wide characters count for two columns.
+func indent(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		if r == '\t' {
+			sb.WriteByte('\t')
+			continue
+		}
+		sb.WriteString(strings.Repeat(" ", runewidth.RuneWidth(r)))
+	}
+	return sb.String()
+}
+
+// caret returns a caret for every column of tok, which starts rest of the
+// line. A token that runs on past the end of the line is only underlined up
+// to it, and a missing one gets a single caret.
+func caret(rest, tok string) string {
+	if len(tok) > len(rest) {
+		tok = rest
+	}
+	width := runewidth.StringWidth(tok)
+	if width < 1 {
+		width = 1
+	}
+	return strings.Repeat("^", width)
+}
+
+// suggestKeyword returns a "did you mean" hint for a word that looks like a
+// misspelt keyword, such as MACTH or RETRUN, or "" if there is none. The
+// keywords the parser expected are preferred over the others.
+func suggestKeyword(word string, expected []string) string {
+	if !isWord(word) {
+		return ""
+	}
+	word = strings.ToUpper(word)
+
+	var preferred, reserved []string
+	for _, name := range expected {
+		if isKeyword(name) {
+			preferred = append(preferred, name)
+		}
+	}
+	for _, k := range reservedWords {
+		reserved = append(reserved, k.String())
+	}
+	for _, candidates := range [][]string{preferred, reserved} {
+		best, bestDist := "", maxTypoDistance(word)+1
+		for _, kw := range candidates {
+			if kw == word {
+				// The keyword is spelt right, it is just in the wrong
+				// place.
+				return ""
+			}
+			if d := editDistance(word, kw); d < bestDist {
+				best, bestDist = kw, d
+			}
+		}
+		if best != "" {
+			return fmt.Sprintf("did you mean %s?", best)
+		}
+	}
+	return ""
+}
+
+// maxTypoDistance is how many edits a word may be away from a keyword and
+// still be taken for a typo. Short words need to be closer, or nearly
+// every variable name would look like one.
+func maxTypoDistance(word string) int {
+	switch n := len(word); {
+	case n <= 2:
+		return 0
+	case n <= 4:
+		return 1
+	}
+	return 2
+}
+
+// isKeyword reports whether a description of an expected token is that of
+// a keyword, rather than punctuation or a class of tokens such as "a name".
+func isKeyword(name string) bool {
+	return isWord(name) && strings.ToUpper(name) == name
+}
+
+func isWord(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsLetter(r) && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// editDistance returns the optimal string alignment distance between a and
+// b: the number of insertions, deletions, substitutions and transpositions
+// of adjacent characters needed to turn one into the other.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
+
+func min(x int, ys ...int) int {
+	for _, y := range ys {
+		if y < x {
+			x = y
+		}
+	}
+	return x
+}
@@ -0,0 +1,80 @@
+package cypher
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/antlr/antlr4/runtime/Go/antlr"
+
+	antlrparser "github.com/a-poor/cypher/parser"
+)
+
+// The ANTLR parser generated from Cypher.g4 is kept as the reference for
+// the hand-written one: the two must agree on which queries are valid. The
+// only exception is literals whose values cannot be represented, such as
+// integers out of range, which the grammar has no way to rule out; no such
+// queries are kept in testdata.
+
+// antlrAccepts reports whether the generated parser accepts a query.
+func antlrAccepts(query string) bool {
+	l := &countingListener{DefaultErrorListener: antlr.NewDefaultErrorListener()}
+	lexer := antlrparser.NewCypherLexer(antlr.NewInputStream(query))
+	lexer.RemoveErrorListeners()
+	lexer.AddErrorListener(l)
+	p := antlrparser.NewCypherParser(antlr.NewCommonTokenStream(lexer, antlr.TokenDefaultChannel))
+	p.RemoveErrorListeners()
+	p.AddErrorListener(l)
+	p.OC_Cypher()
+	return l.errors == 0
+}
+
+type countingListener struct {
+	*antlr.DefaultErrorListener
+	errors int
+}
+
+func (l *countingListener) SyntaxError(antlr.Recognizer, interface{}, int, int, string, antlr.RecognitionException) {
+	l.errors++
+}
+
+// readInvalid returns the queries in testdata/invalid, one per line. Most
+// of them are invalid, but some are valid queries that only differ from
+// invalid ones in their whitespace.
+func readInvalid(tb testing.TB) []corpusQuery {
+	tb.Helper()
+	files, err := filepath.Glob(filepath.Join("testdata", "invalid", "*.txt"))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	sort.Strings(files)
+	var queries []corpusQuery
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		name := strings.TrimSuffix(filepath.Base(f), ".txt")
+		for i, line := range strings.Split(string(b), "\n") {
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			line = strings.ReplaceAll(line, `\n`, "\n")
+			queries = append(queries, corpusQuery{name: name + ":" + strconv.Itoa(i+1), text: line})
+		}
+	}
+	return queries
+}
+
+func TestDifferential(t *testing.T) {
+	queries := append(readCorpus(t), readInvalid(t)...)
+	for _, q := range queries {
+		_, err := Parse(q.text)
+		if got, want := err == nil, antlrAccepts(q.text); got != want {
+			t.Errorf("%s: %q: accepted = %v, ANTLR accepted = %v (%v)", q.name, q.text, got, want, err)
+		}
+	}
+}
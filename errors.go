@@ -0,0 +1,69 @@
+package cypher
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseError describes a single syntax error in a query.
+type ParseError struct {
+	Line        int      // 1-based line of the offending input
+	Column      int      // 1-based column, counted in runes
+	UTF16Column int      // 1-based column, counted in UTF-16 code units
+	Offset      int      // byte offset of the offending input
+	Token       string   // text of the offending token, empty at end of input
+	Expected    []string // what would have been accepted instead, e.g. "a pattern" or "'('"
+	Msg         string   // description of the problem
+	Hint        string   // suggested fix, e.g. "did you mean MATCH?", if any
+}
+
+// Error implements the error interface.
+func (e *ParseError) Error() string {
+	if e.Hint != "" {
+		return fmt.Sprintf("%d:%d: %s (%s)", e.Line, e.Column, e.Msg, e.Hint)
+	}
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Msg)
+}
+
+// ErrorList is a list of syntax errors, in the order they were found.
+//
+// Parse returns an ErrorList, rather than a single *ParseError, whenever a
+// query fails to parse.
+type ErrorList []*ParseError
+
+// Error implements the error interface.
+func (l ErrorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	case 2:
+		return fmt.Sprintf("%s (and 1 more error)", l[0])
+	}
+	return fmt.Sprintf("%s (and %d more errors)", l[0], len(l)-1)
+}
+
+// Err returns an error equivalent to the list, or nil if the list is
+// empty.
+func (l ErrorList) Err() error {
+	if len(l) == 0 {
+		return nil
+	}
+	return l
+}
+
+func joinExpected(names []string) string {
+	if len(names) == 1 {
+		return names[0]
+	}
+	return "one of " + strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
+
+func quoteRune(s string) string {
+	for _, r := range s {
+		return strconv.QuoteRune(r)
+	}
+	return `""`
+}
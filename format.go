@@ -0,0 +1,924 @@
+package cypher
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/a-poor/cypher/ast"
+)
+
+// Format prints a node as Cypher, in a canonical form: keywords in upper
+// case, single spaces between tokens, and each clause of a query on a line
+// of its own.
+//
+// Parentheses are only added where the structure of the tree needs them,
+// and names are escaped with backticks where they would not otherwise be
+// read as names. Literals are printed as they were written, from their
+// Literal fields, unless those are empty. Comments are left out, and so is
+// the text of BadClause and BadExpr nodes, which is printed as it is.
+//
+// Parsing the result of formatting a query gives back the same tree, apart
+// from spans and comments: ast.Equal reports them equal with
+// ast.IgnoreSpans and ast.IgnoreComments. Pretty prints in other styles.
+func Format(node ast.Node) string {
+	p := &printer{}
+	p.node(node)
+	return p.String()
+}
+
+func (p *printer) node(n ast.Node) {
+	switch n := n.(type) {
+	case nil:
+	case *ast.Script:
+		for i, s := range n.Statements {
+			if i > 0 {
+				p.write("\n")
+			}
+			if q, ok := s.(*ast.Query); ok {
+				p.query(q, ";")
+			} else {
+				p.node(s)
+			}
+		}
+	case *ast.Query:
+		p.query(n, "")
+	case ast.Clause:
+		p.clause(n)
+	case ast.Expr:
+		p.expr(n, precLowest)
+	case *ast.Pattern:
+		p.pattern(n, false, false)
+	case *ast.NodePattern:
+		p.nodePattern(n)
+	case *ast.RelationshipPattern:
+		p.relationshipPattern(n)
+	case *ast.YieldItem:
+		p.yieldItem(n)
+	case *ast.MergeAction:
+		p.mergeAction(n)
+	case *ast.SetItem:
+		p.setItem(n)
+	case *ast.RemoveItem:
+		p.removeItem(n)
+	case *ast.Projection:
+		p.projection(n, false)
+	case *ast.ProjectionItem:
+		p.projectionItem(n)
+	case *ast.SortItem:
+		p.sortItem(n)
+	case *ast.MapEntry:
+		p.mapEntry(n)
+	case *ast.CaseWhen:
+		p.caseWhen(n)
+	case *ast.ParamCommand:
+		p.write(":param ")
+		if n.Name != "" {
+			p.write(n.Name)
+			p.write(" => ")
+		}
+		p.write(n.Value)
+	case *ast.UseCommand:
+		p.write(":use ")
+		p.write(n.Database)
+	case *ast.BeginCommand:
+		p.write(":begin")
+	case *ast.CommitCommand:
+		p.write(":commit")
+	case *ast.RollbackCommand:
+		p.write(":rollback")
+	case *ast.Command:
+		p.write(":")
+		p.write(n.Name)
+		if n.Args != "" {
+			p.write(" ")
+			p.write(n.Args)
+		}
+	default:
+		panic(fmt.Sprintf("cypher.Format: unexpected node type %T", n))
+	}
+}
+
+// query writes a query, followed by term.
+func (p *printer) query(q *ast.Query, term string) {
+	p.open(q)
+	for i, c := range q.Clauses {
+		if i > 0 {
+			p.newline(0)
+		}
+		p.clause(c)
+	}
+	p.write(term)
+	// Those after the query are at the end of the text, each on a line of
+	// its own.
+	for _, c := range p.nodeComments(q) {
+		if c.Trailing {
+			p.newline(0)
+			p.write(c.Raw)
+		}
+	}
+}
+
+// Names
+
+// Kinds of names, which differ in the keywords they may be.
+const (
+	symbolicName = iota // a variable, function or procedure name
+	schemaName          // a label, relationship type or property key
+)
+
+// name writes a name, escaped if need be.
+func (p *printer) name(name string, kind int) {
+	p.write(quoteName(name, kind))
+}
+
+// quoteName returns a name as it must be written: as it is if it lexes as
+// a name of the kind on its own, and in backticks otherwise.
+func quoteName(name string, kind int) string {
+	l := newLexer(name, 0, len(name), nil)
+	t := l.next()
+	if t.end == len(name) && t.kind != tokEscapedName {
+		if kind == symbolicName && t.kind.isName() || kind == schemaName && t.kind.isSchemaName() {
+			return name
+		}
+	}
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// qualifiedName writes a function or procedure name, whose parts are
+// separated by dots.
+func (p *printer) qualifiedName(name string) {
+	for i, part := range strings.Split(name, ".") {
+		if i > 0 {
+			p.write(".")
+		}
+		p.name(part, symbolicName)
+	}
+}
+
+func (p *printer) variable(v *ast.Variable) {
+	if v != nil {
+		p.open(v)
+		p.name(v.Name, symbolicName)
+		p.close(v)
+	}
+}
+
+// labels writes labels, as in ":Person:Actor".
+func (p *printer) labels(labels []string) {
+	for _, l := range labels {
+		p.write(":")
+		p.name(l, schemaName)
+	}
+}
+
+// Clauses
+
+// clause writes a clause on one line if it fits, and broken over several
+// otherwise.
+func (p *printer) clause(c ast.Clause) {
+	p.open(c)
+	p.group(func(p *printer, broken bool) { p.clauseBody(c, broken) })
+	p.close(c)
+}
+
+// clauseBody writes a clause. Broken, its lists have an item on each line
+// and what follows them, such as WHERE, starts a line.
+func (p *printer) clauseBody(c ast.Clause, broken bool) {
+	switch c := c.(type) {
+	case *ast.BadClause:
+		p.write(c.Text())
+	case *ast.Match:
+		if c.Optional {
+			p.keyword("OPTIONAL ")
+		}
+		p.keyword("MATCH")
+		p.pattern(c.Pattern, true, broken)
+		p.where(c.Where, broken)
+	case *ast.Unwind:
+		p.keyword("UNWIND ")
+		p.expr(c.Expr, precLowest)
+		p.keyword(" AS ")
+		p.variable(c.Variable)
+	case *ast.InQueryCall:
+		p.call(c.Procedure, c.Args, false)
+		p.yield(false, c.Yield, c.Where, broken)
+	case *ast.StandaloneCall:
+		p.call(c.Procedure, c.Args, c.Implicit)
+		p.yield(c.YieldAll, c.Yield, c.Where, broken)
+	case *ast.Create:
+		p.keyword("CREATE")
+		p.pattern(c.Pattern, true, broken)
+	case *ast.Merge:
+		p.keyword("MERGE ")
+		p.pathPatternGroup(c.Pattern, 1)
+		for _, a := range c.Actions {
+			p.space(broken, 1)
+			p.mergeAction(a)
+		}
+	case *ast.Set:
+		p.set(c, broken)
+	case *ast.Remove:
+		p.keyword("REMOVE")
+		p.list(len(c.Items), true, broken, func(i int) { p.removeItem(c.Items[i]) })
+	case *ast.Delete:
+		if c.Detach {
+			p.keyword("DETACH ")
+		}
+		p.keyword("DELETE")
+		p.list(len(c.Exprs), true, broken, func(i int) { p.expr(c.Exprs[i], precLowest) })
+	case *ast.With:
+		p.keyword("WITH")
+		p.projection(c.Projection, broken)
+		p.where(c.Where, broken)
+	case *ast.Return:
+		p.keyword("RETURN")
+		p.projection(c.Projection, broken)
+	case *ast.Union:
+		p.keyword("UNION")
+		if c.All {
+			p.keyword(" ALL")
+		}
+	default:
+		panic(fmt.Sprintf("cypher.Format: unexpected clause type %T", c))
+	}
+}
+
+// list writes n items, separated by commas, with a space before each, or
+// before the first only if lead is set. Broken, and if there are several,
+// each item is on a line of its own instead.
+func (p *printer) list(n int, lead, broken bool, item func(i int)) {
+	several := broken && n > 1
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			p.write(",")
+		}
+		switch {
+		case several:
+			p.newline(1)
+		case i > 0 || lead:
+			p.write(" ")
+		}
+		item(i)
+	}
+}
+
+// where writes " WHERE cond", if there is a condition. Broken, WHERE
+// starts a line, and so does each operand of a long chain of ANDs or ORs.
+func (p *printer) where(cond ast.Expr, broken bool) {
+	if cond == nil {
+		return
+	}
+	p.space(broken, 0)
+	p.keyword("WHERE ")
+	b, ok := cond.(*ast.BinaryExpr)
+	if !broken || !ok || b.Op != ast.OpAnd && b.Op != ast.OpOr {
+		p.expr(cond, precLowest)
+		return
+	}
+	p.group(func(p *printer, broken bool) {
+		if broken {
+			p.chain(cond, b.Op)
+		} else {
+			p.expr(cond, precLowest)
+		}
+	})
+}
+
+// chain writes the operands of a chain of one binary operator, each after
+// the first on a new line, starting with the operator.
+func (p *printer) chain(x ast.Expr, op ast.BinaryOp) {
+	b, ok := x.(*ast.BinaryExpr)
+	if !ok || b.Op != op {
+		p.expr(x, binaryPrec[op])
+		return
+	}
+	p.open(b)
+	p.chain(b.X, op)
+	p.newline(1)
+	p.keyword(op.String())
+	p.write(" ")
+	p.expr(b.Y, binaryPrec[op]+1)
+	p.close(b)
+}
+
+// call writes CALL, a procedure name and its arguments.
+func (p *printer) call(name string, args []ast.Expr, implicit bool) {
+	p.keyword("CALL ")
+	p.qualifiedName(name)
+	if !implicit {
+		p.write("(")
+		p.exprs(args)
+		p.write(")")
+	}
+}
+
+// yield writes the YIELD of a procedure call, if it has one.
+func (p *printer) yield(all bool, items []*ast.YieldItem, where ast.Expr, broken bool) {
+	switch {
+	case all:
+		p.space(broken, 1)
+		p.keyword("YIELD *")
+		return
+	case items == nil:
+		return
+	}
+	p.space(broken, 1)
+	p.keyword("YIELD")
+	p.list(len(items), true, false, func(i int) { p.yieldItem(items[i]) })
+	p.where(where, broken)
+}
+
+func (p *printer) yieldItem(item *ast.YieldItem) {
+	p.open(item)
+	if item.Field != "" {
+		p.name(item.Field, symbolicName)
+		p.keyword(" AS ")
+	}
+	p.variable(item.Variable)
+	p.close(item)
+}
+
+func (p *printer) mergeAction(a *ast.MergeAction) {
+	p.open(a)
+	if a.OnCreate {
+		p.keyword("ON CREATE ")
+	} else {
+		p.keyword("ON MATCH ")
+	}
+	p.open(a.Set)
+	p.set(a.Set, false)
+	p.close(a.Set)
+	p.close(a)
+}
+
+func (p *printer) set(s *ast.Set, broken bool) {
+	p.keyword("SET")
+	p.list(len(s.Items), true, broken, func(i int) { p.setItem(s.Items[i]) })
+}
+
+func (p *printer) setItem(item *ast.SetItem) {
+	p.open(item)
+	p.expr(item.Target, precProperty)
+	switch item.Op {
+	case ast.SetLabels:
+		p.labels(item.Labels)
+	case ast.SetMerge:
+		p.write(" += ")
+		p.expr(item.Value, precLowest)
+	default:
+		p.write(" = ")
+		p.expr(item.Value, precLowest)
+	}
+	p.close(item)
+}
+
+func (p *printer) removeItem(item *ast.RemoveItem) {
+	p.open(item)
+	p.expr(item.Target, precProperty)
+	p.labels(item.Labels)
+	p.close(item)
+}
+
+// projection writes what follows WITH or RETURN, starting with a space.
+func (p *printer) projection(proj *ast.Projection, broken bool) {
+	p.open(proj)
+	if proj.Distinct {
+		p.keyword(" DISTINCT")
+	}
+	n := len(proj.Items)
+	if proj.Star {
+		n++
+	}
+	p.list(n, true, broken, func(i int) {
+		switch {
+		case !proj.Star:
+			p.projectionItem(proj.Items[i])
+		case i == 0:
+			p.write("*")
+		default:
+			p.projectionItem(proj.Items[i-1])
+		}
+	})
+	if len(proj.OrderBy) > 0 {
+		p.space(broken, 0)
+		p.keyword("ORDER BY")
+		p.list(len(proj.OrderBy), true, false, func(i int) { p.sortItem(proj.OrderBy[i]) })
+	}
+	if proj.Skip != nil {
+		p.space(broken, 0)
+		p.keyword("SKIP ")
+		p.expr(proj.Skip, precLowest)
+	}
+	if proj.Limit != nil {
+		p.space(broken, 0)
+		p.keyword("LIMIT ")
+		p.expr(proj.Limit, precLowest)
+	}
+	p.close(proj)
+}
+
+func (p *printer) projectionItem(item *ast.ProjectionItem) {
+	p.open(item)
+	p.expr(item.Expr, precLowest)
+	if item.Alias != nil {
+		p.keyword(" AS ")
+		p.variable(item.Alias)
+	}
+	p.close(item)
+}
+
+func (p *printer) sortItem(item *ast.SortItem) {
+	p.open(item)
+	p.expr(item.Expr, precLowest)
+	if item.Descending {
+		p.keyword(" DESC")
+	}
+	p.close(item)
+}
+
+// Patterns
+
+// pattern writes the paths of a pattern, with a space before the first if
+// lead is set. Broken, a pattern of several paths has each on a line of
+// its own, and paths too wide for a line are broken too.
+func (p *printer) pattern(pat *ast.Pattern, lead, broken bool) {
+	p.open(pat)
+	level := 1
+	if broken && len(pat.Paths) > 1 {
+		level = 2
+	}
+	p.list(len(pat.Paths), lead, broken, func(i int) {
+		if broken {
+			p.pathPatternGroup(pat.Paths[i], level)
+		} else {
+			p.pathPattern(pat.Paths[i], false, 0)
+		}
+	})
+	p.close(pat)
+}
+
+// pathPatternGroup writes a path on one line if it fits, and otherwise
+// with each relationship starting a line at the given level.
+func (p *printer) pathPatternGroup(path *ast.PathPattern, level int) {
+	p.group(func(p *printer, broken bool) { p.pathPattern(path, broken, level) })
+}
+
+func (p *printer) pathPattern(path *ast.PathPattern, broken bool, level int) {
+	p.open(path)
+	p.path(path, broken, level)
+	p.close(path)
+}
+
+func (p *printer) path(path *ast.PathPattern, broken bool, level int) {
+	if path.Variable != nil {
+		p.variable(path.Variable)
+		p.write(" = ")
+	}
+	if path.Shortest != ast.NotShortest {
+		p.write(path.Shortest.String())
+		p.write("(")
+	}
+	for i, n := range path.Nodes {
+		if i > 0 && i-1 < len(path.Relationships) {
+			switch {
+			case broken:
+				p.newline(level)
+			case p.style.ArrowSpaces:
+				p.write(" ")
+			}
+			p.relationshipPattern(path.Relationships[i-1])
+			if p.style.ArrowSpaces {
+				p.write(" ")
+			}
+		}
+		p.nodePattern(n)
+	}
+	if path.Shortest != ast.NotShortest {
+		p.write(")")
+	}
+}
+
+func (p *printer) nodePattern(n *ast.NodePattern) {
+	p.open(n)
+	p.write("(")
+	p.variable(n.Variable)
+	p.labels(n.Labels)
+	if n.Properties != nil {
+		if n.Variable != nil || len(n.Labels) > 0 {
+			p.write(" ")
+		}
+		p.expr(n.Properties, precAtom)
+	}
+	p.write(")")
+	p.close(n)
+}
+
+func (p *printer) relationshipPattern(r *ast.RelationshipPattern) {
+	p.open(r)
+	if r.Direction == ast.DirectionLeft || r.Direction == ast.DirectionBoth {
+		p.write("<")
+	}
+	p.write("-")
+	if r.Variable != nil || len(r.Types) > 0 || r.VarLength || r.Properties != nil {
+		p.write("[")
+		p.variable(r.Variable)
+		for i, t := range r.Types {
+			if i == 0 {
+				p.write(":")
+			} else {
+				p.write("|")
+			}
+			p.name(t, schemaName)
+		}
+		if r.VarLength {
+			p.write("*")
+			switch {
+			case r.MinHops != nil && r.MaxHops != nil && *r.MinHops == *r.MaxHops:
+				p.write(strconv.Itoa(*r.MinHops))
+			case r.MinHops != nil || r.MaxHops != nil:
+				if r.MinHops != nil {
+					p.write(strconv.Itoa(*r.MinHops))
+				}
+				p.write("..")
+				if r.MaxHops != nil {
+					p.write(strconv.Itoa(*r.MaxHops))
+				}
+			}
+		}
+		if r.Properties != nil {
+			if r.Variable != nil || len(r.Types) > 0 || r.VarLength {
+				p.write(" ")
+			}
+			p.expr(r.Properties, precAtom)
+		}
+		p.write("]")
+	}
+	p.write("-")
+	if r.Direction == ast.DirectionRight || r.Direction == ast.DirectionBoth {
+		p.write(">")
+	}
+	p.close(r)
+}
+
+// Expressions
+
+// Levels of precedence, from loosest to tightest, as in the grammar. An
+// expression is written in parentheses where a tighter one is needed.
+const (
+	precLowest     = iota
+	precOr         // OR
+	precXor        // XOR
+	precAnd        // AND
+	precNot        // NOT
+	precComparison // =, <>, <, >, <=, >=
+	precAdditive   // +, -
+	precMultiply   // *, /, %
+	precPower      // ^
+	precUnary      // unary + and -
+	precPostfix    // IN, STARTS WITH, ENDS WITH, CONTAINS, IS NULL, [...]
+	precLabels     // n:Label
+	precProperty   // n.key
+	precAtom       // everything else
+)
+
+var binaryPrec = [...]int{
+	ast.OpOr:  precOr,
+	ast.OpXor: precXor,
+	ast.OpAnd: precAnd,
+	ast.OpEq:  precComparison,
+	ast.OpNeq: precComparison,
+	ast.OpLt:  precComparison,
+	ast.OpGt:  precComparison,
+	ast.OpLe:  precComparison,
+	ast.OpGe:  precComparison,
+	ast.OpAdd: precAdditive,
+	ast.OpSub: precAdditive,
+	ast.OpMul: precMultiply,
+	ast.OpDiv: precMultiply,
+	ast.OpMod: precMultiply,
+	ast.OpPow: precPower,
+}
+
+// precedence returns the level of precedence of an expression.
+func precedence(x ast.Expr) int {
+	switch x := x.(type) {
+	case *ast.BinaryExpr:
+		if x.Op >= 0 && int(x.Op) < len(binaryPrec) {
+			return binaryPrec[x.Op]
+		}
+		return precLowest
+	case *ast.UnaryExpr:
+		if x.Op == ast.OpNot {
+			return precNot
+		}
+		return precUnary
+	case *ast.In, *ast.StringPredicate, *ast.IsNull, *ast.IndexExpr, *ast.SliceExpr:
+		return precPostfix
+	case *ast.HasLabels:
+		return precLabels
+	case *ast.PropertyAccess:
+		return precProperty
+	}
+	return precAtom
+}
+
+// exprs writes a comma separated list of expressions.
+func (p *printer) exprs(xs []ast.Expr) {
+	for i, x := range xs {
+		if i > 0 {
+			p.write(", ")
+		}
+		p.expr(x, precLowest)
+	}
+}
+
+// expr writes an expression where one of at least the given precedence is
+// needed, in parentheses if it is looser.
+func (p *printer) expr(x ast.Expr, prec int) {
+	if x == nil {
+		return
+	}
+	if precedence(x) < prec {
+		p.write("(")
+		defer p.write(")")
+	}
+	p.open(x)
+	defer p.close(x)
+
+	switch x := x.(type) {
+	case *ast.BadExpr:
+		p.write(x.Text())
+	case *ast.Variable:
+		p.name(x.Name, symbolicName)
+	case *ast.Parameter:
+		p.write("$")
+		if isDecimal(x.Name) {
+			p.write(x.Name)
+		} else {
+			p.name(x.Name, symbolicName)
+		}
+	case *ast.IntegerLiteral:
+		if x.Literal != "" {
+			p.write(x.Literal)
+		} else {
+			p.write(strconv.FormatInt(x.Value, 10))
+		}
+	case *ast.FloatLiteral:
+		if x.Literal != "" {
+			p.write(x.Literal)
+		} else {
+			p.write(formatFloat(x.Value))
+		}
+	case *ast.StringLiteral:
+		p.write(p.stringLiteral(x))
+	case *ast.BooleanLiteral:
+		if x.Value {
+			p.write("true")
+		} else {
+			p.write("false")
+		}
+	case *ast.NullLiteral:
+		p.write("null")
+	case *ast.ListLiteral:
+		p.write("[")
+		p.exprs(x.Elems)
+		p.write("]")
+	case *ast.MapLiteral:
+		p.write("{")
+		if p.style.MapSpaces && len(x.Entries) > 0 {
+			p.write(" ")
+		}
+		for i, e := range x.Entries {
+			if i > 0 {
+				p.write(", ")
+			}
+			p.mapEntry(e)
+		}
+		if p.style.MapSpaces && len(x.Entries) > 0 {
+			p.write(" ")
+		}
+		p.write("}")
+	case *ast.BinaryExpr:
+		prec := precedence(x)
+		p.expr(x.X, prec)
+		p.write(" ")
+		p.keyword(x.Op.String())
+		p.write(" ")
+		// Operators nest to the left, so the right operand must bind
+		// more tightly.
+		p.expr(x.Y, prec+1)
+	case *ast.UnaryExpr:
+		p.keyword(x.Op.String())
+		if x.Op == ast.OpNot {
+			p.write(" ")
+			p.expr(x.X, precNot)
+			break
+		}
+		// Keep "- -x" from running together.
+		if y, ok := x.X.(*ast.UnaryExpr); ok && y.Op != ast.OpNot {
+			p.write(" ")
+		}
+		p.expr(x.X, precUnary)
+	case *ast.StringPredicate:
+		p.expr(x.X, precPostfix)
+		p.write(" ")
+		p.keyword(x.Op.String())
+		p.write(" ")
+		p.expr(x.Y, precLabels)
+	case *ast.IsNull:
+		p.expr(x.X, precPostfix)
+		if x.Not {
+			p.keyword(" IS NOT NULL")
+		} else {
+			p.keyword(" IS NULL")
+		}
+	case *ast.In:
+		p.expr(x.X, precPostfix)
+		p.keyword(" IN ")
+		p.expr(x.List, precLabels)
+	case *ast.PropertyAccess:
+		p.expr(x.X, precProperty)
+		p.write(".")
+		p.name(x.Key, schemaName)
+	case *ast.HasLabels:
+		p.expr(x.X, precProperty)
+		p.labels(x.Labels)
+	case *ast.IndexExpr:
+		p.expr(x.X, precPostfix)
+		p.write("[")
+		p.expr(x.Index, precLowest)
+		p.write("]")
+	case *ast.SliceExpr:
+		p.expr(x.X, precPostfix)
+		p.write("[")
+		p.expr(x.Low, precLowest)
+		p.write("..")
+		p.expr(x.High, precLowest)
+		p.write("]")
+	case *ast.CaseExpr:
+		p.keyword("CASE")
+		if x.Subject != nil {
+			p.write(" ")
+			p.expr(x.Subject, precLowest)
+		}
+		for _, w := range x.Whens {
+			p.write(" ")
+			p.caseWhen(w)
+		}
+		if x.Else != nil {
+			p.keyword(" ELSE ")
+			p.expr(x.Else, precLowest)
+		}
+		p.keyword(" END")
+	case *ast.FunctionCall:
+		p.qualifiedName(x.Name)
+		p.write("(")
+		if x.Distinct {
+			p.keyword("DISTINCT ")
+		}
+		p.exprs(x.Args)
+		p.write(")")
+	case *ast.CountStar:
+		p.write("count(*)")
+	case *ast.FilterExpr:
+		p.write(strings.ToLower(x.Kind.String()))
+		p.write("(")
+		p.filter(x.Variable, x.List, x.Where)
+		p.write(")")
+	case *ast.ListComprehension:
+		p.write("[")
+		p.filter(x.Variable, x.List, x.Where)
+		if x.Result != nil {
+			p.write(" | ")
+			p.expr(x.Result, precLowest)
+		}
+		p.write("]")
+	case *ast.PatternComprehension:
+		p.write("[")
+		p.pathPattern(x.Pattern, false, 0)
+		p.where(x.Where, false)
+		p.write(" | ")
+		p.expr(x.Result, precLowest)
+		p.write("]")
+	case *ast.ExistsSubquery:
+		p.keyword("EXISTS")
+		p.write(" {")
+		if x.Query != nil {
+			// The clauses of a subquery are kept on one line.
+			p.open(x.Query)
+			for _, c := range x.Query.Clauses {
+				p.write(" ")
+				p.open(c)
+				p.clauseBody(c, false)
+				p.close(c)
+			}
+			p.close(x.Query)
+		} else {
+			p.pattern(x.Pattern, true, false)
+			p.where(x.Where, false)
+		}
+		p.write(" }")
+	case *ast.PathPattern:
+		p.path(x, false, 0)
+	default:
+		panic(fmt.Sprintf("cypher.Format: unexpected expression type %T", x))
+	}
+}
+
+func (p *printer) mapEntry(e *ast.MapEntry) {
+	p.open(e)
+	p.name(e.Key, schemaName)
+	p.write(": ")
+	p.expr(e.Value, precLowest)
+	p.close(e)
+}
+
+func (p *printer) caseWhen(w *ast.CaseWhen) {
+	p.open(w)
+	p.keyword("WHEN ")
+	p.expr(w.When, precLowest)
+	p.keyword(" THEN ")
+	p.expr(w.Then, precLowest)
+	p.close(w)
+}
+
+// filter writes "x IN list WHERE cond", as in a FilterExpr or
+// ListComprehension.
+func (p *printer) filter(v *ast.Variable, list, where ast.Expr) {
+	p.variable(v)
+	p.keyword(" IN ")
+	p.expr(list, precLowest)
+	p.where(where, false)
+}
+
+// Literals
+
+// isDecimal reports whether s is a DecimalInteger, which may also name a
+// parameter, as in "$0".
+func isDecimal(s string) bool {
+	l := newLexer(s, 0, len(s), nil)
+	t := l.next()
+	return t.kind == tokDecimal && t.end == len(s)
+}
+
+// formatFloat returns a float as a literal. The grammar has no "+" in
+// exponents, and needs a "." or an exponent to tell a float from an
+// integer.
+func formatFloat(f float64) string {
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	s = strings.Replace(s, "e+", "e", 1)
+	if !strings.ContainsAny(s, ".eIN") {
+		s += ".0"
+	}
+	return s
+}
+
+// stringLiteral returns a string literal as it is written, in the quotes
+// of the style.
+func (p *printer) stringLiteral(x *ast.StringLiteral) string {
+	switch {
+	case x.Literal == "" && p.style.Quote == QuoteDouble:
+		return quote(x.Value, '"')
+	case x.Literal == "":
+		return quote(x.Value, '\'')
+	case p.style.Quote == QuoteSingle && x.Literal[0] != '\'':
+		return quote(x.Value, '\'')
+	case p.style.Quote == QuoteDouble && x.Literal[0] != '"':
+		return quote(x.Value, '"')
+	}
+	return x.Literal
+}
+
+// quote returns a string as a literal in the given quotes, with escape
+// sequences for those quotes, backslashes and control characters.
+func quote(s string, q rune) string {
+	var sb strings.Builder
+	sb.WriteRune(q)
+	for _, r := range s {
+		switch r {
+		case q, '\\':
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		case '\b':
+			sb.WriteString(`\b`)
+		case '\f':
+			sb.WriteString(`\f`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		default:
+			if r < 0x20 || r == 0x7f || r == utf8.RuneError {
+				fmt.Fprintf(&sb, `\u%04X`, r)
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+	}
+	sb.WriteRune(q)
+	return sb.String()
+}
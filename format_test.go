@@ -0,0 +1,56 @@
+package cypher
+
+import (
+	"testing"
+
+	"github.com/a-poor/cypher/ast"
+)
+
+// Formatting a query and parsing the result must give back the same tree.
+func TestFormatRoundTrip(t *testing.T) {
+	queries := readCorpus(t)
+	for _, q := range readInvalid(t) {
+		if _, err := Parse(q.text); err == nil {
+			queries = append(queries, q)
+		}
+	}
+	for _, q := range queries {
+		want, err := Parse(q.text)
+		if err != nil {
+			t.Errorf("%s: %v", q.name, err)
+			continue
+		}
+		text := Format(want)
+		got, err := Parse(text)
+		if err != nil {
+			t.Errorf("%s: formatted query does not parse: %v\n%s", q.name, err, text)
+			continue
+		}
+		if !ast.Equal(got, want, ast.IgnoreSpans|ast.IgnoreComments) {
+			t.Errorf("%s: formatted query parses differently:\n%s", q.name, text)
+		}
+		if again := Format(got); again != text {
+			t.Errorf("%s: formatting is not stable:\n%s\n%s", q.name, text, again)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"return (1+2)*3", "RETURN (1 + 2) * 3"},
+		{"RETURN ((1 * 2)) + 3", "RETURN 1 * 2 + 3"},
+		{"RETURN a - (b - c), (a - b) - c", "RETURN a - (b - c), a - b - c"},
+		{"RETURN (NOT a) = b, NOT (a = b)", "RETURN (NOT a) = b, NOT a = b"},
+		{"MATCH (`a b`:`match`) RETURN `a b`.x AS `match`", "MATCH (`a b`:match)\nRETURN `a b`.x AS `match`"},
+		{"match (n)-[r:T*1..2]->(m) where n.x>1 return m", "MATCH (n)-[r:T*1..2]->(m) WHERE n.x > 1\nRETURN m"},
+	}
+	for _, tt := range tests {
+		q, err := Parse(tt.in)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.in, err)
+		}
+		if got := Format(q); got != tt.want {
+			t.Errorf("Format(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+//go:build go1.18
+
+package cypher
+
+import "testing"
+
+// FuzzParse checks that the parsers never panic, whatever their input, and
+// that concrete syntax trees print back to the text they were parsed from.
+//
+//	go test -run '^$' -fuzz FuzzParse
+func FuzzParse(f *testing.F) {
+	for _, q := range readCorpus(f) {
+		f.Add(q.text)
+	}
+	for _, q := range readInvalid(f) {
+		f.Add(q.text)
+	}
+	f.Add("MATCH (a)\xb1")
+	f.Add("RETURN 'a\xff'")
+	f.Add(":param x => 1\nRETURN $x; // done")
+	f.Fuzz(func(t *testing.T, text string) {
+		Parse(text)
+		ParseScript(text)
+		if tree, _ := ParseCST(text); tree.String() != text {
+			t.Errorf("ParseCST(%q).String() = %q", text, tree.String())
+		}
+		if tree, _ := ParseScriptCST(text); tree.String() != text {
+			t.Errorf("ParseScriptCST(%q).String() = %q", text, tree.String())
+		}
+	})
+}
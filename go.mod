@@ -2,9 +2,6 @@ module github.com/a-poor/cypher
 
 go 1.17
 
-require (
-	github.com/antlr/antlr4/runtime/Go/antlr v0.0.0-20211213210530-5d6a78255383
-	github.com/olekukonko/tablewriter v0.0.5
-)
+require github.com/antlr/antlr4/runtime/Go/antlr v0.0.0-20211213210530-5d6a78255383
 
-require github.com/mattn/go-runewidth v0.0.9 // indirect
+require github.com/mattn/go-runewidth v0.0.9
@@ -0,0 +1,268 @@
+package cypher
+
+import (
+	"html"
+	"strings"
+
+	"github.com/a-poor/cypher/ast"
+	"github.com/a-poor/cypher/cst"
+)
+
+// TokenKind is the kind of a token that Tokenize returns.
+type TokenKind int
+
+const (
+	TokenWhitespace TokenKind = iota
+	TokenComment
+	TokenKeyword
+	TokenIdentifier // a variable, or a name in a clause with syntax errors
+	TokenLabel
+	TokenRelationshipType
+	TokenPropertyKey
+	TokenString
+	TokenNumber
+	TokenParameter // a parameter, with its "$"
+	TokenOperator
+	TokenPunctuation
+	TokenFunction // the name of a function or procedure
+)
+
+var tokenKindNames = [...]string{
+	TokenWhitespace:       "whitespace",
+	TokenComment:          "comment",
+	TokenKeyword:          "keyword",
+	TokenIdentifier:       "identifier",
+	TokenLabel:            "label",
+	TokenRelationshipType: "relationship type",
+	TokenPropertyKey:      "property key",
+	TokenString:           "string",
+	TokenNumber:           "number",
+	TokenParameter:        "parameter",
+	TokenOperator:         "operator",
+	TokenPunctuation:      "punctuation",
+	TokenFunction:         "function",
+}
+
+func (k TokenKind) String() string {
+	if k < 0 || int(k) >= len(tokenKindNames) {
+		return "unknown"
+	}
+	return tokenKindNames[k]
+}
+
+// Token is a token of Cypher source text, classified for highlighting.
+type Token struct {
+	Kind TokenKind
+	Text string
+	Loc  ast.Span
+}
+
+// Tokenize splits a query, or a script as ParseScript reads it, into
+// tokens. The tokens cover all of the text, whitespace and comments
+// included, so joining their texts gives back the text.
+//
+// Tokens are classified by where they are in the syntax tree, and not only
+// by what they look like: a name may be a label, a relationship type, a
+// property key or a variable, and a keyword such as COUNT may be the name
+// of a function. The names of the fields a procedure yields are property
+// keys, since they are the keys of the records it returns. Where the text
+// has syntax errors, the tokens of the clauses with errors are classified
+// by what they look like, with names that are keywords as keywords and
+// others as identifiers. The tokens of commands, such as ":param", are
+// keywords.
+func Tokenize(query string) []Token {
+	tree, _ := ParseScriptCST(query)
+	var toks []Token
+	add := func(kind TokenKind, text string, loc ast.Span) {
+		// A parameter is a single token, although the lexer splits it
+		// into "$" and a name, and so are runs of whitespace, which the
+		// tree splits into lines.
+		if n := len(toks); n > 0 && kind == toks[n-1].Kind && (kind == TokenParameter || kind == TokenWhitespace) &&
+			toks[n-1].Loc.End.Offset == loc.Start.Offset {
+			toks[n-1].Text += text
+			toks[n-1].Loc.End = loc.End
+			return
+		}
+		toks = append(toks, Token{Kind: kind, Text: text, Loc: loc})
+	}
+	trivia := func(ts []cst.Trivia) {
+		for _, t := range ts {
+			kind := TokenWhitespace
+			if t.IsComment() {
+				kind = TokenComment
+			}
+			add(kind, t.Text, t.Loc)
+		}
+	}
+	var walk func(n *cst.Node)
+	walk = func(n *cst.Node) {
+		for i, c := range n.Children {
+			switch c := c.(type) {
+			case *cst.Token:
+				trivia(c.Leading)
+				add(classifyToken(n, i), c.Text, c.Loc)
+				trivia(c.Trailing)
+			case *cst.Node:
+				walk(c)
+			}
+		}
+	}
+	walk(tree.Root)
+	trivia(tree.EOF.Leading)
+	return toks
+}
+
+// operatorTokens are the tokens that are operators in expressions.
+var operatorTokens = map[string]bool{
+	"+": true, "-": true, "*": true, "/": true, "%": true, "^": true,
+	"=": true, "<>": true, "<": true, ">": true, "<=": true, ">=": true,
+	"+=": true,
+}
+
+// classifyToken returns the kind of the i'th child of a node, which is a
+// token.
+func classifyToken(n *cst.Node, i int) TokenKind {
+	tok := n.Children[i].(*cst.Token)
+	text, name := tok.Text, tok.IsName()
+	switch n.AST.(type) {
+	case *ast.Command, *ast.ParamCommand, *ast.UseCommand, *ast.BeginCommand,
+		*ast.CommitCommand, *ast.RollbackCommand:
+		return TokenKeyword
+	case *ast.Variable:
+		return TokenIdentifier
+	case *ast.Parameter:
+		return TokenParameter
+	case *ast.StringLiteral:
+		return TokenString
+	case *ast.IntegerLiteral, *ast.FloatLiteral:
+		return TokenNumber
+	case *ast.PropertyAccess, *ast.MapEntry:
+		if name {
+			return TokenPropertyKey
+		}
+	case *ast.FunctionCall, *ast.CountStar, *ast.PathPattern:
+		// The name of the function, which may have a namespace, or the
+		// shortestPath around a pattern.
+		if name && !strings.EqualFold(text, "DISTINCT") || text == "." {
+			return TokenFunction
+		}
+	case *ast.InQueryCall, *ast.StandaloneCall:
+		if procedureName(n, i) {
+			return TokenFunction
+		}
+	case *ast.YieldItem:
+		// The field before AS; a field yielded as it is is a Variable.
+		if i == 0 && name {
+			return TokenPropertyKey
+		}
+	case *ast.BinaryExpr, *ast.UnaryExpr, *ast.SetItem:
+		if operatorTokens[text] {
+			return TokenOperator
+		}
+	}
+	if i > 0 && name {
+		if prev, ok := n.Children[i-1].(*cst.Token); ok && (prev.Text == ":" || prev.Text == "|") {
+			if _, ok := n.AST.(*ast.RelationshipPattern); ok {
+				return TokenRelationshipType
+			}
+			return TokenLabel
+		}
+	}
+	if text == "=" {
+		if _, ok := n.AST.(*ast.PathPattern); ok {
+			return TokenOperator // as in "p = (a)-->(b)"
+		}
+	}
+	return lexicalKind(tok)
+}
+
+// procedureName reports whether the i'th child of a CALL clause, which is
+// a token, is part of the name of the procedure: after CALL and before the
+// arguments or YIELD.
+func procedureName(n *cst.Node, i int) bool {
+	tok := n.Children[i].(*cst.Token)
+	for j := i - 1; j >= 0; j-- {
+		prev, ok := n.Children[j].(*cst.Token)
+		if !ok {
+			return false
+		}
+		switch {
+		case strings.EqualFold(prev.Text, "CALL"):
+			return tok.IsName() || tok.Text == "."
+		case prev.Text == "(" || strings.EqualFold(prev.Text, "YIELD"):
+			return false
+		}
+	}
+	return false
+}
+
+// lexicalKind returns the kind of a token, going by what it looks like.
+func lexicalKind(tok *cst.Token) TokenKind {
+	text := tok.Text
+	switch {
+	case tok.IsName():
+		if strings.HasPrefix(text, "`") || keywords[strings.ToUpper(text)] == 0 {
+			return TokenIdentifier
+		}
+		return TokenKeyword
+	case text[0] >= '0' && text[0] <= '9':
+		return TokenNumber
+	case text[0] == '\'' || text[0] == '"':
+		return TokenString
+	}
+	return TokenPunctuation
+}
+
+// ANSI escape sequences for the kinds of tokens, for HighlightANSI.
+var ansiColors = map[TokenKind]string{
+	TokenComment:          "\x1b[90m",   // grey
+	TokenKeyword:          "\x1b[1;34m", // bold blue
+	TokenLabel:            "\x1b[33m",   // yellow
+	TokenRelationshipType: "\x1b[36m",   // cyan
+	TokenPropertyKey:      "\x1b[35m",   // magenta
+	TokenString:           "\x1b[32m",   // green
+	TokenNumber:           "\x1b[31m",   // red
+	TokenParameter:        "\x1b[1;35m", // bold magenta
+}
+
+// HighlightANSI returns the text of a query or script with its tokens
+// coloured by ANSI escape sequences, for terminals. Identifiers, the names
+// of functions, operators and punctuation are left in the terminal's
+// colour.
+func HighlightANSI(query string) string {
+	var sb strings.Builder
+	for _, tok := range Tokenize(query) {
+		if color := ansiColors[tok.Kind]; color != "" {
+			sb.WriteString(color)
+			sb.WriteString(tok.Text)
+			sb.WriteString("\x1b[0m")
+			continue
+		}
+		sb.WriteString(tok.Text)
+	}
+	return sb.String()
+}
+
+// HighlightHTML returns the text of a query or script as HTML, with each
+// token other than whitespace in a span whose class is "cypher-" and its
+// kind, with dashes for spaces, as in:
+//
+//	<span class="cypher-keyword">MATCH</span> <span class="cypher-punctuation">(</span>...
+//
+// The result keeps the line breaks of the text, so it belongs in a <pre>
+// element, and a style sheet gives the classes their colours.
+func HighlightHTML(query string) string {
+	var sb strings.Builder
+	for _, tok := range Tokenize(query) {
+		if tok.Kind == TokenWhitespace {
+			sb.WriteString(tok.Text)
+			continue
+		}
+		sb.WriteString(`<span class="cypher-`)
+		sb.WriteString(strings.ReplaceAll(tok.Kind.String(), " ", "-"))
+		sb.WriteString(`">`)
+		sb.WriteString(html.EscapeString(tok.Text))
+		sb.WriteString("</span>")
+	}
+	return sb.String()
+}
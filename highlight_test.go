@@ -0,0 +1,137 @@
+package cypher
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string // the tokens other than whitespace, as kind:text
+	}{
+		{
+			"MATCH (a:Person {name: $name})-[r:KNOWS|LIKES*1..3]->(b)",
+			[]string{
+				"keyword:MATCH", "punctuation:(", "identifier:a", "punctuation::", "label:Person",
+				"punctuation:{", "property key:name", "punctuation::", "parameter:$name", "punctuation:}",
+				"punctuation:)", "punctuation:-", "punctuation:[", "identifier:r", "punctuation::",
+				"relationship type:KNOWS", "punctuation:|", "relationship type:LIKES", "punctuation:*",
+				"number:1", "punctuation:..", "number:3", "punctuation:]", "punctuation:-", "punctuation:>",
+				"punctuation:(", "identifier:b", "punctuation:)",
+			},
+		},
+		{
+			"MATCH p = (n) WHERE n:`My Label` AND n.age >= 1.5 RETURN p",
+			[]string{
+				"keyword:MATCH", "identifier:p", "operator:=", "punctuation:(", "identifier:n", "punctuation:)",
+				"keyword:WHERE", "identifier:n", "punctuation::", "label:`My Label`", "keyword:AND",
+				"identifier:n", "punctuation:.", "property key:age", "operator:>=", "number:1.5",
+				"keyword:RETURN", "identifier:p",
+			},
+		},
+		{
+			"MATCH (n) SET n += {x: 'y'}, n:Old REMOVE n.x",
+			[]string{
+				"keyword:MATCH", "punctuation:(", "identifier:n", "punctuation:)", "keyword:SET",
+				"identifier:n", "operator:+=", "punctuation:{", "property key:x", "punctuation::",
+				"string:'y'", "punctuation:}", "punctuation:,", "identifier:n", "punctuation::", "label:Old",
+				"keyword:REMOVE", "identifier:n", "punctuation:.", "property key:x",
+			},
+		},
+		{
+			// Keywords that name functions and properties.
+			"MATCH (n) RETURN count(DISTINCT n.count), count(*)",
+			[]string{
+				"keyword:MATCH", "punctuation:(", "identifier:n", "punctuation:)", "keyword:RETURN",
+				"function:count", "punctuation:(", "keyword:DISTINCT", "identifier:n", "punctuation:.",
+				"property key:count", "punctuation:)", "punctuation:,", "function:count", "punctuation:(",
+				"punctuation:*", "punctuation:)",
+			},
+		},
+		{
+			"CALL db.labels() YIELD label AS l RETURN -l",
+			[]string{
+				"keyword:CALL", "function:db", "function:.", "function:labels", "punctuation:(",
+				"punctuation:)", "keyword:YIELD", "property key:label", "keyword:AS", "identifier:l",
+				"keyword:RETURN", "operator:-", "identifier:l",
+			},
+		},
+		{
+			// Names with namespaces, whose parts may be keywords.
+			"CALL db.index.fulltext.queryNodes('i', 'q') YIELD node RETURN apoc.text.join('a', '')",
+			[]string{
+				"keyword:CALL", "function:db", "function:.", "function:index", "function:.",
+				"function:fulltext", "function:.", "function:queryNodes", "punctuation:(", "string:'i'",
+				"punctuation:,", "string:'q'", "punctuation:)", "keyword:YIELD", "identifier:node",
+				"keyword:RETURN", "function:apoc", "function:.", "function:text", "function:.",
+				"function:join", "punctuation:(", "string:'a'", "punctuation:,", "string:''", "punctuation:)",
+			},
+		},
+		{
+			":param x => 1\nRETURN $x; // x",
+			[]string{
+				"keyword::param x => 1", "keyword:RETURN", "parameter:$x", "punctuation:;", "comment:// x",
+			},
+		},
+		{
+			// Clauses with errors are tokenized by what they look like.
+			"MATCH (n) RETRUN n.x",
+			[]string{
+				"keyword:MATCH", "punctuation:(", "identifier:n", "punctuation:)", "identifier:RETRUN",
+				"identifier:n", "punctuation:.", "identifier:x",
+			},
+		},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, tok := range Tokenize(tt.in) {
+			if tok.Kind != TokenWhitespace {
+				got = append(got, tok.Kind.String()+":"+tok.Text)
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Tokenize(%q) =\n%q\nwant\n%q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// The tokens must cover the text, in order and without gaps.
+func TestTokenizeCorpus(t *testing.T) {
+	for _, q := range readCorpus(t) {
+		var sb strings.Builder
+		offset := 0
+		for _, tok := range Tokenize(q.text) {
+			if tok.Loc.Start.Offset != offset || tok.Loc.End.Offset != offset+len(tok.Text) {
+				t.Errorf("%s: token %q at %d-%d; want it at %d", q.name, tok.Text, tok.Loc.Start.Offset, tok.Loc.End.Offset, offset)
+				break
+			}
+			offset = tok.Loc.End.Offset
+			sb.WriteString(tok.Text)
+		}
+		if sb.String() != q.text {
+			t.Errorf("%s: tokens make\n%s\nwant\n%s", q.name, sb.String(), q.text)
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	const q = "MATCH (n:Person) // <all>\nWHERE n.name = 'A&B' RETURN n"
+	wantHTML := `<span class="cypher-keyword">MATCH</span> <span class="cypher-punctuation">(</span>` +
+		`<span class="cypher-identifier">n</span><span class="cypher-punctuation">:</span>` +
+		`<span class="cypher-label">Person</span><span class="cypher-punctuation">)</span> ` +
+		`<span class="cypher-comment">// &lt;all&gt;</span>` + "\n" +
+		`<span class="cypher-keyword">WHERE</span> <span class="cypher-identifier">n</span>` +
+		`<span class="cypher-punctuation">.</span><span class="cypher-property-key">name</span> ` +
+		`<span class="cypher-operator">=</span> <span class="cypher-string">&#39;A&amp;B&#39;</span> ` +
+		`<span class="cypher-keyword">RETURN</span> <span class="cypher-identifier">n</span>`
+	if got := HighlightHTML(q); got != wantHTML {
+		t.Errorf("HighlightHTML =\n%s\nwant\n%s", got, wantHTML)
+	}
+	wantANSI := "\x1b[1;34mMATCH\x1b[0m (n:\x1b[33mPerson\x1b[0m) \x1b[90m// <all>\x1b[0m\n" +
+		"\x1b[1;34mWHERE\x1b[0m n.\x1b[35mname\x1b[0m = \x1b[32m'A&B'\x1b[0m \x1b[1;34mRETURN\x1b[0m n"
+	if got := HighlightANSI(q); got != wantANSI {
+		t.Errorf("HighlightANSI =\n%q\nwant\n%q", got, wantANSI)
+	}
+}
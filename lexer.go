@@ -0,0 +1,666 @@
+package cypher
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// tokenKind is the kind of a token of Cypher source text.
+type tokenKind int
+
+const (
+	tokEOF          tokenKind = iota
+	tokIllegal                // a character that cannot start any token
+	tokSpace                  // whitespace
+	tokLineComment            // a "//" comment, without its line break
+	tokBlockComment           // a "/* */" comment
+
+	tokName        // an unescaped name, such as n or Person
+	tokEscapedName // a name in backticks
+	tokString      // a string in single or double quotes
+	tokDecimal     // a decimal integer
+	tokHex         // a hexadecimal integer, such as 0x1F
+	tokOctal       // an octal integer, such as 017
+	tokFloat       // a floating point number, such as 1.5 or 1e3
+
+	tokSemicolon
+	tokComma
+	tokDot
+	tokDotDot
+	tokColon
+	tokPipe
+	tokDollar
+	tokLParen
+	tokRParen
+	tokLBracket
+	tokRBracket
+	tokLBrace
+	tokRBrace
+	tokEq
+	tokNeq
+	tokLt
+	tokGt
+	tokLe
+	tokGe
+	tokPlus
+	tokPlusEq
+	tokMinus
+	tokStar
+	tokSlash
+	tokPercent
+	tokCaret
+	tokLeftArrowHead  // the Unicode forms of '<', which only start relationships
+	tokRightArrowHead // the Unicode forms of '>'
+	tokDash           // the Unicode forms of '-'
+
+	// Keywords, which are matched without regard to case.
+	kwUnion
+	kwAll
+	kwOptional
+	kwMatch
+	kwUnwind
+	kwAs
+	kwMerge
+	kwOn
+	kwCreate
+	kwSet
+	kwDetach
+	kwDelete
+	kwRemove
+	kwCall
+	kwYield
+	kwWith
+	kwReturn
+	kwDistinct
+	kwOrder
+	kwBy
+	kwSkip
+	kwLimit
+	kwAscending
+	kwAsc
+	kwDescending
+	kwDesc
+	kwWhere
+	kwOr
+	kwXor
+	kwAnd
+	kwNot
+	kwIn
+	kwStarts
+	kwEnds
+	kwContains
+	kwIs
+	kwNull
+	kwCount
+	kwAny
+	kwNone
+	kwSingle
+	kwTrue
+	kwFalse
+	kwExists
+	kwCase
+	kwElse
+	kwEnd
+	kwWhen
+	kwThen
+	kwConstraint
+	kwDo
+	kwFor
+	kwRequire
+	kwUnique
+	kwMandatory
+	kwScalar
+	kwOf
+	kwAdd
+	kwDrop
+	kwFilter
+	kwExtract
+)
+
+// tokenNames holds the way each kind of token is described in error
+// messages: keywords and punctuation as they are written, other tokens by
+// what they are.
+var tokenNames = [...]string{
+	tokEOF:            "end of input",
+	tokIllegal:        "an invalid character",
+	tokSpace:          "whitespace",
+	tokLineComment:    "a comment",
+	tokBlockComment:   "a comment",
+	tokName:           "a name",
+	tokEscapedName:    "a name",
+	tokString:         "a string",
+	tokDecimal:        "an integer",
+	tokHex:            "an integer",
+	tokOctal:          "an integer",
+	tokFloat:          "a number",
+	tokSemicolon:      "';'",
+	tokComma:          "','",
+	tokDot:            "'.'",
+	tokDotDot:         "'..'",
+	tokColon:          "':'",
+	tokPipe:           "'|'",
+	tokDollar:         "'$'",
+	tokLParen:         "'('",
+	tokRParen:         "')'",
+	tokLBracket:       "'['",
+	tokRBracket:       "']'",
+	tokLBrace:         "'{'",
+	tokRBrace:         "'}'",
+	tokEq:             "'='",
+	tokNeq:            "'<>'",
+	tokLt:             "'<'",
+	tokGt:             "'>'",
+	tokLe:             "'<='",
+	tokGe:             "'>='",
+	tokPlus:           "'+'",
+	tokPlusEq:         "'+='",
+	tokMinus:          "'-'",
+	tokStar:           "'*'",
+	tokSlash:          "'/'",
+	tokPercent:        "'%'",
+	tokCaret:          "'^'",
+	tokLeftArrowHead:  "'<'",
+	tokRightArrowHead: "'>'",
+	tokDash:           "'-'",
+	kwUnion:           "UNION",
+	kwAll:             "ALL",
+	kwOptional:        "OPTIONAL",
+	kwMatch:           "MATCH",
+	kwUnwind:          "UNWIND",
+	kwAs:              "AS",
+	kwMerge:           "MERGE",
+	kwOn:              "ON",
+	kwCreate:          "CREATE",
+	kwSet:             "SET",
+	kwDetach:          "DETACH",
+	kwDelete:          "DELETE",
+	kwRemove:          "REMOVE",
+	kwCall:            "CALL",
+	kwYield:           "YIELD",
+	kwWith:            "WITH",
+	kwReturn:          "RETURN",
+	kwDistinct:        "DISTINCT",
+	kwOrder:           "ORDER",
+	kwBy:              "BY",
+	kwSkip:            "SKIP",
+	kwLimit:           "LIMIT",
+	kwAscending:       "ASCENDING",
+	kwAsc:             "ASC",
+	kwDescending:      "DESCENDING",
+	kwDesc:            "DESC",
+	kwWhere:           "WHERE",
+	kwOr:              "OR",
+	kwXor:             "XOR",
+	kwAnd:             "AND",
+	kwNot:             "NOT",
+	kwIn:              "IN",
+	kwStarts:          "STARTS",
+	kwEnds:            "ENDS",
+	kwContains:        "CONTAINS",
+	kwIs:              "IS",
+	kwNull:            "NULL",
+	kwCount:           "COUNT",
+	kwAny:             "ANY",
+	kwNone:            "NONE",
+	kwSingle:          "SINGLE",
+	kwTrue:            "TRUE",
+	kwFalse:           "FALSE",
+	kwExists:          "EXISTS",
+	kwCase:            "CASE",
+	kwElse:            "ELSE",
+	kwEnd:             "END",
+	kwWhen:            "WHEN",
+	kwThen:            "THEN",
+	kwConstraint:      "CONSTRAINT",
+	kwDo:              "DO",
+	kwFor:             "FOR",
+	kwRequire:         "REQUIRE",
+	kwUnique:          "UNIQUE",
+	kwMandatory:       "MANDATORY",
+	kwScalar:          "SCALAR",
+	kwOf:              "OF",
+	kwAdd:             "ADD",
+	kwDrop:            "DROP",
+	kwFilter:          "FILTER",
+	kwExtract:         "EXTRACT",
+}
+
+func (k tokenKind) String() string { return tokenNames[k] }
+
+// keywords maps the upper case spelling of each keyword to its kind.
+var keywords = func() map[string]tokenKind {
+	m := map[string]tokenKind{}
+	for k := kwUnion; k <= kwExtract; k++ {
+		m[tokenNames[k]] = k
+	}
+	return m
+}()
+
+// reservedWords are the keywords that may not be used as variable names,
+// though they may still be used as labels, relationship types and property
+// keys. They are listed in alphabetical order.
+var reservedWords = []tokenKind{
+	kwAdd, kwAll, kwAnd, kwAs, kwAsc, kwAscending, kwBy, kwCase, kwConstraint,
+	kwContains, kwCreate, kwDelete, kwDesc, kwDescending, kwDetach,
+	kwDistinct, kwDo, kwDrop, kwElse, kwEnd, kwEnds, kwExists, kwFalse,
+	kwFor, kwIn, kwIs, kwLimit, kwMandatory, kwMatch, kwMerge, kwNot,
+	kwNull, kwOf, kwOn, kwOptional, kwOr, kwOrder, kwRemove, kwRequire,
+	kwReturn, kwScalar, kwSet, kwSkip, kwStarts, kwThen, kwTrue, kwUnion,
+	kwUnique, kwUnwind, kwWhen, kwWhere, kwWith, kwXor,
+}
+
+// isReserved reports whether the kind is one of reservedWords.
+func (k tokenKind) isReserved() bool {
+	for _, r := range reservedWords {
+		if k == r {
+			return true
+		}
+	}
+	return false
+}
+
+// isName reports whether a token of the kind can be used as a variable,
+// function or procedure name. A few keywords, such as COUNT and ANY, can.
+func (k tokenKind) isName() bool {
+	switch k {
+	case tokName, tokEscapedName, kwCount, kwAny, kwNone, kwSingle, kwFilter, kwExtract:
+		return true
+	}
+	return false
+}
+
+// isSchemaName reports whether a token of the kind can be used as a label,
+// relationship type or property key, which may also be reserved words.
+func (k tokenKind) isSchemaName() bool {
+	return k.isName() || k.isReserved()
+}
+
+// isTrivia reports whether the kind is whitespace or a comment.
+func (k tokenKind) isTrivia() bool {
+	return k == tokSpace || k == tokLineComment || k == tokBlockComment
+}
+
+// token is a single token of the source text.
+type token struct {
+	kind       tokenKind
+	start, end int    // byte offsets in the source text
+	text       string // the source text of the token
+
+	// sp is set when whitespace or a comment comes before the token. The
+	// grammar requires whitespace between some tokens and forbids it
+	// between others.
+	sp bool
+}
+
+// lexer splits source text into tokens, whitespace and comments included,
+// following the lexical rules of the openCypher grammar.
+type lexer struct {
+	text string
+	pos  int // byte offset of the next token
+	end  int // byte offset at which to stop
+
+	// err, if set, is called with the offset of each lexical error, such
+	// as an invalid character or an unterminated string.
+	err func(offset int, msg string)
+}
+
+// newLexer returns a lexer for the byte range [start, end) of text. Token
+// offsets are relative to the whole text.
+func newLexer(text string, start, end int, err func(offset int, msg string)) *lexer {
+	return &lexer{text: text, pos: start, end: end, err: err}
+}
+
+func (l *lexer) error(offset int, msg string) {
+	if l.err != nil {
+		l.err(offset, msg)
+	}
+}
+
+// peek returns the rune at byte offset i and its width in bytes, or -1 and
+// 0 past the end of the input. A byte that is not valid UTF-8 is returned
+// as utf8.RuneError, with a width of 1.
+func (l *lexer) peek(i int) (r rune, width int) {
+	if i >= l.end {
+		return -1, 0
+	}
+	if c := l.text[i]; c < utf8.RuneSelf {
+		return rune(c), 1
+	}
+	return utf8.DecodeRuneInString(l.text[i:l.end])
+}
+
+// at returns the rune at byte offset i, or -1 past the end of the input.
+func (l *lexer) at(i int) rune {
+	r, _ := l.peek(i)
+	return r
+}
+
+// next returns the next token. At the end of the input it returns a token
+// of kind tokEOF, again and again.
+func (l *lexer) next() token {
+	start := l.pos
+	kind := l.scan()
+	return token{kind: kind, start: start, end: l.pos, text: l.text[start:l.pos]}
+}
+
+// scan moves past the next token and returns its kind.
+func (l *lexer) scan() tokenKind {
+	i := l.pos
+	r, width := l.peek(i)
+	if r < 0 {
+		return tokEOF
+	}
+	if isSpace(r) {
+		for r, n := l.peek(i); r >= 0 && isSpace(r); r, n = l.peek(i) {
+			i += n
+		}
+		l.pos = i
+		return tokSpace
+	}
+
+	switch {
+	case isDigit(r), r == '.' && isDigit(l.at(i+1)):
+		return l.number()
+	case isIdentStart(r):
+		return l.name()
+	}
+
+	l.pos = i + width
+	two := func(next byte, kind, short tokenKind) tokenKind {
+		if l.at(l.pos) == rune(next) {
+			l.pos++
+			return kind
+		}
+		return short
+	}
+	switch r {
+	case '"', '\'':
+		return l.string(r)
+	case '`':
+		return l.escapedName()
+	case '/':
+		switch l.at(l.pos) {
+		case '/':
+			return l.lineComment()
+		case '*':
+			return l.blockComment()
+		}
+		return tokSlash
+	case ';':
+		return tokSemicolon
+	case ',':
+		return tokComma
+	case '.':
+		return two('.', tokDotDot, tokDot)
+	case ':':
+		return tokColon
+	case '|':
+		return tokPipe
+	case '$':
+		return tokDollar
+	case '(':
+		return tokLParen
+	case ')':
+		return tokRParen
+	case '[':
+		return tokLBracket
+	case ']':
+		return tokRBracket
+	case '{':
+		return tokLBrace
+	case '}':
+		return tokRBrace
+	case '=':
+		return tokEq
+	case '<':
+		if l.at(l.pos) == '>' {
+			l.pos++
+			return tokNeq
+		}
+		return two('=', tokLe, tokLt)
+	case '>':
+		return two('=', tokGe, tokGt)
+	case '+':
+		return two('=', tokPlusEq, tokPlus)
+	case '-':
+		return tokMinus
+	case '*':
+		return tokStar
+	case '%':
+		return tokPercent
+	case '^':
+		return tokCaret
+	case '\u27e8', '\u3008', '\ufe64', '\uff1c':
+		return tokLeftArrowHead
+	case '\u27e9', '\u3009', '\ufe65', '\uff1e':
+		return tokRightArrowHead
+	case '\u00ad', '\u2010', '\u2011', '\u2012', '\u2013', '\u2014', '\u2015',
+		'\u2212', '\ufe58', '\ufe63', '\uff0d':
+		return tokDash
+	}
+	if r == utf8.RuneError && width == 1 {
+		l.error(i, "invalid UTF-8 encoding")
+	} else {
+		l.error(i, "invalid character "+quoteRune(l.text[i:]))
+	}
+	return tokIllegal
+}
+
+// number scans a numeric literal. Of the grammar's rules for numbers, the
+// one that matches the longest text wins, as it would in the ANTLR lexer:
+// "1.5" is a single number, but "1..5" is 1 followed by "..".
+func (l *lexer) number() tokenKind {
+	start := l.pos
+	digits := func(i int, ok func(rune) bool) int {
+		for ok(l.at(i)) {
+			i++
+		}
+		return i
+	}
+
+	end, kind := start, tokDecimal
+	longest := func(e int, k tokenKind) {
+		if e > end {
+			end, kind = e, k
+		}
+	}
+
+	// HexInteger: '0x' HexDigit+
+	if l.at(start) == '0' && l.at(start+1) == 'x' {
+		if e := digits(start+2, isHexDigit); e > start+2 {
+			longest(e, tokHex)
+		}
+	}
+	// DecimalInteger: '0' | [1-9] Digit*
+	switch r := l.at(start); {
+	case r == '0':
+		longest(start+1, tokDecimal)
+	case isDigit(r):
+		longest(digits(start, isDigit), tokDecimal)
+	}
+	// OctalInteger: '0' OctDigit+
+	if l.at(start) == '0' {
+		if e := digits(start+1, isOctDigit); e > start+1 {
+			longest(e, tokOctal)
+		}
+	}
+	// RegularDecimalReal: Digit* '.' Digit+
+	mantissa := digits(start, isDigit)
+	if l.at(mantissa) == '.' {
+		if e := digits(mantissa+1, isDigit); e > mantissa+1 {
+			longest(e, tokFloat)
+			mantissa = e
+		}
+	}
+	// ExponentDecimalReal: (Digit+ | Digit+ '.' Digit+ | '.' Digit+) [eE] '-'? Digit+
+	if mantissa > start && (l.at(mantissa) == 'e' || l.at(mantissa) == 'E') {
+		i := mantissa + 1
+		if l.at(i) == '-' {
+			i++
+		}
+		if e := digits(i, isDigit); e > i {
+			longest(e, tokFloat)
+		}
+	}
+
+	l.pos = end
+	return kind
+}
+
+// name scans an unescaped name or a keyword.
+func (l *lexer) name() tokenKind {
+	start := l.pos
+	_, n := l.peek(start)
+	i := start + n
+	for r, n := l.peek(i); r >= 0 && isIdentPart(r); r, n = l.peek(i) {
+		i += n
+	}
+	l.pos = i
+	if kw, ok := keywords[strings.ToUpper(l.text[start:i])]; ok {
+		return kw
+	}
+	return tokName
+}
+
+// escapedName scans a name in backticks. A backtick inside the name is
+// written as two.
+func (l *lexer) escapedName() tokenKind {
+	start := l.pos - 1
+	for {
+		i := strings.IndexByte(l.text[l.pos:l.end], '`')
+		if i < 0 {
+			l.pos = l.end
+			l.error(start, "unterminated name")
+			return tokEscapedName
+		}
+		l.pos += i + 1
+		if l.at(l.pos) != '`' {
+			return tokEscapedName
+		}
+		l.pos++
+	}
+}
+
+// string scans a string literal, whose opening quote has been read.
+func (l *lexer) string(quote rune) tokenKind {
+	start := l.pos - 1
+	for {
+		r, width := l.peek(l.pos)
+		switch r {
+		case -1:
+			l.error(start, "unterminated string")
+			return tokString
+		case quote:
+			l.pos++
+			return tokString
+		case '\\':
+			l.escape()
+			continue
+		}
+		l.pos += width
+	}
+}
+
+// escape scans an escape sequence in a string.
+func (l *lexer) escape() {
+	start := l.pos
+	l.pos++
+	switch l.at(l.pos) {
+	case '\\', '\'', '"', 'b', 'B', 'f', 'F', 'n', 'N', 'r', 'R', 't', 'T':
+		l.pos++
+		return
+	case 'u', 'U':
+		l.pos++
+		n := 0
+		for n < 8 && isHexDigit(l.at(l.pos+n)) {
+			n++
+		}
+		switch {
+		case n == 8:
+			l.pos += 8
+			return
+		case n >= 4:
+			l.pos += 4
+			return
+		}
+	}
+	l.error(start, "invalid escape sequence in string")
+}
+
+func (l *lexer) lineComment() tokenKind {
+	i := strings.IndexAny(l.text[l.pos:l.end], "\r\n")
+	if i < 0 {
+		l.pos = l.end
+	} else {
+		l.pos += i
+	}
+	return tokLineComment
+}
+
+// blockComment scans a "/* */" comment. As in the grammar, a '*' inside the
+// comment takes the character after it along, so "**/" does not end one.
+func (l *lexer) blockComment() tokenKind {
+	start := l.pos - 1
+	l.pos++
+	for l.pos < l.end {
+		if l.text[l.pos] != '*' {
+			l.pos++
+			continue
+		}
+		if l.at(l.pos+1) == '/' {
+			l.pos += 2
+			return tokBlockComment
+		}
+		if l.pos+1 < l.end {
+			_, n := utf8.DecodeRuneInString(l.text[l.pos+1 : l.end])
+			l.pos += n
+		}
+		l.pos++
+	}
+	l.pos = l.end
+	l.error(start, "unterminated comment")
+	return tokBlockComment
+}
+
+func isSpace(r rune) bool {
+	switch r {
+	case ' ', '\t', '\n', '\v', '\f', '\r', '\u001c', '\u001d', '\u001e', '\u001f',
+		'\u00a0', '\u1680', '\u180e', '\u2000', '\u2001', '\u2002', '\u2003',
+		'\u2004', '\u2005', '\u2006', '\u2007', '\u2008', '\u2009', '\u200a',
+		'\u2028', '\u2029', '\u202f', '\u205f', '\u3000':
+		return true
+	}
+	return false
+}
+
+func isDigit(r rune) bool { return '0' <= r && r <= '9' }
+
+func isOctDigit(r rune) bool { return '0' <= r && r <= '7' }
+
+func isHexDigit(r rune) bool {
+	return isDigit(r) || 'a' <= r && r <= 'f' || 'A' <= r && r <= 'F'
+}
+
+// isIdentStart and isIdentPart follow the grammar, which is based on the
+// Unicode identifier syntax: a name starts with an ID_Start character or a
+// connector such as '_', and goes on with ID_Continue characters and
+// currency symbols.
+func isIdentStart(r rune) bool {
+	if r < utf8.RuneSelf {
+		return 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || r == '_'
+	}
+	return isIDStart(r) || unicode.Is(unicode.Pc, r)
+}
+
+func isIdentPart(r rune) bool {
+	if r < utf8.RuneSelf {
+		return 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || r == '_' || r == '$' || isDigit(r)
+	}
+	return isIDStart(r) || unicode.In(r, unicode.Mn, unicode.Mc, unicode.Nd, unicode.Pc, unicode.Other_ID_Continue, unicode.Sc)
+}
+
+func isIDStart(r rune) bool {
+	return unicode.In(r, unicode.L, unicode.Nl, unicode.Other_ID_Start) &&
+		!unicode.In(r, unicode.Pattern_Syntax, unicode.Pattern_White_Space)
+}
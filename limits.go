@@ -0,0 +1,132 @@
+package cypher
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/a-poor/cypher/ast"
+)
+
+// Options limits the resources ParseContext may spend on a query. A zero
+// limit means no limit, apart from MaxDepth.
+type Options struct {
+	// MaxBytes is the longest query, in bytes, that will be parsed.
+	MaxBytes int
+
+	// MaxTokens is the most tokens a query may have, not counting
+	// whitespace and comments.
+	MaxTokens int
+
+	// MaxDepth is how deeply expressions and patterns may be nested, as
+	// counted by parentheses, brackets, braces, CASE expressions, NOT and
+	// signs. The parser recurses once for each level, so deep nesting
+	// costs both time and stack. Zero means DefaultMaxDepth.
+	MaxDepth int
+}
+
+// SizeLimitError is returned by ParseContext for a query longer than
+// Options.MaxBytes.
+type SizeLimitError struct {
+	Size  int // length of the query in bytes
+	Limit int
+}
+
+func (e *SizeLimitError) Error() string {
+	return fmt.Sprintf("query is %d bytes long, more than the limit of %d", e.Size, e.Limit)
+}
+
+// TokenLimitError is returned by ParseContext for a query with more than
+// Options.MaxTokens tokens.
+type TokenLimitError struct {
+	Limit int
+}
+
+func (e *TokenLimitError) Error() string {
+	return fmt.Sprintf("query has more than %d tokens", e.Limit)
+}
+
+// DepthLimitError is returned by ParseContext for a query nested more
+// deeply than Options.MaxDepth, or DefaultMaxDepth.
+type DepthLimitError struct {
+	Limit int
+	Pos   ast.Position // position of the token that went over the limit
+}
+
+func (e *DepthLimitError) Error() string {
+	return fmt.Sprintf("%s: query is nested more than %d levels deep", e.Pos, e.Limit)
+}
+
+// ParseContext is like Parse, but gives up when ctx is done, and refuses
+// queries that go over the limits in opts, which may be nil.
+//
+// If the query goes over a limit, the error is a *SizeLimitError,
+// *TokenLimitError or *DepthLimitError, and no query is returned. The size
+// and number of tokens are checked before the query is parsed, and the
+// depth while it is. If ctx is done first, the error is ctx.Err().
+// Otherwise the results are those of Parse.
+func ParseContext(ctx context.Context, query string, opts *Options) (q *ast.Query, err error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	if opts.MaxBytes > 0 && len(query) > opts.MaxBytes {
+		return nil, &SizeLimitError{Size: len(query), Limit: opts.MaxBytes}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	src := newSource(query)
+	if err := checkLimits(ctx, src, opts); err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			switch r := r.(type) {
+			case cancelled:
+				q, err = nil, r.err
+			case tooDeep:
+				q, err = nil, r.err
+			default:
+				panic(r)
+			}
+		}
+	}()
+	p := newParser(ctx, src)
+	if opts.MaxDepth > 0 {
+		p.maxDepth = opts.MaxDepth
+	}
+	q = p.query()
+	return q, p.errors().Err()
+}
+
+// checkLimits runs the lexer over the input of src, counting its tokens,
+// so that a query with too many is refused before the parser keeps them.
+func checkLimits(ctx context.Context, src *source, opts *Options) error {
+	if opts.MaxTokens <= 0 {
+		return nil
+	}
+	l := newLexer(src.text, src.base, src.end(), nil)
+
+	count := 0
+	for i := 1; ; i++ {
+		if i%cancelCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+
+		tok := l.next()
+		switch tok.kind {
+		case tokEOF:
+			return nil
+		case tokSpace, tokLineComment, tokBlockComment, tokIllegal:
+			continue
+		}
+
+		count++
+		if opts.MaxTokens > 0 && count > opts.MaxTokens {
+			return &TokenLimitError{Limit: opts.MaxTokens}
+		}
+	}
+}
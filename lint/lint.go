@@ -0,0 +1,317 @@
+// Package lint finds likely mistakes and slow patterns in Cypher queries
+// that are valid, such as variables that are never used or MATCH clauses
+// whose patterns make a cartesian product.
+//
+// Each check is a Rule, with a name that can be used to pick the rules to
+// run. Run runs rules over a query, using what sema.Check finds out about
+// it.
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/a-poor/cypher/ast"
+	"github.com/a-poor/cypher/sema"
+)
+
+// Rule is a check of a query.
+type Rule struct {
+	Name string // such as "unused-variable"
+	Doc  string // what the rule reports, and why
+
+	// Check reports what the rule finds in the query of a pass.
+	Check func(*Pass)
+}
+
+// Pass is a run of a rule over a query.
+type Pass struct {
+	Query *ast.Query
+	Info  *sema.Info
+
+	rule  *Rule
+	diags []Diagnostic
+}
+
+// Report reports something the rule found at a node.
+func (p *Pass) Report(n ast.Node, format string, args ...interface{}) {
+	p.diags = append(p.diags, Diagnostic{
+		Rule: p.rule.Name,
+		Span: n.Span(),
+		Msg:  fmt.Sprintf(format, args...),
+	})
+}
+
+// Diagnostic is something a rule found in a query.
+type Diagnostic struct {
+	Rule string
+	Span ast.Span
+	Msg  string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d:%d: %s (%s)", d.Span.Start.Line, d.Span.Start.Column, d.Msg, d.Rule)
+}
+
+// Rules holds the rules of the package, sorted by name.
+var Rules = []*Rule{
+	AllNodesScan,
+	CartesianProduct,
+	NullComparison,
+	UnboundedPath,
+	UnusedVariable,
+}
+
+// Lookup returns the rule with the given name, or nil if there is none.
+func Lookup(name string) *Rule {
+	for _, r := range Rules {
+		if r.Name == name {
+			return r
+		}
+	}
+	return nil
+}
+
+// Run runs rules over a query, returning what they find sorted by
+// position. Info is what sema.Check finds out about the query; if it is
+// nil, Run calls Check itself.
+func Run(q *ast.Query, info *sema.Info, rules []*Rule) []Diagnostic {
+	if info == nil {
+		info = sema.Check(q)
+	}
+	var diags []Diagnostic
+	for _, r := range rules {
+		p := &Pass{Query: q, Info: info, rule: r}
+		r.Check(p)
+		diags = append(diags, p.diags...)
+	}
+	sort.SliceStable(diags, func(i, j int) bool {
+		return diags[i].Span.Start.Offset < diags[j].Span.Start.Offset
+	})
+	return diags
+}
+
+// UnusedVariable reports variables that are declared but never used.
+var UnusedVariable = &Rule{
+	Name: "unused-variable",
+	Doc: "Reports variables declared by MATCH, UNWIND, WITH or YIELD that are never used. " +
+		"Leave the variable out, or start its name with an underscore if it is there to document the query.",
+	Check: func(p *Pass) {
+		stars := starProjections(p.Query)
+		for _, sym := range p.Info.Symbols {
+			if len(sym.Refs) > 0 || strings.HasPrefix(sym.Name, "_") {
+				continue
+			}
+			switch sym.Clause.(type) {
+			case *ast.Match, *ast.Unwind, *ast.With, *ast.InQueryCall:
+			default:
+				continue
+			}
+			if projectedByStar(sym, stars) {
+				continue
+			}
+			p.Report(sym.Decl, "variable `%s` is declared but never used", sym.Name)
+		}
+	},
+}
+
+// starProjections returns the offsets of the WITH * and RETURN * clauses
+// of a query.
+func starProjections(q *ast.Query) []int {
+	var offsets []int
+	ast.Inspect(q, func(n ast.Node) bool {
+		if proj, ok := n.(*ast.Projection); ok && proj.Star {
+			offsets = append(offsets, proj.Span().Start.Offset)
+		}
+		return true
+	})
+	return offsets
+}
+
+// projectedByStar reports whether a WITH * or RETURN * after the
+// declaration of a symbol may use it.
+func projectedByStar(sym *sema.Symbol, stars []int) bool {
+	for _, off := range stars {
+		if off > sym.Decl.Span().Start.Offset {
+			return true
+		}
+	}
+	return false
+}
+
+// CartesianProduct reports MATCH clauses whose paths are not connected.
+var CartesianProduct = &Rule{
+	Name: "cartesian-product",
+	Doc: "Reports MATCH clauses with paths that share no variable, with each other or with what came before. " +
+		"Every match of one is combined with every match of the other, which is rarely meant and slow.",
+	Check: func(p *Pass) {
+		forEachMatch(p.Query, func(m *ast.Match) {
+			paths := m.Pattern.Paths
+			if len(paths) < 2 {
+				return
+			}
+			// Join the paths that share variables into components, and
+			// find the components anchored by variables bound before.
+			parent := make([]int, len(paths))
+			for i := range parent {
+				parent[i] = i
+			}
+			var find func(i int) int
+			find = func(i int) int {
+				for parent[i] != i {
+					i = parent[i]
+				}
+				return i
+			}
+			first := map[string]int{} // path each name is first in
+			anchored := map[int]bool{}
+			for i, path := range paths {
+				for _, v := range pathVariables(path) {
+					if j, ok := first[v.Name]; ok {
+						parent[find(i)] = find(j)
+					} else {
+						first[v.Name] = i
+					}
+					if bound(p.Info, v, m) {
+						anchored[i] = true
+					}
+				}
+			}
+			// Report the first path of each component not anchored, but
+			// the first component, if none is anchored, is what the others
+			// are combined with.
+			reported := map[int]bool{}
+			for i := range paths {
+				if anchored[i] {
+					anchored[find(i)] = true
+				}
+			}
+			for i, path := range paths {
+				root := find(i)
+				if reported[root] || anchored[root] || root == find(0) && len(anchored) == 0 {
+					reported[root] = true
+					continue
+				}
+				reported[root] = true
+				p.Report(path, "path is not connected to the rest of the pattern, which makes a cartesian product")
+			}
+		})
+	},
+}
+
+// UnboundedPath reports variable-length relationships with no upper bound.
+var UnboundedPath = &Rule{
+	Name: "unbounded-path",
+	Doc: "Reports variable-length relationships in MATCH with no maximum length, as in [*] or [*2..]. " +
+		"On a large graph they can follow a huge number of paths; give a maximum, as in [*..5].",
+	Check: func(p *Pass) {
+		forEachMatch(p.Query, func(m *ast.Match) {
+			for _, path := range m.Pattern.Paths {
+				if path.Shortest != ast.NotShortest {
+					continue
+				}
+				for _, r := range path.Relationships {
+					if r.VarLength && r.MaxHops == nil {
+						p.Report(r, "variable-length relationship has no maximum length")
+					}
+				}
+			}
+		})
+	},
+}
+
+// NullComparison reports comparisons with null using = or <>.
+var NullComparison = &Rule{
+	Name: "null-comparison",
+	Doc: "Reports comparisons with null using = or <>, which are null rather than true or false whatever " +
+		"the other side is. Use IS NULL or IS NOT NULL instead.",
+	Check: func(p *Pass) {
+		ast.Inspect(p.Query, func(n ast.Node) bool {
+			x, ok := n.(*ast.BinaryExpr)
+			if !ok || x.Op != ast.OpEq && x.Op != ast.OpNeq {
+				return true
+			}
+			if isNull(x.X) || isNull(x.Y) {
+				instead := "IS NULL"
+				if x.Op == ast.OpNeq {
+					instead = "IS NOT NULL"
+				}
+				p.Report(x, "comparison with null using %s is always null; use %s", x.Op, instead)
+			}
+			return true
+		})
+	},
+}
+
+func isNull(x ast.Expr) bool {
+	_, ok := x.(*ast.NullLiteral)
+	return ok
+}
+
+// AllNodesScan reports MATCH paths that can only be found by looking at
+// every node.
+var AllNodesScan = &Rule{
+	Name: "all-nodes-scan",
+	Doc: "Reports paths in MATCH with no label on any node, no type on any relationship, and no node bound " +
+		"before, which can only be found by scanning all the nodes of the graph.",
+	Check: func(p *Pass) {
+		forEachMatch(p.Query, func(m *ast.Match) {
+			for _, path := range m.Pattern.Paths {
+				if !scansAllNodes(p.Info, path, m) {
+					continue
+				}
+				p.Report(path, "path has no label, relationship type or bound node, so every node must be scanned")
+			}
+		})
+	},
+}
+
+func scansAllNodes(info *sema.Info, path *ast.PathPattern, m *ast.Match) bool {
+	for _, n := range path.Nodes {
+		if len(n.Labels) > 0 || n.Variable != nil && bound(info, n.Variable, m) {
+			return false
+		}
+	}
+	for _, r := range path.Relationships {
+		if len(r.Types) > 0 || r.Variable != nil && bound(info, r.Variable, m) {
+			return false
+		}
+	}
+	return true
+}
+
+// forEachMatch calls f for each MATCH clause of a query, including those
+// of its subqueries.
+func forEachMatch(q *ast.Query, f func(*ast.Match)) {
+	ast.Inspect(q, func(n ast.Node) bool {
+		if m, ok := n.(*ast.Match); ok && m.Pattern != nil {
+			f(m)
+		}
+		return true
+	})
+}
+
+// pathVariables returns the variables of the nodes and relationships of a
+// path.
+func pathVariables(path *ast.PathPattern) []*ast.Variable {
+	var vars []*ast.Variable
+	for _, n := range path.Nodes {
+		if n.Variable != nil {
+			vars = append(vars, n.Variable)
+		}
+	}
+	for _, r := range path.Relationships {
+		if r.Variable != nil {
+			vars = append(vars, r.Variable)
+		}
+	}
+	return vars
+}
+
+// bound reports whether v names a symbol declared before the MATCH clause
+// m.
+func bound(info *sema.Info, v *ast.Variable, m *ast.Match) bool {
+	sym := info.Uses[v]
+	return sym != nil && sym.Clause != m && sym.Decl.Span().Start.Offset < m.Span().Start.Offset
+}
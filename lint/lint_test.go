@@ -0,0 +1,86 @@
+package lint_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/a-poor/cypher"
+	"github.com/a-poor/cypher/lint"
+)
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		rule  string
+		query string
+		want  []string
+	}{
+		{"unused-variable", "MATCH (a)-[r]->(b) RETURN a", []string{
+			"1:12: variable `r` is declared but never used (unused-variable)",
+			"1:17: variable `b` is declared but never used (unused-variable)",
+		}},
+		{"unused-variable", "MATCH (a)-[_r]->(b) WHERE b.x > 1 RETURN a", nil},
+		{"unused-variable", "UNWIND [1, 2] AS x WITH 1 AS y RETURN 2", []string{
+			"1:18: variable `x` is declared but never used (unused-variable)",
+			"1:30: variable `y` is declared but never used (unused-variable)",
+		}},
+		{"unused-variable", "MATCH (a)-->(b) RETURN *", nil},
+		{"unused-variable", "CREATE (a:Person) RETURN 1", nil},
+
+		{"cartesian-product", "MATCH (a:A), (b:B) RETURN a, b", []string{
+			"1:14: path is not connected to the rest of the pattern, which makes a cartesian product (cartesian-product)",
+		}},
+		{"cartesian-product", "MATCH (a:A), (b:B), (a)-->(b) RETURN a, b", nil},
+		{"cartesian-product", "MATCH (a:A) WITH a MATCH (a)-->(b), (c:C) RETURN b, c", []string{
+			"1:37: path is not connected to the rest of the pattern, which makes a cartesian product (cartesian-product)",
+		}},
+		{"cartesian-product", "MATCH (a:A), (b:B) WITH a, b MATCH (a)-->(x), (b)-->(y) RETURN x, y", []string{
+			"1:14: path is not connected to the rest of the pattern, which makes a cartesian product (cartesian-product)",
+		}},
+
+		{"unbounded-path", "MATCH (a)-[*]->(b), (a)-[*2..]->(c), (a)-[*..3]->(d) RETURN b, c, d", []string{
+			"1:10: variable-length relationship has no maximum length (unbounded-path)",
+			"1:24: variable-length relationship has no maximum length (unbounded-path)",
+		}},
+
+		{"null-comparison", "MATCH (n) WHERE n.x = null OR null <> n.y RETURN n", []string{
+			"1:17: comparison with null using = is always null; use IS NULL (null-comparison)",
+			"1:31: comparison with null using <> is always null; use IS NOT NULL (null-comparison)",
+		}},
+		{"null-comparison", "MATCH (n) WHERE n.x IS NULL RETURN n", nil},
+
+		{"all-nodes-scan", "MATCH (n) RETURN n", []string{
+			"1:7: path has no label, relationship type or bound node, so every node must be scanned (all-nodes-scan)",
+		}},
+		{"all-nodes-scan", "MATCH (n:Person), (a)-[:KNOWS]->(b) RETURN n, a, b", nil},
+		{"all-nodes-scan", "MATCH (n:Person) MATCH (n)-->(m) RETURN m", nil},
+	}
+	for _, tt := range tests {
+		rule := lint.Lookup(tt.rule)
+		if rule == nil {
+			t.Fatalf("no rule %q", tt.rule)
+		}
+		q, err := cypher.Parse(tt.query)
+		if err != nil {
+			t.Errorf("%q: %v", tt.query, err)
+			continue
+		}
+		var got []string
+		for _, d := range lint.Run(q, nil, []*lint.Rule{rule}) {
+			got = append(got, d.String())
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s on %q =\n%q\nwant\n%q", tt.rule, tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestRulesSorted(t *testing.T) {
+	for i, r := range lint.Rules {
+		if i > 0 && lint.Rules[i-1].Name >= r.Name {
+			t.Errorf("rule %s comes after %s", r.Name, lint.Rules[i-1].Name)
+		}
+		if r.Doc == "" || r.Check == nil {
+			t.Errorf("rule %s has no Doc or Check", r.Name)
+		}
+	}
+}
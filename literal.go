@@ -0,0 +1,131 @@
+package cypher
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// This file decodes the text of literal tokens into the values they stand
+// for. The lexer has already reported any text that is not well formed, so
+// what is left to check is whether the value can be represented.
+
+// literalError is a problem with a literal, at a byte offset in its text.
+type literalError struct {
+	offset int
+	msg    string
+}
+
+// parseInteger returns the value of a DecimalInteger, HexInteger or
+// OctalInteger. If minus is set, the integer is negated, which lets it be
+// one larger than math.MaxInt64; its value is then returned as it is
+// before the negation, which for math.MinInt64 is math.MinInt64 itself.
+func parseInteger(text string, minus bool) (int64, *literalError) {
+	// Hexadecimal and octal integers are written with the same prefixes
+	// as in Go.
+	n, err := strconv.ParseUint(text, 0, 64)
+	switch {
+	case err == nil && n <= math.MaxInt64:
+		return int64(n), nil
+	case err == nil && minus && n == -math.MinInt64:
+		return math.MinInt64, nil
+	}
+	return 0, &literalError{0, "integer out of range"}
+}
+
+// parseFloat returns the value of a RegularDecimalReal or
+// ExponentDecimalReal.
+func parseFloat(text string) (float64, *literalError) {
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return 0, &literalError{0, "number out of range"}
+	}
+	return f, nil
+}
+
+// unquote returns the value of a StringLiteral: the text between the
+// quotes, with its escape sequences replaced by the characters they stand
+// for. Escaped UTF-16 surrogate pairs, as in "\uD83D\uDE00", are combined
+// into a single character, as they are in Java.
+//
+// Invalid escape sequences and unterminated strings, which the lexer
+// reports, are decoded as best they can be: the backslash of an invalid
+// escape sequence stands for itself.
+func unquote(text string) (string, *literalError) {
+	body := text[1:]
+	if len(body) > 0 && body[len(body)-1] == text[0] {
+		body = body[:len(body)-1]
+	}
+	if !strings.ContainsRune(body, '\\') {
+		return body, nil
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(body))
+	for i := 0; i < len(body); {
+		if body[i] != '\\' {
+			sb.WriteByte(body[i])
+			i++
+			continue
+		}
+		start := i
+		r, n := escape(body[i:])
+		if n == 0 {
+			sb.WriteByte('\\')
+			i++
+			continue
+		}
+		i += n
+		if utf16.IsSurrogate(r) {
+			// Only the first half of a pair may start it.
+			lo, m := escape(body[i:])
+			if r >= 0xdc00 || m == 0 || utf16.DecodeRune(r, lo) == utf8.RuneError {
+				return "", &literalError{1 + start, "invalid Unicode code point in string"}
+			}
+			r = utf16.DecodeRune(r, lo)
+			i += m
+		}
+		if !utf8.ValidRune(r) {
+			return "", &literalError{1 + start, "invalid Unicode code point in string"}
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String(), nil
+}
+
+// escape decodes the escape sequence at the start of s, returning the
+// character it stands for and its length. The length is 0 if s does not
+// start with an escape sequence.
+func escape(s string) (rune, int) {
+	if len(s) < 2 || s[0] != '\\' {
+		return 0, 0
+	}
+	switch s[1] {
+	case '\\', '\'', '"':
+		return rune(s[1]), 2
+	case 'b', 'B':
+		return '\b', 2
+	case 'f', 'F':
+		return '\f', 2
+	case 'n', 'N':
+		return '\n', 2
+	case 'r', 'R':
+		return '\r', 2
+	case 't', 'T':
+		return '\t', 2
+	case 'u', 'U':
+		// As in the lexer, eight hex digits are taken if there are eight,
+		// and four otherwise.
+		for _, n := range []int{8, 4} {
+			if len(s) < 2+n {
+				continue
+			}
+			if v, err := strconv.ParseUint(s[2:2+n], 16, 32); err == nil {
+				return rune(v), 2 + n
+			}
+		}
+	}
+	return 0, 0
+}
@@ -0,0 +1,98 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/a-poor/cypher/ast"
+	"github.com/a-poor/cypher/complete"
+	"github.com/a-poor/cypher/cst"
+	"github.com/a-poor/cypher/sema"
+)
+
+func (s *Server) completion(params json.RawMessage) (interface{}, error) {
+	var p TextDocumentPositionParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	d, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	list := &CompletionList{Items: []CompletionItem{}}
+	offset := d.offset(p.Position)
+	start, end, ok := d.statementAt(offset)
+	if !ok {
+		return list, nil
+	}
+	for i, c := range complete.At(d.text[start:end], offset-start, s.opts.Schema) {
+		item := CompletionItem{
+			Label:    c.Text,
+			Kind:     completionKinds[c.Kind],
+			Detail:   c.Detail,
+			SortText: fmt.Sprintf("%04d", i),
+			TextEdit: &TextEdit{Range: d.rangeOf(start+c.Start, offset), NewText: c.Text},
+		}
+		switch c.Kind {
+		case complete.Function:
+			if f := sema.LookupFunction(c.Text); f != nil {
+				item.Documentation = &MarkupContent{Kind: "markdown", Value: f.Doc}
+			}
+		case complete.Keyword:
+			if doc := keywordDocs[strings.ToUpper(c.Text)]; doc != "" {
+				item.Documentation = &MarkupContent{Kind: "markdown", Value: doc}
+			}
+		case complete.Parameter:
+			item.Label = "$" + c.Text
+		default:
+			if item.Detail == "" {
+				item.Detail = c.Kind.String()
+			}
+		}
+		list.Items = append(list.Items, item)
+	}
+	return list, nil
+}
+
+var completionKinds = map[complete.Kind]int{
+	complete.Keyword:          CompletionItemKindKeyword,
+	complete.Variable:         CompletionItemKindVariable,
+	complete.Function:         CompletionItemKindFunction,
+	complete.Label:            CompletionItemKindClass,
+	complete.RelationshipType: CompletionItemKindClass,
+	complete.PropertyKey:      CompletionItemKindProperty,
+	complete.Parameter:        CompletionItemKindConstant,
+}
+
+// statementAt returns the byte offsets of the statement of the document
+// at an offset, which runs from the semicolon or command before it to the
+// one after it, and whether there is one. There is none in a command.
+func (d *document) statementAt(offset int) (start, end int, ok bool) {
+	start, end = 0, len(d.text)
+	for _, c := range d.tree.Root.Children {
+		var span ast.Span
+		switch c := c.(type) {
+		case *cst.Token:
+			if c.Text != ";" {
+				continue
+			}
+			span = c.Loc
+		case *cst.Node:
+			if _, ok := c.AST.(*ast.Query); ok {
+				continue
+			}
+			span = c.Span()
+			if span.Start.Offset < offset && offset <= span.End.Offset {
+				return 0, 0, false
+			}
+		}
+		switch {
+		case span.End.Offset <= offset:
+			start = span.End.Offset
+		case span.Start.Offset >= offset:
+			return start, span.Start.Offset, true
+		}
+	}
+	return start, end, true
+}
@@ -0,0 +1,29 @@
+package lsp
+
+// keywordDocs documents the clauses and other keywords of Cypher, by the
+// keyword in upper case, for hover.
+var keywordDocs = map[string]string{
+	"MATCH":    "**MATCH** *pattern* [WHERE *condition*]\n\nFinds the parts of the graph that match a pattern, binding their nodes and relationships to the variables of the pattern.",
+	"OPTIONAL": "**OPTIONAL MATCH** *pattern* [WHERE *condition*]\n\nLike MATCH, but where the pattern has no match, binds its new variables to null instead of dropping the row.",
+	"WHERE":    "**WHERE** *condition*\n\nKeeps only the rows, or the matches of a pattern, for which the condition is true.",
+	"RETURN":   "**RETURN** [DISTINCT] *items* [ORDER BY ...] [SKIP *n*] [LIMIT *n*]\n\nEnds a query, and says what it returns: a column for each item.",
+	"WITH":     "**WITH** [DISTINCT] *items* [ORDER BY ...] [SKIP *n*] [LIMIT *n*] [WHERE *condition*]\n\nPasses the items on to the rest of the query, as RETURN would return them. Only the variables it passes on stay in scope.",
+	"UNWIND":   "**UNWIND** *list* AS *variable*\n\nTurns a list into rows, with one element bound to the variable in each.",
+	"CREATE":   "**CREATE** *pattern*\n\nCreates the nodes and relationships of a pattern. Every relationship needs a type and a direction.",
+	"MERGE":    "**MERGE** *pattern* [ON CREATE SET ...] [ON MATCH SET ...]\n\nMatches a pattern, or creates it as a whole if it has no match.",
+	"ON":       "**ON CREATE SET** ... / **ON MATCH SET** ...\n\nSets properties or labels after MERGE, only when it creates its pattern or only when it matches it.",
+	"SET":      "**SET** *items*\n\nSets properties and labels of nodes and relationships: `n.x = value`, `n = map`, `n += map` or `n:Label`.",
+	"REMOVE":   "**REMOVE** *items*\n\nRemoves properties and labels from nodes and relationships: `n.x` or `n:Label`.",
+	"DELETE":   "**DELETE** *expressions*\n\nDeletes nodes, relationships and paths. A node that still has relationships cannot be deleted, unless with DETACH DELETE.",
+	"DETACH":   "**DETACH DELETE** *expressions*\n\nDeletes nodes together with their relationships.",
+	"CALL":     "**CALL** *procedure*(*arguments*) [YIELD *fields*] [WHERE *condition*]\n\nCalls a procedure, binding the fields it yields to variables.",
+	"YIELD":    "**YIELD** *field* [AS *variable*], ...\n\nSays which fields of the results of a procedure to bind to variables.",
+	"UNION":    "*query* **UNION** [ALL] *query*\n\nCombines the results of queries that return the same columns. UNION removes duplicate rows, and UNION ALL keeps them.",
+	"ORDER":    "**ORDER BY** *expression* [ASC | DESC], ...\n\nSorts the rows of RETURN or WITH.",
+	"SKIP":     "**SKIP** *n*\n\nLeaves out the first n rows of RETURN or WITH.",
+	"LIMIT":    "**LIMIT** *n*\n\nKeeps at most n rows of RETURN or WITH.",
+	"DISTINCT": "**DISTINCT**\n\nLeaves out duplicates: of rows after RETURN or WITH, and of values in an aggregate function such as `count(DISTINCT x)`.",
+	"AS":       "*expression* **AS** *name*\n\nNames a column, or binds a variable.",
+	"EXISTS":   "**EXISTS** { *pattern* [WHERE ...] } or **EXISTS** { *query* }\n\nIs true if the pattern or subquery has a match.",
+	"CASE":     "**CASE** [*subject*] WHEN ... THEN ... [ELSE ...] **END**\n\nReturns the result of the first WHEN that matches, or of ELSE, or null.",
+}
@@ -0,0 +1,227 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/a-poor/cypher"
+	"github.com/a-poor/cypher/ast"
+	"github.com/a-poor/cypher/sema"
+)
+
+func (s *Server) hover(params json.RawMessage) (interface{}, error) {
+	var p TextDocumentPositionParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	d, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	l, ok := d.leafAt(d.offset(p.Position))
+	if !ok {
+		return nil, nil
+	}
+
+	var text string
+	switch d.kindAt(l.tok.Loc.Start.Offset) {
+	case cypher.TokenIdentifier:
+		if v, ok := l.parent.AST.(*ast.Variable); ok {
+			if sym := d.symbol(v); sym != nil {
+				text = fmt.Sprintf("```cypher\n(%s) %s\n```", sym.Kind, sym.Name)
+			}
+		}
+	case cypher.TokenFunction:
+		name := ""
+		switch n := l.parent.AST.(type) {
+		case *ast.FunctionCall:
+			name = n.Name
+		case *ast.CountStar:
+			name = "count"
+		}
+		if f := sema.LookupFunction(name); f != nil {
+			text = fmt.Sprintf("```cypher\n%s\n```\n\n%s", f.Signature, f.Doc)
+		}
+	case cypher.TokenKeyword:
+		text = keywordDocs[strings.ToUpper(l.tok.Text)]
+	}
+	if text == "" {
+		return nil, nil
+	}
+	r := d.spanRange(l.tok.Loc)
+	return &Hover{Contents: MarkupContent{Kind: "markdown", Value: text}, Range: &r}, nil
+}
+
+// symbol returns the symbol a variable names, or nil.
+func (d *document) symbol(v *ast.Variable) *sema.Symbol {
+	for _, info := range d.infos {
+		if sym := info.Uses[v]; sym != nil {
+			return sym
+		}
+	}
+	return nil
+}
+
+// symbolAt returns the symbol of the variable at a position, or nil.
+func (d *document) symbolAt(pos Position) *sema.Symbol {
+	l, ok := d.leafAt(d.offset(pos))
+	if !ok {
+		return nil
+	}
+	v, ok := l.parent.AST.(*ast.Variable)
+	if !ok {
+		return nil
+	}
+	return d.symbol(v)
+}
+
+// occurrences returns the variables of a symbol: its declaration, if decl
+// is set, and its uses.
+func occurrences(sym *sema.Symbol, decl bool) []*ast.Variable {
+	var vs []*ast.Variable
+	if decl {
+		vs = append(vs, sym.Decl)
+	}
+	return append(vs, sym.Refs...)
+}
+
+func (s *Server) definition(params json.RawMessage) (interface{}, error) {
+	var p TextDocumentPositionParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	d, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	sym := d.symbolAt(p.Position)
+	if sym == nil {
+		return nil, nil
+	}
+	return &Location{URI: d.uri, Range: d.spanRange(sym.Decl.Span())}, nil
+}
+
+func (s *Server) references(params json.RawMessage) (interface{}, error) {
+	var p ReferenceParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	d, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	locs := []Location{}
+	if sym := d.symbolAt(p.Position); sym != nil {
+		for _, v := range occurrences(sym, p.Context.IncludeDeclaration) {
+			locs = append(locs, Location{URI: d.uri, Range: d.spanRange(v.Span())})
+		}
+	}
+	return locs, nil
+}
+
+func (s *Server) rename(params json.RawMessage) (interface{}, error) {
+	var p RenameParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	d, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	sym := d.symbolAt(p.Position)
+	if sym == nil {
+		return nil, &Error{Code: CodeRequestFailed, Message: "no variable to rename here"}
+	}
+	if p.NewName == "" {
+		return nil, &Error{Code: CodeInvalidParams, Message: "the new name is empty"}
+	}
+	text := variableText(p.NewName)
+	var edits []TextEdit
+	for _, v := range occurrences(sym, true) {
+		edits = append(edits, TextEdit{Range: d.spanRange(v.Span()), NewText: text})
+	}
+	return &WorkspaceEdit{Changes: map[string][]TextEdit{d.uri: edits}}, nil
+}
+
+// variableText returns a variable name as it must be written: as it is if
+// it parses as a name, and in backticks otherwise.
+func variableText(name string) string {
+	q, err := cypher.Parse("RETURN 0 AS " + name)
+	if err == nil {
+		if v := q.Clauses[0].(*ast.Return).Projection.Items[0].Alias; v.Name == name {
+			return name
+		}
+	}
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// nameComment matches a comment that names the query after it, as in
+// "// name: find_friends".
+var nameComment = regexp.MustCompile(`^\s*//\s*name:\s*(\S.*?)\s*$`)
+
+func (s *Server) documentSymbol(params json.RawMessage) (interface{}, error) {
+	var p DocumentSymbolParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	d, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	syms := []DocumentSymbol{}
+	for _, q := range d.queries() {
+		span := q.Span()
+		sym := DocumentSymbol{
+			Name:           snippet(q.Text(), 40),
+			Detail:         "query",
+			Kind:           SymbolKindFunction,
+			Range:          d.spanRange(span),
+			SelectionRange: d.spanRange(span),
+		}
+		if name, line := d.queryName(span.Start.Line - 1); name != "" {
+			sym.Name = name
+			sym.Range.Start = Position{Line: line}
+		}
+		for _, v := range d.infos[q].Symbols {
+			if v.Clause == nil {
+				continue
+			}
+			sym.Children = append(sym.Children, DocumentSymbol{
+				Name:           v.Name,
+				Detail:         v.Kind.String(),
+				Kind:           SymbolKindVariable,
+				Range:          d.spanRange(v.Decl.Span()),
+				SelectionRange: d.spanRange(v.Decl.Span()),
+			})
+		}
+		syms = append(syms, sym)
+	}
+	return syms, nil
+}
+
+// queryName returns the name given to the query starting on a line, by a
+// "// name: ..." comment in the lines of comments just above it, and the
+// line of that comment.
+func (d *document) queryName(line int) (string, int) {
+	for i := line - 1; i >= 0; i-- {
+		text := strings.TrimSpace(d.line(i))
+		if !strings.HasPrefix(text, "//") {
+			break
+		}
+		if m := nameComment.FindStringSubmatch(text); m != nil {
+			return m[1], i
+		}
+	}
+	return "", 0
+}
+
+// line returns the text of a line of the document, counted from zero.
+func (d *document) line(i int) string {
+	end := len(d.text)
+	if i+1 < len(d.lines) {
+		end = d.lines[i+1]
+	}
+	return strings.TrimRight(d.text[d.lines[i]:end], "\r\n")
+}
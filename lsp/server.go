@@ -0,0 +1,454 @@
+// Package lsp implements a Language Server Protocol server for Cypher.
+//
+// The server keeps the documents the client opens parsed, and publishes
+// their syntax errors, the semantic errors sema.Check finds and what the
+// lint rules find as diagnostics. It formats documents as
+// cypher.PrettyScript does, shows the documentation of built-in functions
+// and clauses on hover, completes words as package complete does, finds the
+// declarations and uses of variables and renames them, lists the queries of
+// a document as symbols, and classifies its tokens for semantic
+// highlighting.
+//
+// The server talks JSON-RPC over a reader and a writer, as an editor does
+// over the standard input and output of cmd/cypher-lsp:
+//
+//	s := lsp.NewServer(&lsp.Options{Rules: lint.Rules})
+//	err := s.Serve(os.Stdin, os.Stdout)
+package lsp
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/a-poor/cypher"
+	"github.com/a-poor/cypher/ast"
+	"github.com/a-poor/cypher/complete"
+	"github.com/a-poor/cypher/cst"
+	"github.com/a-poor/cypher/lint"
+	"github.com/a-poor/cypher/sema"
+)
+
+// Options configure a Server.
+type Options struct {
+	// Rules are the lint rules whose findings are published as warnings.
+	// Nil means all the rules of package lint, and an empty slice none.
+	Rules []*lint.Rule
+
+	// Style is the style documents are formatted in. Nil means the zero
+	// Style, indented by the tab size the client asks for.
+	Style *cypher.Style
+
+	// Schema has the labels, relationship types and property keys to
+	// suggest as completions, besides those the document uses. It may be
+	// nil.
+	Schema *complete.Schema
+}
+
+// Server is a language server for Cypher documents.
+type Server struct {
+	opts Options
+	conn *conn
+	docs map[string]*document // by URI
+
+	initialized bool // whether the initialize request has been answered
+	shutdown    bool // whether the shutdown request has been answered
+}
+
+// NewServer returns a server with the given options, which may be nil.
+func NewServer(opts *Options) *Server {
+	s := &Server{docs: map[string]*document{}}
+	if opts != nil {
+		s.opts = *opts
+	}
+	if s.opts.Rules == nil {
+		s.opts.Rules = lint.Rules
+	}
+	return s
+}
+
+// ErrNoShutdown is returned by Serve when the client sends the exit
+// notification without having sent the shutdown request.
+var ErrNoShutdown = errors.New("lsp: exit without shutdown")
+
+// Serve reads requests and notifications from r and writes responses and
+// notifications to w, until the client sends the exit notification or r
+// reaches its end. It returns nil after an orderly exit.
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	s.conn = newConn(r, w)
+	for {
+		msg, err := s.conn.read()
+		switch {
+		case err == io.EOF:
+			return nil
+		case err != nil:
+			var rpcErr *Error
+			if errors.As(err, &rpcErr) {
+				s.conn.reply(&nullID, nil, rpcErr)
+				continue
+			}
+			return err
+		}
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return ErrNoShutdown
+			}
+			return nil
+		}
+		if err := s.handle(msg); err != nil {
+			return err
+		}
+	}
+}
+
+// handler handles a request, returning its result, or a notification.
+type handler func(s *Server, params json.RawMessage) (interface{}, error)
+
+var handlers = map[string]handler{
+	"initialize":  (*Server).initialize,
+	"initialized": nil,
+	"shutdown": func(s *Server, _ json.RawMessage) (interface{}, error) {
+		s.shutdown = true
+		return nil, nil
+	},
+
+	"textDocument/didOpen":   (*Server).didOpen,
+	"textDocument/didChange": (*Server).didChange,
+	"textDocument/didClose":  (*Server).didClose,
+	"textDocument/didSave":   nil,
+
+	"textDocument/completion":          (*Server).completion,
+	"textDocument/formatting":          (*Server).formatting,
+	"textDocument/hover":               (*Server).hover,
+	"textDocument/definition":          (*Server).definition,
+	"textDocument/references":          (*Server).references,
+	"textDocument/rename":              (*Server).rename,
+	"textDocument/documentSymbol":      (*Server).documentSymbol,
+	"textDocument/semanticTokens/full": (*Server).semanticTokens,
+}
+
+// handle handles a message, returning an error only if the connection
+// fails.
+func (s *Server) handle(msg *message) error {
+	isRequest := msg.ID != nil
+	h, ok := handlers[msg.Method]
+	var result interface{}
+	var err error
+	switch {
+	case msg.Method == "":
+		// A response, to a request the server does not send.
+		return nil
+	case !ok:
+		if !isRequest {
+			// Notifications the server does not know, such as those
+			// starting with "$/", are to be ignored.
+			return nil
+		}
+		err = &Error{Code: CodeMethodNotFound, Message: "method not found: " + msg.Method}
+	case !s.initialized && msg.Method != "initialize":
+		err = &Error{Code: CodeServerNotInitialized, Message: "server not initialized"}
+	case s.shutdown:
+		err = &Error{Code: CodeInvalidRequest, Message: "server is shut down"}
+	case h != nil:
+		result, err = h(s, msg.Params)
+	}
+	if !isRequest {
+		return nil
+	}
+	return s.conn.reply(msg.ID, result, err)
+}
+
+// decode decodes the parameters of a request.
+func decode(params json.RawMessage, v interface{}) error {
+	if err := json.Unmarshal(params, v); err != nil {
+		return &Error{Code: CodeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+func (s *Server) initialize(params json.RawMessage) (interface{}, error) {
+	var p InitializeParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	s.initialized = true
+	return &InitializeResult{
+		Capabilities: ServerCapabilities{
+			TextDocumentSync:           TextDocumentSyncOptions{OpenClose: true, Change: SyncFull},
+			HoverProvider:              true,
+			DefinitionProvider:         true,
+			ReferencesProvider:         true,
+			RenameProvider:             true,
+			DocumentFormattingProvider: true,
+			DocumentSymbolProvider:     true,
+			SemanticTokensProvider: &SemanticTokensOptions{
+				Legend: SemanticTokensLegend{TokenTypes: tokenTypes, TokenModifiers: tokenModifiers},
+				Full:   true,
+			},
+			CompletionProvider: &CompletionOptions{TriggerCharacters: []string{".", ":", "$"}},
+		},
+		ServerInfo: ServerInfo{Name: "cypher-lsp"},
+	}, nil
+}
+
+// Documents
+
+// document is a document the client has opened, parsed.
+type document struct {
+	uri     string
+	version int
+	text    string
+	lines   []int // offsets of the starts of lines
+
+	tree   *cst.Tree
+	script *ast.Script
+	errs   cypher.ErrorList
+	infos  map[*ast.Query]*sema.Info
+
+	toks []cypher.Token // from tokens, once it is called
+}
+
+func newDocument(uri string, version int, text string) *document {
+	d := &document{uri: uri, version: version, text: text, lines: lineStarts(text)}
+	tree, err := cypher.ParseScriptCST(text)
+	d.tree = tree
+	d.script = tree.AST().(*ast.Script)
+	errors.As(err, &d.errs)
+	d.infos = map[*ast.Query]*sema.Info{}
+	for _, q := range d.queries() {
+		d.infos[q] = sema.Check(q)
+	}
+	return d
+}
+
+// lineStarts returns the offsets of the starts of the lines of a text.
+func lineStarts(text string) []int {
+	lines := []int{0}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			lines = append(lines, i+1)
+		}
+	}
+	return lines
+}
+
+// queries returns the queries of the document, leaving out commands.
+func (d *document) queries() []*ast.Query {
+	var qs []*ast.Query
+	for _, stmt := range d.script.Statements {
+		if q, ok := stmt.(*ast.Query); ok {
+			qs = append(qs, q)
+		}
+	}
+	return qs
+}
+
+// position returns the position of a byte offset of the document.
+func (d *document) position(offset int) Position {
+	if offset > len(d.text) {
+		offset = len(d.text)
+	}
+	line := sort.Search(len(d.lines), func(i int) bool { return d.lines[i] > offset }) - 1
+	return Position{Line: line, Character: utf16Len(d.text[d.lines[line]:offset])}
+}
+
+// offset returns the byte offset of a position of the document. Positions
+// past the end of a line are taken to be at its end.
+func (d *document) offset(pos Position) int {
+	if pos.Line < 0 {
+		return 0
+	}
+	if pos.Line >= len(d.lines) {
+		return len(d.text)
+	}
+	start, end := d.lines[pos.Line], len(d.text)
+	if pos.Line+1 < len(d.lines) {
+		end = d.lines[pos.Line+1] - 1
+	}
+	n := 0
+	for i, r := range d.text[start:end] {
+		if n >= pos.Character {
+			return start + i
+		}
+		n += utf16RuneLen(r)
+	}
+	return end
+}
+
+// rangeOf returns the range of the document between two byte offsets.
+func (d *document) rangeOf(start, end int) Range {
+	return Range{Start: d.position(start), End: d.position(end)}
+}
+
+func (d *document) spanRange(span ast.Span) Range {
+	return d.rangeOf(span.Start.Offset, span.End.Offset)
+}
+
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16RuneLen(r)
+	}
+	return n
+}
+
+func utf16RuneLen(r rune) int {
+	if r >= 0x10000 && r <= utf8.MaxRune {
+		return 2
+	}
+	return 1
+}
+
+func (s *Server) document(uri string) (*document, error) {
+	d := s.docs[uri]
+	if d == nil {
+		return nil, &Error{Code: CodeInvalidParams, Message: "unknown document " + uri}
+	}
+	return d, nil
+}
+
+func (s *Server) didOpen(params json.RawMessage) (interface{}, error) {
+	var p DidOpenTextDocumentParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	s.open(newDocument(p.TextDocument.URI, p.TextDocument.Version, p.TextDocument.Text))
+	return nil, nil
+}
+
+func (s *Server) didChange(params json.RawMessage) (interface{}, error) {
+	var p DidChangeTextDocumentParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	d, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	text := d.text
+	for _, c := range p.ContentChanges {
+		if c.Range == nil {
+			text = c.Text
+			continue
+		}
+		// Clients may send ranges even though the server asks for
+		// whole documents. Only the lines of the text are needed to find
+		// them; it is parsed once all the changes are made.
+		cur := &document{text: text, lines: lineStarts(text)}
+		text = text[:cur.offset(c.Range.Start)] + c.Text + text[cur.offset(c.Range.End):]
+	}
+	s.open(newDocument(d.uri, p.TextDocument.Version, text))
+	return nil, nil
+}
+
+func (s *Server) didClose(params json.RawMessage) (interface{}, error) {
+	var p DidCloseTextDocumentParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	delete(s.docs, p.TextDocument.URI)
+	// Clear the diagnostics of the closed document.
+	s.conn.notify("textDocument/publishDiagnostics", &PublishDiagnosticsParams{URI: p.TextDocument.URI, Diagnostics: []Diagnostic{}})
+	return nil, nil
+}
+
+// open keeps a document, and publishes its diagnostics.
+func (s *Server) open(d *document) {
+	s.docs[d.uri] = d
+	s.conn.notify("textDocument/publishDiagnostics", &PublishDiagnosticsParams{
+		URI:         d.uri,
+		Version:     d.version,
+		Diagnostics: s.diagnostics(d),
+	})
+}
+
+// diagnostics returns the syntax errors of a document, and for the
+// queries without any, their semantic errors and what the lint rules find
+// in them.
+func (s *Server) diagnostics(d *document) []Diagnostic {
+	diags := []Diagnostic{}
+	broken := map[*ast.Query]bool{}
+	for _, e := range d.errs {
+		msg := e.Msg
+		if e.Hint != "" {
+			msg += " (" + e.Hint + ")"
+		}
+		diags = append(diags, Diagnostic{
+			Range:    d.rangeOf(e.Offset, e.Offset+len(e.Token)),
+			Severity: SeverityError,
+			Source:   "cypher",
+			Message:  msg,
+		})
+		for _, q := range d.queries() {
+			if span := q.Span(); span.Start.Offset <= e.Offset && e.Offset <= span.End.Offset {
+				broken[q] = true
+			}
+		}
+	}
+	for _, q := range d.queries() {
+		if broken[q] {
+			continue
+		}
+		info := d.infos[q]
+		for _, e := range info.Errors {
+			diags = append(diags, Diagnostic{
+				Range:    d.spanRange(e.Span),
+				Severity: SeverityError,
+				Source:   "cypher",
+				Message:  e.Msg,
+			})
+		}
+		if len(info.Errors) > 0 {
+			continue
+		}
+		for _, l := range lint.Run(q, info, s.opts.Rules) {
+			diags = append(diags, Diagnostic{
+				Range:    d.spanRange(l.Span),
+				Severity: SeverityWarning,
+				Code:     l.Rule,
+				Source:   "cypher-lint",
+				Message:  l.Msg,
+			})
+		}
+	}
+	return diags
+}
+
+func (s *Server) formatting(params json.RawMessage) (interface{}, error) {
+	var p DocumentFormattingParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	d, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	var style cypher.Style
+	if s.opts.Style != nil {
+		style = *s.opts.Style
+	} else if p.Options.TabSize > 0 {
+		style.Indent = p.Options.TabSize
+	}
+	text, err := cypher.PrettyScript(d.text, &style)
+	if err != nil || text == d.text {
+		// Documents with syntax errors are left alone.
+		return []TextEdit{}, nil
+	}
+	return []TextEdit{{Range: d.rangeOf(0, len(d.text)), NewText: text}}, nil
+}
+
+// snippet returns the first line of text, shortened to about max
+// characters.
+func snippet(text string, max int) string {
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		text = text[:i]
+	}
+	text = strings.TrimSpace(text)
+	if utf8.RuneCountInString(text) > max {
+		text = string([]rune(text)[:max-1]) + "…"
+	}
+	return text
+}
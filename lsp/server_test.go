@@ -0,0 +1,452 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/textproto"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/a-poor/cypher/complete"
+)
+
+// client is a client of a server running in the same process.
+type client struct {
+	t      *testing.T
+	conn   *conn
+	id     int
+	notes  []*message    // notifications not yet taken
+	msgs   chan *message // messages from the server
+	served chan error    // what Serve returned
+}
+
+func newClient(t *testing.T, opts *Options) *client {
+	t.Helper()
+	toServer, fromClient := io.Pipe()
+	toClient, fromServer := io.Pipe()
+	c := &client{
+		t:      t,
+		conn:   newConn(toClient, fromClient),
+		msgs:   make(chan *message, 100),
+		served: make(chan error, 1),
+	}
+	go func() {
+		c.served <- NewServer(opts).Serve(toServer, fromServer)
+		fromServer.Close()
+	}()
+	go func() {
+		for {
+			msg, err := c.conn.read()
+			if err != nil {
+				close(c.msgs)
+				return
+			}
+			c.msgs <- msg
+		}
+	}()
+	t.Cleanup(func() { fromClient.Close() })
+	return c
+}
+
+// call sends a request, and decodes the result of its response into
+// result, returning the error of the response.
+func (c *client) call(method string, params, result interface{}) *Error {
+	c.t.Helper()
+	c.id++
+	id := json.RawMessage(strconv.Itoa(c.id))
+	data, _ := json.Marshal(params)
+	if err := c.conn.write(&message{ID: &id, Method: method, Params: data}); err != nil {
+		c.t.Fatalf("%s: %v", method, err)
+	}
+	for msg := range c.msgs {
+		if msg.Method != "" {
+			c.notes = append(c.notes, msg)
+			continue
+		}
+		if string(*msg.ID) != string(id) {
+			c.t.Fatalf("%s: response to request %s, want %s", method, *msg.ID, id)
+		}
+		if msg.Error != nil {
+			return msg.Error
+		}
+		if result != nil {
+			if err := json.Unmarshal(msg.Result, result); err != nil {
+				c.t.Fatalf("%s: %v", method, err)
+			}
+		}
+		return nil
+	}
+	c.t.Fatalf("%s: no response", method)
+	return nil
+}
+
+// notify sends a notification.
+func (c *client) notify(method string, params interface{}) {
+	c.t.Helper()
+	if err := c.conn.notify(method, params); err != nil {
+		c.t.Fatalf("%s: %v", method, err)
+	}
+}
+
+// diagnostics returns the diagnostics the server next publishes.
+func (c *client) diagnostics() PublishDiagnosticsParams {
+	c.t.Helper()
+	var p PublishDiagnosticsParams
+	for len(c.notes) == 0 {
+		msg, ok := <-c.msgs
+		if !ok {
+			c.t.Fatal("no diagnostics")
+		}
+		c.notes = append(c.notes, msg)
+	}
+	msg := c.notes[0]
+	c.notes = c.notes[1:]
+	if msg.Method != "textDocument/publishDiagnostics" {
+		c.t.Fatalf("got %s notification, want diagnostics", msg.Method)
+	}
+	if err := json.Unmarshal(msg.Params, &p); err != nil {
+		c.t.Fatal(err)
+	}
+	return p
+}
+
+// open opens a document, returning its diagnostics.
+func (c *client) open(uri, text string) []Diagnostic {
+	c.t.Helper()
+	c.notify("textDocument/didOpen", DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{URI: uri, LanguageID: "cypher", Version: 1, Text: text},
+	})
+	return c.diagnostics().Diagnostics
+}
+
+func newInitializedClient(t *testing.T, opts *Options) *client {
+	t.Helper()
+	c := newClient(t, opts)
+	var res InitializeResult
+	if err := c.call("initialize", InitializeParams{}, &res); err != nil {
+		t.Fatal(err)
+	}
+	if res.ServerInfo.Name != "cypher-lsp" || !res.Capabilities.HoverProvider ||
+		!reflect.DeepEqual(res.Capabilities.SemanticTokensProvider.Legend.TokenTypes, tokenTypes) {
+		t.Fatalf("initialize = %+v", res)
+	}
+	c.notify("initialized", struct{}{})
+	return c
+}
+
+func pos(line, char int) Position { return Position{Line: line, Character: char} }
+
+func rng(line, start, end int) Range { return Range{Start: pos(line, start), End: pos(line, end)} }
+
+func at(uri string, p Position) TextDocumentPositionParams {
+	return TextDocumentPositionParams{TextDocument: TextDocumentIdentifier{URI: uri}, Position: p}
+}
+
+const doc = `// name: adults
+MATCH (p:Person)-[:KNOWS]->(f)
+WHERE p.age > $min
+RETURN toUpper(f.name) AS name, count(*) AS n;
+`
+
+func TestLifecycle(t *testing.T) {
+	c := newClient(t, nil)
+	if err := c.call("textDocument/hover", at("file:///a.cypher", pos(0, 0)), nil); err == nil || err.Code != CodeServerNotInitialized {
+		t.Errorf("hover before initialize = %v; want code %d", err, CodeServerNotInitialized)
+	}
+	if err := c.call("initialize", InitializeParams{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.call("cypher/unknown", struct{}{}, nil); err == nil || err.Code != CodeMethodNotFound {
+		t.Errorf("unknown method = %v; want code %d", err, CodeMethodNotFound)
+	}
+	if err := c.call("shutdown", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	c.notify("exit", nil)
+	if err := <-c.served; err != nil {
+		t.Errorf("Serve = %v; want nil", err)
+	}
+}
+
+func TestExitWithoutShutdown(t *testing.T) {
+	c := newInitializedClient(t, nil)
+	c.notify("exit", nil)
+	if err := <-c.served; err != ErrNoShutdown {
+		t.Errorf("Serve = %v; want %v", err, ErrNoShutdown)
+	}
+}
+
+func TestParseErrorReply(t *testing.T) {
+	toServer, fromClient := io.Pipe()
+	toClient, fromServer := io.Pipe()
+	go NewServer(nil).Serve(toServer, fromServer)
+	defer fromClient.Close()
+	go io.WriteString(fromClient, "Content-Length: 8\r\n\r\n{broken}")
+	r := bufio.NewReader(toClient)
+	header, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, _ := strconv.Atoi(header.Get("Content-Length"))
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		t.Fatal(err)
+	}
+	// The id of a message that is not JSON is unknown, and must be null.
+	var got map[string]interface{}
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatal(err)
+	}
+	if id, ok := got["id"]; !ok || id != nil || !strings.Contains(string(body), `"code":-32700`) {
+		t.Errorf("reply to a parse error = %s; want a null id and code %d", body, CodeParseError)
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	c := newInitializedClient(t, nil)
+	tests := []struct {
+		text string
+		want []Diagnostic
+	}{
+		{doc, []Diagnostic{}},
+		{"MATCH (n RETURN n", []Diagnostic{{
+			Range: rng(0, 9, 15), Severity: SeverityError, Source: "cypher",
+			Message: `unexpected "RETURN", expected one of ':', '{', '$' or ')'`,
+		}}},
+		{"MATCH (n) RETURN m;\nMATCH (x) RETURN x", []Diagnostic{{
+			Range: rng(0, 17, 18), Severity: SeverityError, Source: "cypher",
+			Message: "variable `m` is not declared",
+		}, {
+			Range: rng(1, 6, 9), Severity: SeverityWarning, Code: "all-nodes-scan", Source: "cypher-lint",
+			Message: "path has no label, relationship type or bound node, so every node must be scanned",
+		}}},
+	}
+	for _, tt := range tests {
+		if got := c.open("file:///a.cypher", tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("diagnostics of %q:\n got %+v\nwant %+v", tt.text, got, tt.want)
+		}
+	}
+
+	c.notify("textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument:   VersionedTextDocumentIdentifier{URI: "file:///a.cypher", Version: 2},
+		ContentChanges: []TextDocumentContentChangeEvent{{Range: &Range{Start: pos(0, 17), End: pos(0, 18)}, Text: "n"}},
+	})
+	if p := c.diagnostics(); p.Version != 2 || len(p.Diagnostics) != 2 || p.Diagnostics[0].Code != "all-nodes-scan" {
+		t.Errorf("diagnostics after change = %+v; want version 2 and two warnings", p)
+	}
+
+	// Each change applies to the text the one before left.
+	c.notify("textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument: VersionedTextDocumentIdentifier{URI: "file:///a.cypher", Version: 3},
+		ContentChanges: []TextDocumentContentChangeEvent{
+			{Text: "MATCH (n:A) RETURN n"},
+			{Range: &Range{Start: pos(0, 19), End: pos(0, 20)}, Text: "m"},
+			{Range: &Range{Start: pos(0, 0), End: pos(0, 0)}, Text: "// x\n"},
+		},
+	})
+	want := []Diagnostic{{
+		Range: rng(1, 19, 20), Severity: SeverityError, Source: "cypher",
+		Message: "variable `m` is not declared",
+	}}
+	if p := c.diagnostics(); p.Version != 3 || !reflect.DeepEqual(p.Diagnostics, want) {
+		t.Errorf("diagnostics after changes = %+v; want version 3 and %+v", p, want)
+	}
+
+	c.notify("textDocument/didClose", DidCloseTextDocumentParams{TextDocument: TextDocumentIdentifier{URI: "file:///a.cypher"}})
+	if p := c.diagnostics(); len(p.Diagnostics) != 0 {
+		t.Errorf("diagnostics after close = %+v; want none", p)
+	}
+}
+
+func TestHover(t *testing.T) {
+	c := newInitializedClient(t, nil)
+	c.open("file:///a.cypher", doc)
+	tests := []struct {
+		pos  Position
+		want string // a part of the hover text, or "" for none
+		at   Range
+	}{
+		{pos(3, 9), "toUpper(input :: STRING) :: STRING", rng(3, 7, 14)},
+		{pos(3, 14), "", Range{}}, // the parenthesis
+		{pos(3, 32), "count(", rng(3, 32, 37)},
+		{pos(1, 2), "**MATCH**", rng(1, 0, 5)},
+		{pos(1, 7), "(node) p", rng(1, 7, 8)},
+		{pos(1, 10), "", Range{}}, // the label
+	}
+	for _, tt := range tests {
+		var h *Hover
+		if err := c.call("textDocument/hover", at("file:///a.cypher", tt.pos), &h); err != nil {
+			t.Fatal(err)
+		}
+		switch {
+		case tt.want == "" && h != nil:
+			t.Errorf("hover at %v = %q; want none", tt.pos, h.Contents.Value)
+		case tt.want == "":
+		case h == nil:
+			t.Errorf("hover at %v = none; want %q", tt.pos, tt.want)
+		case !strings.Contains(h.Contents.Value, tt.want) || *h.Range != tt.at:
+			t.Errorf("hover at %v = %q at %v; want %q at %v", tt.pos, h.Contents.Value, *h.Range, tt.want, tt.at)
+		}
+	}
+}
+
+func TestNavigation(t *testing.T) {
+	c := newInitializedClient(t, nil)
+	c.open("file:///a.cypher", doc)
+
+	var loc *Location
+	if err := c.call("textDocument/definition", at("file:///a.cypher", pos(3, 15)), &loc); err != nil {
+		t.Fatal(err)
+	}
+	if want := (Location{URI: "file:///a.cypher", Range: rng(1, 28, 29)}); loc == nil || *loc != want {
+		t.Errorf("definition = %v; want %v", loc, want)
+	}
+
+	var refs []Location
+	params := ReferenceParams{TextDocumentPositionParams: at("file:///a.cypher", pos(2, 6))}
+	params.Context.IncludeDeclaration = true
+	if err := c.call("textDocument/references", params, &refs); err != nil {
+		t.Fatal(err)
+	}
+	want := []Location{{"file:///a.cypher", rng(1, 7, 8)}, {"file:///a.cypher", rng(2, 6, 7)}}
+	if !reflect.DeepEqual(refs, want) {
+		t.Errorf("references = %v; want %v", refs, want)
+	}
+
+	for _, tt := range []struct{ name, text string }{
+		{"person", "person"},
+		{"a person", "`a person`"},
+	} {
+		var edit WorkspaceEdit
+		params := RenameParams{TextDocumentPositionParams: at("file:///a.cypher", pos(1, 7)), NewName: tt.name}
+		if err := c.call("textDocument/rename", params, &edit); err != nil {
+			t.Fatal(err)
+		}
+		want := map[string][]TextEdit{"file:///a.cypher": {{rng(1, 7, 8), tt.text}, {rng(2, 6, 7), tt.text}}}
+		if !reflect.DeepEqual(edit.Changes, want) {
+			t.Errorf("rename to %q = %v; want %v", tt.name, edit.Changes, want)
+		}
+	}
+	params2 := RenameParams{TextDocumentPositionParams: at("file:///a.cypher", pos(1, 10)), NewName: "x"}
+	if err := c.call("textDocument/rename", params2, nil); err == nil {
+		t.Error("rename of a label succeeded")
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	c := newInitializedClient(t, &Options{Schema: &complete.Schema{Labels: []string{"Person"}, PropertyKeys: []string{"born"}}})
+	c.open("file:///a.cypher", "MATCH (n:Person) RETURN n.name;\n:param x => 1\nMATCH (m:Person) WHERE m.\n")
+	tests := []struct {
+		pos  Position
+		want []CompletionItem
+	}{
+		{pos(2, 25), []CompletionItem{
+			{Label: "born", Kind: CompletionItemKindProperty, Detail: "property key", SortText: "0000", TextEdit: &TextEdit{rng(2, 25, 25), "born"}},
+		}},
+		{pos(2, 11), []CompletionItem{
+			{Label: "Person", Kind: CompletionItemKindClass, Detail: "label", SortText: "0000", TextEdit: &TextEdit{rng(2, 9, 11), "Person"}},
+		}},
+		{pos(0, 26), []CompletionItem{
+			{Label: "name", Kind: CompletionItemKindProperty, Detail: "property key", SortText: "0000", TextEdit: &TextEdit{rng(0, 26, 26), "name"}},
+			{Label: "born", Kind: CompletionItemKindProperty, Detail: "property key", SortText: "0001", TextEdit: &TextEdit{rng(0, 26, 26), "born"}},
+		}},
+		{pos(1, 3), []CompletionItem{}}, // in a command
+	}
+	for _, tt := range tests {
+		var list CompletionList
+		if err := c.call("textDocument/completion", at("file:///a.cypher", tt.pos), &list); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(list.Items, tt.want) {
+			t.Errorf("completion at %v = %+v; want %+v", tt.pos, list.Items, tt.want)
+		}
+	}
+
+	var list CompletionList
+	if err := c.call("textDocument/completion", at("file:///a.cypher", pos(2, 17)), &list); err != nil {
+		t.Fatal(err)
+	}
+	var where *CompletionItem
+	for i, item := range list.Items {
+		if item.Label == "WHERE" {
+			where = &list.Items[i]
+		}
+	}
+	if where == nil || where.Kind != CompletionItemKindKeyword || where.Documentation == nil {
+		t.Errorf("completion after MATCH = %+v; want WHERE, with its docs", list.Items)
+	}
+}
+
+func TestFormatting(t *testing.T) {
+	c := newInitializedClient(t, nil)
+	c.open("file:///a.cypher", "match (n:A)  return n")
+	var edits []TextEdit
+	params := DocumentFormattingParams{TextDocument: TextDocumentIdentifier{URI: "file:///a.cypher"}}
+	if err := c.call("textDocument/formatting", params, &edits); err != nil {
+		t.Fatal(err)
+	}
+	want := []TextEdit{{rng(0, 0, 21), "MATCH (n:A)\nRETURN n\n"}}
+	if !reflect.DeepEqual(edits, want) {
+		t.Errorf("formatting = %v; want %v", edits, want)
+	}
+}
+
+func TestDocumentSymbol(t *testing.T) {
+	c := newInitializedClient(t, nil)
+	c.open("file:///a.cypher", doc+"\nMATCH (m) RETURN m\n")
+	var syms []DocumentSymbol
+	params := DocumentSymbolParams{TextDocument: TextDocumentIdentifier{URI: "file:///a.cypher"}}
+	if err := c.call("textDocument/documentSymbol", params, &syms); err != nil {
+		t.Fatal(err)
+	}
+	if len(syms) != 2 {
+		t.Fatalf("documentSymbol = %+v; want 2 symbols", syms)
+	}
+	if s := syms[0]; s.Name != "adults" || s.Range.Start != pos(0, 0) || s.SelectionRange.Start != pos(1, 0) {
+		t.Errorf("first symbol = %+v; want adults from line 0", s)
+	}
+	var vars []string
+	for _, v := range syms[0].Children {
+		vars = append(vars, v.Name+" "+v.Detail)
+	}
+	if want := []string{"p node", "f node", "name value", "n value"}; !reflect.DeepEqual(vars, want) {
+		t.Errorf("variables = %q; want %q", vars, want)
+	}
+	if s := syms[1]; s.Name != "MATCH (m) RETURN m" {
+		t.Errorf("second symbol = %q; want the text of the query", s.Name)
+	}
+}
+
+func TestSemanticTokens(t *testing.T) {
+	c := newInitializedClient(t, nil)
+	c.open("file:///a.cypher", "// q\nMATCH (p:Person {name: $n})\nRETURN p.age + 1, 'x';\nCALL db.labels()")
+	var toks SemanticTokens
+	params := SemanticTokensParams{TextDocument: TextDocumentIdentifier{URI: "file:///a.cypher"}}
+	if err := c.call("textDocument/semanticTokens/full", params, &toks); err != nil {
+		t.Fatal(err)
+	}
+	want := []int{
+		0, 0, 4, tokComment, 0, // q
+		1, 0, 5, tokKeyword, 0, // MATCH
+		0, 7, 1, tokVariable, 1, // p
+		0, 2, 6, tokType, 0, // Person
+		0, 8, 4, tokProperty, 0, // name
+		0, 6, 2, tokParameter, 0, // $n
+		1, 0, 6, tokKeyword, 0, // RETURN
+		0, 7, 1, tokVariable, 0, // p
+		0, 2, 3, tokProperty, 0, // age
+		0, 4, 1, tokOperator, 0, // +
+		0, 2, 1, tokNumber, 0, // 1
+		0, 3, 3, tokString, 0, // 'x'
+		1, 0, 4, tokKeyword, 0, // CALL
+		0, 5, 2, tokFunction, 0, // db
+		0, 2, 1, tokFunction, 0, // .
+		0, 1, 6, tokFunction, 0, // labels
+	}
+	if !reflect.DeepEqual(toks.Data, want) {
+		t.Errorf("semantic tokens =\n%v\nwant\n%v", toks.Data, want)
+	}
+}
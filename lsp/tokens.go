@@ -0,0 +1,180 @@
+package lsp
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/a-poor/cypher"
+	"github.com/a-poor/cypher/ast"
+	"github.com/a-poor/cypher/cst"
+)
+
+// The types and modifiers of semantic tokens, in the order of the legend.
+var (
+	tokenTypes = []string{
+		"keyword", "variable", "parameter", "function", "property",
+		"type", "string", "number", "comment", "operator",
+	}
+	tokenModifiers = []string{"declaration"}
+)
+
+const (
+	tokKeyword = iota
+	tokVariable
+	tokParameter
+	tokFunction
+	tokProperty
+	tokType
+	tokString
+	tokNumber
+	tokComment
+	tokOperator
+)
+
+// leaf is a token of a document, with the node it is a child of.
+type leaf struct {
+	tok    *cst.Token
+	parent *cst.Node
+}
+
+// leaves returns the tokens of the document, in source order.
+func (d *document) leaves() []leaf {
+	var leaves []leaf
+	var walk func(n *cst.Node)
+	walk = func(n *cst.Node) {
+		for _, c := range n.Children {
+			switch c := c.(type) {
+			case *cst.Token:
+				leaves = append(leaves, leaf{c, n})
+			case *cst.Node:
+				walk(c)
+			}
+		}
+	}
+	walk(d.tree.Root)
+	return leaves
+}
+
+// leafAt returns the token at a byte offset, or just before it if the
+// offset is at the end of a name, and whether there is one.
+func (d *document) leafAt(offset int) (leaf, bool) {
+	leaves := d.leaves()
+	i := sort.Search(len(leaves), func(i int) bool { return leaves[i].tok.Loc.End.Offset > offset })
+	if i < len(leaves) && leaves[i].tok.Loc.Start.Offset <= offset {
+		return leaves[i], true
+	}
+	if i > 0 && leaves[i-1].tok.Loc.End.Offset == offset && leaves[i-1].tok.IsName() {
+		return leaves[i-1], true
+	}
+	return leaf{}, false
+}
+
+// semanticTypes maps the kinds of tokens that cypher.Tokenize finds to
+// semantic token types. The kinds it leaves out, whitespace and
+// punctuation, are not sent to the client.
+var semanticTypes = map[cypher.TokenKind]int{
+	cypher.TokenComment:          tokComment,
+	cypher.TokenKeyword:          tokKeyword,
+	cypher.TokenIdentifier:       tokVariable,
+	cypher.TokenFunction:         tokFunction,
+	cypher.TokenLabel:            tokType,
+	cypher.TokenRelationshipType: tokType,
+	cypher.TokenPropertyKey:      tokProperty,
+	cypher.TokenString:           tokString,
+	cypher.TokenNumber:           tokNumber,
+	cypher.TokenParameter:        tokParameter,
+	cypher.TokenOperator:         tokOperator,
+}
+
+// tokens returns the tokens of the document, as cypher.Tokenize classifies
+// them.
+func (d *document) tokens() []cypher.Token {
+	if d.toks == nil {
+		d.toks = cypher.Tokenize(d.text)
+	}
+	return d.toks
+}
+
+// kindAt returns the kind of the token at a byte offset, or
+// cypher.TokenWhitespace if there is none.
+func (d *document) kindAt(offset int) cypher.TokenKind {
+	toks := d.tokens()
+	i := sort.Search(len(toks), func(i int) bool { return toks[i].Loc.End.Offset > offset })
+	if i < len(toks) && toks[i].Loc.Start.Offset <= offset {
+		return toks[i].Kind
+	}
+	return cypher.TokenWhitespace
+}
+
+// semanticToken is a classified range of a document.
+type semanticToken struct {
+	start, end int
+	typ        int
+	decl       bool
+}
+
+// semanticTokens returns the classified tokens and comments of the
+// document, in source order.
+func (d *document) semanticTokens() []semanticToken {
+	vars := map[int]*ast.Variable{}
+	ast.Inspect(d.script, func(n ast.Node) bool {
+		if v, ok := n.(*ast.Variable); ok {
+			vars[v.Span().Start.Offset] = v
+		}
+		return true
+	})
+	var toks []semanticToken
+	for _, tok := range d.tokens() {
+		typ, ok := semanticTypes[tok.Kind]
+		if !ok {
+			continue
+		}
+		t := semanticToken{start: tok.Loc.Start.Offset, end: tok.Loc.End.Offset, typ: typ}
+		if v := vars[t.start]; v != nil && typ == tokVariable {
+			if sym := d.symbol(v); sym != nil {
+				t.decl = sym.Decl == v
+			}
+		}
+		toks = append(toks, t)
+	}
+	return toks
+}
+
+func (s *Server) semanticTokens(params json.RawMessage) (interface{}, error) {
+	var p SemanticTokensParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	d, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	data := []int{}
+	var last Position
+	for _, t := range d.semanticTokens() {
+		mods := 0
+		if t.decl {
+			mods = 1
+		}
+		// Tokens may not span lines, so those that do, such as block
+		// comments, are split into a token for each line.
+		for start := t.start; start < t.end; {
+			end := t.end
+			if i := strings.IndexByte(d.text[start:end], '\n'); i >= 0 {
+				end = start + i
+			}
+			if end > start {
+				pos := d.position(start)
+				delta := pos.Character
+				if pos.Line == last.Line {
+					delta -= last.Character
+				}
+				data = append(data, pos.Line-last.Line, delta, utf16Len(d.text[start:end]), t.typ, mods)
+				last = pos
+			}
+			start = end + 1
+		}
+	}
+	return &SemanticTokens{Data: data}, nil
+}
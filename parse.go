@@ -0,0 +1,1773 @@
+package cypher
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/a-poor/cypher/ast"
+)
+
+// parser is a recursive descent parser for Cypher, which builds the nodes
+// of the ast package directly.
+//
+// It follows the openCypher grammar in Cypher.g4 closely, down to the
+// places where the grammar requires or forbids whitespace between tokens,
+// so that it accepts exactly the queries the grammar does. Where the
+// grammar can only tell its alternatives apart by trying them, so does the
+// parser.
+//
+// A syntax error panics with bailout, which unwinds to the nearest place
+// the parser can recover from: the clause the error is in, the condition
+// of a MATCH, or the start of a speculative parse.
+type parser struct {
+	ctx  context.Context
+	src  *source
+	toks []token // the tokens of the input, without whitespace and comments, ending with tokEOF
+	pos  int     // index of the current token in toks
+	errs ErrorList
+
+	// expected describes what the parser has looked for at the current
+	// token, for error messages.
+	expected []string
+
+	spec   int // depth of speculative parses, in which errors are not recovered from
+	nested int // depth of subqueries, in which errors are not recovered from
+	steps  int // tokens consumed, for checking the context
+
+	// depth is how deeply the parser has recursed into nested expressions
+	// and patterns, which may be no more than maxDepth.
+	depth, maxDepth int
+
+	// negated is the index of the token after the last unary minus, or 0.
+	// An integer there may be one larger than math.MaxInt64.
+	negated int
+
+	// closer holds, for each "(" in toks, the index of the ")" that
+	// closes it, or -1 if none does.
+	closer []int
+
+	// notPattern holds the indexes of the tokens at which a relationship
+	// pattern has been tried for and not found.
+	notPattern map[int]bool
+}
+
+// bailout is the value the parser panics with on a syntax error.
+type bailout struct{}
+
+// cancelled is the value the parser panics with when its context is done.
+type cancelled struct {
+	err error
+}
+
+// tooDeep is the value the parser panics with when the input is nested
+// more deeply than it allows. Unlike a syntax error, it is not recovered
+// from: it unwinds all the way, so that speculative parses do not descend
+// into the nesting again.
+type tooDeep struct {
+	err *DepthLimitError
+	tok token // the token that went over the limit
+}
+
+// How many tokens to get through between checks of the context.
+const cancelCheckInterval = 256
+
+// newParser returns a parser for the input of src. Lexical errors are
+// reported straight away, and the tokens they leave behind are skipped.
+func newParser(ctx context.Context, src *source) *parser {
+	p := &parser{ctx: ctx, src: src, maxDepth: DefaultMaxDepth}
+	l := newLexer(src.text, src.base, src.end(), func(offset int, msg string) {
+		p.errs = append(p.errs, p.newError(offset, "", nil, msg))
+	})
+	sp := false
+	for {
+		t := l.next()
+		switch {
+		case t.kind.isTrivia():
+			sp = true
+			continue
+		case t.kind == tokIllegal:
+			continue
+		}
+		t.sp, sp = sp, false
+		p.toks = append(p.toks, t)
+		if t.kind == tokEOF {
+			p.matchParens()
+			return p
+		}
+		p.tick()
+	}
+}
+
+// matchParens fills in p.closer.
+func (p *parser) matchParens() {
+	p.closer = make([]int, len(p.toks))
+	var open []int
+	for i, t := range p.toks {
+		p.closer[i] = -1
+		switch t.kind {
+		case tokLParen:
+			open = append(open, i)
+		case tokRParen:
+			if n := len(open); n > 0 {
+				p.closer[open[n-1]] = i
+				open = open[:n-1]
+			}
+		}
+	}
+}
+
+// tick counts a token, and gives up if the context is done.
+func (p *parser) tick() {
+	p.steps++
+	if p.steps%cancelCheckInterval != 0 || p.ctx.Done() == nil {
+		return
+	}
+	if err := p.ctx.Err(); err != nil {
+		panic(cancelled{err})
+	}
+}
+
+// enter counts a level of nesting, starting at the current token, and
+// gives up if there are more than p.maxDepth of them. The methods that may
+// recurse call it, and defer a call of leave.
+func (p *parser) enter() {
+	p.depth++
+	if p.depth > p.maxDepth {
+		t := p.tok()
+		panic(tooDeep{&DepthLimitError{Limit: p.maxDepth, Pos: p.src.pos(t.start)}, t})
+	}
+}
+
+// leave ends a level of nesting counted by enter.
+func (p *parser) leave() { p.depth-- }
+
+// tok returns the current token.
+func (p *parser) tok() token { return p.toks[p.pos] }
+
+// peek returns the token n places after the current one.
+func (p *parser) peek(n int) token {
+	if p.pos+n >= len(p.toks) {
+		return p.toks[len(p.toks)-1]
+	}
+	return p.toks[p.pos+n]
+}
+
+// next consumes the current token and returns it.
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+		p.expected = p.expected[:0]
+		p.tick()
+	}
+	return t
+}
+
+// is reports whether the current token is of kind k.
+func (p *parser) is(k tokenKind) bool { return p.toks[p.pos].kind == k }
+
+// at is like is, but also notes k as expected, for error messages.
+func (p *parser) at(k tokenKind) bool {
+	if p.is(k) {
+		return true
+	}
+	p.expect(k.String())
+	return false
+}
+
+// got consumes the current token if it is of kind k.
+func (p *parser) got(k tokenKind) bool {
+	if p.at(k) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+// want consumes a token of kind k, or fails.
+func (p *parser) want(k tokenKind) token {
+	if !p.at(k) {
+		p.fail()
+	}
+	return p.next()
+}
+
+// expect notes what the parser is looking for at the current token.
+func (p *parser) expect(desc string) {
+	p.expected = append(p.expected, desc)
+}
+
+// sp fails unless the current token follows whitespace.
+func (p *parser) sp() {
+	if !p.tok().sp {
+		p.expect("whitespace")
+		p.fail()
+	}
+}
+
+// noSP fails if the current token follows whitespace.
+func (p *parser) noSP() {
+	if t := p.tok(); t.sp {
+		p.report(p.newError(t.start, t.text, nil, fmt.Sprintf("unexpected whitespace before %s", describe(t))))
+		panic(bailout{})
+	}
+}
+
+// fail reports that the current token is not what was expected, and
+// unwinds.
+func (p *parser) fail() {
+	p.report(p.unexpected())
+	panic(bailout{})
+}
+
+// report records an error. Errors found while speculating are dropped if
+// the speculation fails.
+func (p *parser) report(err *ParseError) {
+	p.errs = append(p.errs, err)
+}
+
+// reportLiteral reports err, if it is not nil, as a problem with the
+// literal token t.
+func (p *parser) reportLiteral(t token, err *literalError) {
+	if err != nil {
+		p.report(p.newError(t.start+err.offset, t.text, nil, err.msg))
+	}
+}
+
+// unexpected returns the error for the current token.
+func (p *parser) unexpected() *ParseError {
+	t := p.tok()
+	return p.newError(t.start, t.text, p.expected, "unexpected "+describe(t))
+}
+
+func describe(t token) string {
+	if t.kind == tokEOF {
+		return "end of input"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// newError returns an error at a byte offset. expected lists what would
+// have been accepted there, if it is known.
+func (p *parser) newError(offset int, tok string, expected []string, msg string) *ParseError {
+	var descs []string
+	seen := map[string]bool{}
+	for _, d := range expected {
+		if !seen[d] {
+			seen[d] = true
+			descs = append(descs, d)
+		}
+	}
+	if len(descs) > 0 {
+		msg += ", expected " + joinExpected(descs)
+	}
+	pos := p.src.pos(offset)
+	return &ParseError{
+		Line:        pos.Line,
+		Column:      pos.Column,
+		UTF16Column: pos.UTF16Column,
+		Offset:      offset,
+		Token:       tok,
+		Expected:    descs,
+		Msg:         msg,
+		Hint:        suggestKeyword(tok, descs),
+	}
+}
+
+// try runs f speculatively. If f fails, the parser is put back where it
+// was, forgetting any errors f reported, and try returns false.
+func (p *parser) try(f func()) (ok bool) {
+	pos, expected, nerrs := p.pos, append([]string(nil), p.expected...), len(p.errs)
+	p.spec++
+	defer func() {
+		p.spec--
+		if r := recover(); r != nil {
+			if _, isBailout := r.(bailout); !isBailout {
+				panic(r)
+			}
+			p.pos, p.expected, p.errs, ok = pos, expected, p.errs[:nerrs], false
+		}
+	}()
+	f()
+	return true
+}
+
+// info returns the NodeInfo for the tokens consumed since the one at index
+// start. If there are none, the node gets an empty span at that token.
+func (p *parser) info(start int) ast.NodeInfo {
+	if p.pos == start {
+		off := p.toks[start].start
+		return ast.NodeInfo{Loc: p.src.span(off, off)}
+	}
+	from, to := p.toks[start].start, p.toks[p.pos-1].end
+	return ast.NodeInfo{Raw: p.src.text[from:to], Loc: p.src.span(from, to)}
+}
+
+// The statement
+
+// query parses a statement, with an optional semicolon at the end.
+func (p *parser) query() *ast.Query {
+	q := &ast.Query{NodeInfo: ast.NodeInfo{Loc: p.src.span(p.src.base, p.src.base)}}
+	start := p.pos
+	p.clauses(q, true)
+	if p.pos > start {
+		q.NodeInfo = p.info(start)
+	}
+	p.got(tokSemicolon)
+	if !p.at(tokEOF) {
+		p.report(p.unexpected())
+		from := p.pos
+		for !p.is(tokEOF) {
+			p.next()
+		}
+		q.Clauses = append(q.Clauses, p.badClause(from))
+		q.NodeInfo = p.info(start)
+	}
+	return q
+}
+
+// queryState is where the parser is in the grammar of a query, which
+// decides the clauses that may come next.
+type queryState int
+
+const (
+	// At the start of a query, or after WITH or a reading clause: any
+	// clause may follow, but the query may not end.
+	stateReading queryState = iota
+
+	// After an updating clause, which may be followed by further updating
+	// clauses, WITH or RETURN, or end the query.
+	stateUpdating
+
+	// After RETURN or a standalone CALL, which end the query.
+	stateDone
+)
+
+// The keywords that can start a clause, in the order they are listed when
+// one is expected.
+var (
+	readingKeywords  = []tokenKind{kwOptional, kwMatch, kwUnwind, kwCall}
+	updatingKeywords = []tokenKind{kwMerge, kwCreate, kwSet, kwDetach, kwDelete, kwRemove}
+)
+
+// clauses parses the clauses of a query, appending them to q. The query
+// ends at the end of the input or a semicolon if top is set, and at a
+// closing brace otherwise, as in an EXISTS subquery.
+func (p *parser) clauses(q *ast.Query, top bool) {
+	state := stateReading
+	first := true // whether a standalone CALL may come next
+	standalone := false
+	reported := false // whether a clause out of order has been reported
+	for {
+		t := p.tok()
+		if t.kind == tokEOF || top && t.kind == tokSemicolon || !top && t.kind == tokRBrace {
+			if state == stateReading {
+				p.expectClauses(state)
+				p.syntaxError()
+			}
+			return
+		}
+
+		var next queryState
+		switch t.kind {
+		case kwUnion:
+			if (state == stateReading || standalone) && !reported {
+				p.expectClauses(state)
+				p.syntaxError()
+				reported = true
+			}
+			q.Clauses = append(q.Clauses, p.union())
+			state, first, standalone = stateReading, false, false
+			continue
+		case kwOptional, kwMatch, kwUnwind, kwCall:
+			next = stateReading
+			if state != stateReading && !reported {
+				p.expectClauses(state)
+				p.syntaxError()
+				reported = true
+			}
+		case kwMerge, kwCreate, kwSet, kwDetach, kwDelete, kwRemove, kwWith, kwReturn:
+			switch t.kind {
+			case kwWith:
+				next = stateReading
+			case kwReturn:
+				next = stateDone
+			default:
+				next = stateUpdating
+			}
+			if state == stateDone && !reported {
+				p.expectClauses(state)
+				p.syntaxError()
+				reported = true
+			}
+		default:
+			p.expectClauses(state)
+			p.syntaxError()
+			start := p.pos
+			p.skipClause()
+			if p.pos == start {
+				p.next()
+			}
+			q.Clauses = append(q.Clauses, p.badClause(start))
+			continue
+		}
+
+		alone := t.kind == kwCall && first && top && p.callAlone()
+		q.Clauses = append(q.Clauses, p.recoverClause(func() ast.Clause {
+			return p.clause(alone)
+		}))
+		state, first = next, false
+		if alone {
+			state, standalone = stateDone, true
+		}
+	}
+}
+
+// expectClauses notes the clauses that may come next in the given state,
+// or the end of the query.
+func (p *parser) expectClauses(state queryState) {
+	if state == stateReading {
+		for _, k := range readingKeywords {
+			p.expect(k.String())
+		}
+	}
+	if state != stateDone {
+		for _, k := range updatingKeywords {
+			p.expect(k.String())
+		}
+		p.expect(kwWith.String())
+		p.expect(kwReturn.String())
+	}
+	if state != stateReading {
+		p.expect(kwUnion.String())
+	}
+}
+
+// syntaxError reports the current token as unexpected, and unwinds unless
+// the parser can carry on from it.
+func (p *parser) syntaxError() {
+	if !p.recovering() {
+		p.fail()
+	}
+	p.report(p.unexpected())
+}
+
+// callAlone reports whether the CALL at the current token makes up the
+// whole statement, as a standalone CALL.
+func (p *parser) callAlone() bool {
+	braces := 0
+	for i := p.pos + 1; i < len(p.toks); i++ {
+		switch p.toks[i].kind {
+		case tokEOF:
+			return true
+		case tokSemicolon:
+			if braces == 0 {
+				return true
+			}
+		case tokLBrace:
+			braces++
+		case tokRBrace:
+			if braces > 0 {
+				braces--
+			}
+		}
+		if braces == 0 && p.startsClause(i) {
+			return false
+		}
+	}
+	return true
+}
+
+// Clauses
+
+// clause parses the clause at the current token, which must start with a
+// clause keyword. alone is set for a CALL that makes up the whole
+// statement.
+func (p *parser) clause(alone bool) ast.Clause {
+	switch p.tok().kind {
+	case kwOptional, kwMatch:
+		return p.match()
+	case kwUnwind:
+		return p.unwind()
+	case kwCall:
+		if alone {
+			return p.standaloneCall()
+		}
+		return p.inQueryCall()
+	case kwMerge:
+		return p.merge()
+	case kwCreate:
+		return p.create()
+	case kwSet:
+		return p.set()
+	case kwDetach, kwDelete:
+		return p.delete()
+	case kwRemove:
+		return p.remove()
+	case kwWith:
+		return p.with()
+	}
+	return p.returnClause()
+}
+
+// union parses "UNION" or "UNION ALL".
+func (p *parser) union() *ast.Union {
+	start := p.pos
+	p.want(kwUnion)
+	u := &ast.Union{All: p.got(kwAll)}
+	u.NodeInfo = p.info(start)
+	return u
+}
+
+func (p *parser) match() *ast.Match {
+	start := p.pos
+	m := &ast.Match{}
+	if p.got(kwOptional) {
+		m.Optional = true
+		p.sp()
+	}
+	p.want(kwMatch)
+	m.Pattern = p.pattern()
+	if p.at(kwWhere) {
+		m.Where = p.where(true)
+	}
+	m.NodeInfo = p.info(start)
+	return m
+}
+
+// where parses a WHERE and returns its condition. If recover is set, a
+// condition with errors becomes a BadExpr running to the end of the
+// clause.
+func (p *parser) where(recover bool) ast.Expr {
+	p.want(kwWhere)
+	if !recover {
+		p.sp()
+		return p.expr()
+	}
+	return p.recoverExpr(func() ast.Expr {
+		p.sp()
+		return p.expr()
+	})
+}
+
+func (p *parser) unwind() *ast.Unwind {
+	start := p.pos
+	u := &ast.Unwind{}
+	p.want(kwUnwind)
+	u.Expr = p.expr()
+	p.sp()
+	p.want(kwAs)
+	p.sp()
+	u.Variable = p.variable()
+	u.NodeInfo = p.info(start)
+	return u
+}
+
+func (p *parser) inQueryCall() *ast.InQueryCall {
+	start := p.pos
+	c := &ast.InQueryCall{}
+	c.Procedure, c.Args, _ = p.procedureCall(false)
+	if p.at(kwYield) {
+		p.next()
+		p.sp()
+		c.Yield, c.Where = p.yieldItems()
+	}
+	c.NodeInfo = p.info(start)
+	return c
+}
+
+func (p *parser) standaloneCall() *ast.StandaloneCall {
+	start := p.pos
+	c := &ast.StandaloneCall{}
+	c.Procedure, c.Args, c.Implicit = p.procedureCall(true)
+	if p.at(kwYield) {
+		p.next()
+		p.sp()
+		if p.got(tokStar) {
+			c.YieldAll = true
+		} else {
+			c.Yield, c.Where = p.yieldItems()
+		}
+	}
+	c.NodeInfo = p.info(start)
+	return c
+}
+
+// procedureCall parses CALL, the name of the procedure and its arguments.
+// If optional is set, the arguments may be left out, and implicit reports
+// whether they were.
+func (p *parser) procedureCall(optional bool) (name string, args []ast.Expr, implicit bool) {
+	p.want(kwCall)
+	p.sp()
+	name = p.qualifiedName()
+	if optional && !p.at(tokLParen) {
+		return name, nil, true
+	}
+	p.want(tokLParen)
+	if !p.at(tokRParen) {
+		for {
+			args = append(args, p.expr())
+			if !p.got(tokComma) {
+				break
+			}
+		}
+	}
+	p.want(tokRParen)
+	return name, args, false
+}
+
+// yieldItems parses the fields yielded by a procedure, and the WHERE
+// that may follow them.
+func (p *parser) yieldItems() (items []*ast.YieldItem, where ast.Expr) {
+	for {
+		start := p.pos
+		item := &ast.YieldItem{}
+		if p.tok().kind.isName() && p.peek(1).kind == kwAs && p.peek(1).sp {
+			item.Field = unescapeName(p.next().text)
+			p.next()
+			p.sp()
+		}
+		item.Variable = p.variable()
+		item.NodeInfo = p.info(start)
+		items = append(items, item)
+		if !p.got(tokComma) {
+			break
+		}
+	}
+	if p.at(kwWhere) {
+		where = p.where(false)
+	}
+	return items, where
+}
+
+// qualifiedName parses a function or procedure name, such as "db.labels".
+// There may be no whitespace around the dots.
+func (p *parser) qualifiedName() string {
+	name := p.name()
+	for p.is(tokDot) && !p.tok().sp && !p.peek(1).sp && p.peek(1).kind.isName() {
+		p.next()
+		name += "." + p.name()
+	}
+	return name
+}
+
+func (p *parser) merge() *ast.Merge {
+	start := p.pos
+	m := &ast.Merge{}
+	p.want(kwMerge)
+	m.Pattern = p.patternPart()
+	for p.tok().sp && p.at(kwOn) {
+		m.Actions = append(m.Actions, p.mergeAction())
+	}
+	m.NodeInfo = p.info(start)
+	return m
+}
+
+// mergeAction parses ON CREATE SET or ON MATCH SET.
+func (p *parser) mergeAction() *ast.MergeAction {
+	start := p.pos
+	a := &ast.MergeAction{}
+	p.want(kwOn)
+	p.sp()
+	if !p.got(kwMatch) {
+		p.want(kwCreate)
+		a.OnCreate = true
+	}
+	p.sp()
+	a.Set = p.set()
+	a.NodeInfo = p.info(start)
+	return a
+}
+
+func (p *parser) create() *ast.Create {
+	start := p.pos
+	c := &ast.Create{}
+	p.want(kwCreate)
+	c.Pattern = p.pattern()
+	c.NodeInfo = p.info(start)
+	return c
+}
+
+// set parses SET and its items.
+func (p *parser) set() *ast.Set {
+	start := p.pos
+	s := &ast.Set{}
+	p.want(kwSet)
+	for {
+		s.Items = append(s.Items, p.setItem())
+		if !p.got(tokComma) {
+			break
+		}
+	}
+	s.NodeInfo = p.info(start)
+	return s
+}
+
+// setItem parses a property assignment, as in "n.name = 'x'", an
+// assignment or update of a whole node, as in "n = {}" or "n += {}", or
+// labels to add, as in "n:Person".
+func (p *parser) setItem() *ast.SetItem {
+	start := p.pos
+	item := &ast.SetItem{}
+	x := p.atom()
+	if p.is(tokDot) {
+		item.Target = p.propertyLookups(start, x)
+		p.want(tokEq)
+		item.Value = p.expr()
+		item.NodeInfo = p.info(start)
+		return item
+	}
+	if p.pos != start+1 || !p.toks[start].kind.isName() {
+		p.expect(tokDot.String())
+		p.fail()
+	}
+	item.Target = x
+	switch {
+	case p.at(tokEq), p.at(tokPlusEq):
+		if p.next().kind == tokPlusEq {
+			item.Op = ast.SetMerge
+		}
+		item.Value = p.expr()
+	case p.at(tokColon):
+		item.Op = ast.SetLabels
+		item.Labels = p.nodeLabels()
+	default:
+		p.expect(tokDot.String())
+		p.fail()
+	}
+	item.NodeInfo = p.info(start)
+	return item
+}
+
+func (p *parser) remove() *ast.Remove {
+	start := p.pos
+	r := &ast.Remove{}
+	p.want(kwRemove)
+	p.sp()
+	for {
+		r.Items = append(r.Items, p.removeItem())
+		if !p.got(tokComma) {
+			break
+		}
+	}
+	r.NodeInfo = p.info(start)
+	return r
+}
+
+// removeItem parses labels to remove, as in "n:Person", or a property, as
+// in "n.name".
+func (p *parser) removeItem() *ast.RemoveItem {
+	start := p.pos
+	item := &ast.RemoveItem{}
+	if p.tok().kind.isName() && p.peek(1).kind == tokColon && !p.peek(1).sp {
+		item.Target = p.variable()
+		item.Labels = p.nodeLabels()
+	} else {
+		x := p.atom()
+		if !p.at(tokDot) {
+			p.fail()
+		}
+		item.Target = p.propertyLookups(start, x)
+	}
+	item.NodeInfo = p.info(start)
+	return item
+}
+
+// delete parses DELETE or DETACH DELETE.
+func (p *parser) delete() *ast.Delete {
+	start := p.pos
+	d := &ast.Delete{}
+	if p.got(kwDetach) {
+		d.Detach = true
+		p.sp()
+	}
+	p.want(kwDelete)
+	for {
+		d.Exprs = append(d.Exprs, p.expr())
+		if !p.got(tokComma) {
+			break
+		}
+	}
+	d.NodeInfo = p.info(start)
+	return d
+}
+
+func (p *parser) with() *ast.With {
+	start := p.pos
+	w := &ast.With{}
+	p.want(kwWith)
+	w.Projection = p.projection()
+	if p.at(kwWhere) {
+		w.Where = p.where(false)
+	}
+	w.NodeInfo = p.info(start)
+	return w
+}
+
+func (p *parser) returnClause() *ast.Return {
+	start := p.pos
+	r := &ast.Return{}
+	p.want(kwReturn)
+	r.Projection = p.projection()
+	r.NodeInfo = p.info(start)
+	return r
+}
+
+// projection parses what follows WITH or RETURN.
+func (p *parser) projection() *ast.Projection {
+	start := p.pos
+	proj := &ast.Projection{}
+	if p.at(kwDistinct) {
+		p.next()
+		proj.Distinct = true
+	}
+	p.sp()
+	if p.got(tokStar) {
+		proj.Star = true
+		for p.got(tokComma) {
+			proj.Items = append(proj.Items, p.projectionItem())
+		}
+	} else {
+		for {
+			proj.Items = append(proj.Items, p.projectionItem())
+			if !p.got(tokComma) {
+				break
+			}
+		}
+	}
+
+	if p.tok().sp && p.at(kwOrder) {
+		p.next()
+		p.sp()
+		p.want(kwBy)
+		p.sp()
+		for {
+			proj.OrderBy = append(proj.OrderBy, p.sortItem())
+			// The grammar allows no whitespace before the comma.
+			if p.tok().sp || !p.got(tokComma) {
+				break
+			}
+		}
+	}
+	if p.tok().sp && p.at(kwSkip) {
+		p.next()
+		p.sp()
+		proj.Skip = p.expr()
+	}
+	if p.tok().sp && p.at(kwLimit) {
+		p.next()
+		p.sp()
+		proj.Limit = p.expr()
+	}
+	proj.NodeInfo = p.info(start)
+	return proj
+}
+
+func (p *parser) projectionItem() *ast.ProjectionItem {
+	start := p.pos
+	item := &ast.ProjectionItem{Expr: p.expr()}
+	if p.tok().sp && p.at(kwAs) {
+		p.next()
+		p.sp()
+		item.Alias = p.variable()
+	}
+	item.NodeInfo = p.info(start)
+	return item
+}
+
+func (p *parser) sortItem() *ast.SortItem {
+	start := p.pos
+	item := &ast.SortItem{Expr: p.expr()}
+	switch {
+	case p.at(kwAscending), p.at(kwAsc):
+		p.next()
+	case p.at(kwDescending), p.at(kwDesc):
+		p.next()
+		item.Descending = true
+	}
+	item.NodeInfo = p.info(start)
+	return item
+}
+
+// Patterns
+
+func (p *parser) pattern() *ast.Pattern {
+	start := p.pos
+	pat := &ast.Pattern{}
+	for {
+		pat.Paths = append(pat.Paths, p.patternPart())
+		if !p.got(tokComma) {
+			break
+		}
+	}
+	pat.NodeInfo = p.info(start)
+	return pat
+}
+
+// patternPart parses a path pattern, which may be given a name, as in
+// "p = (a)-->(b)".
+func (p *parser) patternPart() *ast.PathPattern {
+	start := p.pos
+	path := &ast.PathPattern{}
+	if p.tok().kind.isName() && p.peek(1).kind == tokEq {
+		path.Variable = p.variable()
+		p.next()
+	}
+	p.patternElement(path)
+	path.NodeInfo = p.info(start)
+	return path
+}
+
+// patternElement parses a chain of nodes and relationships into path. The
+// chain may be wrapped in parentheses, with no whitespace inside them.
+func (p *parser) patternElement(path *ast.PathPattern) {
+	if p.is(tokLParen) && p.peek(1).kind == tokLParen && !p.peek(1).sp {
+		p.enter()
+		defer p.leave()
+		p.next()
+		p.patternElement(path)
+		p.noSP()
+		p.want(tokRParen)
+		return
+	}
+	path.Nodes = append(path.Nodes, p.nodePattern())
+	for p.atRelationship() {
+		path.Relationships = append(path.Relationships, p.relationshipPattern())
+		path.Nodes = append(path.Nodes, p.nodePattern())
+	}
+}
+
+func (p *parser) atRelationship() bool {
+	switch p.tok().kind {
+	case tokLt, tokLeftArrowHead, tokMinus, tokDash:
+		return true
+	}
+	return false
+}
+
+func (p *parser) nodePattern() *ast.NodePattern {
+	start := p.pos
+	n := &ast.NodePattern{}
+	p.want(tokLParen)
+	if p.tok().kind.isName() {
+		n.Variable = p.variable()
+	}
+	if p.at(tokColon) {
+		n.Labels = p.nodeLabels()
+	}
+	if p.at(tokLBrace) || p.at(tokDollar) {
+		n.Properties = p.properties()
+	}
+	p.want(tokRParen)
+	n.NodeInfo = p.info(start)
+	return n
+}
+
+func (p *parser) relationshipPattern() *ast.RelationshipPattern {
+	start := p.pos
+	r := &ast.RelationshipPattern{}
+	left := p.is(tokLt) || p.is(tokLeftArrowHead)
+	if left {
+		p.next()
+	}
+	p.dash()
+	if p.at(tokLBracket) {
+		p.next()
+		if p.tok().kind.isName() {
+			r.Variable = p.variable()
+		}
+		if p.at(tokColon) {
+			p.next()
+			r.Types = append(r.Types, p.schemaName())
+			for p.got(tokPipe) {
+				if p.is(tokColon) && !p.tok().sp {
+					p.next()
+				}
+				r.Types = append(r.Types, p.schemaName())
+			}
+		}
+		if p.at(tokStar) {
+			p.next()
+			r.VarLength = true
+			if p.isInteger() {
+				r.MinHops = p.hops()
+				if r.MinHops != nil {
+					// A copy, so that changing one bound leaves the
+					// other alone.
+					max := *r.MinHops
+					r.MaxHops = &max
+				}
+			}
+			if p.got(tokDotDot) {
+				r.MaxHops = nil
+				if p.isInteger() {
+					r.MaxHops = p.hops()
+				}
+			}
+		}
+		if p.at(tokLBrace) || p.at(tokDollar) {
+			r.Properties = p.properties()
+		}
+		p.want(tokRBracket)
+	}
+	p.dash()
+	right := p.is(tokGt) || p.is(tokRightArrowHead)
+	if right {
+		p.next()
+	}
+	switch {
+	case left && right:
+		r.Direction = ast.DirectionBoth
+	case left:
+		r.Direction = ast.DirectionLeft
+	case right:
+		r.Direction = ast.DirectionRight
+	}
+	r.NodeInfo = p.info(start)
+	return r
+}
+
+func (p *parser) dash() {
+	if p.is(tokMinus) || p.is(tokDash) {
+		p.next()
+		return
+	}
+	p.expect(tokMinus.String())
+	p.fail()
+}
+
+func (p *parser) isInteger() bool {
+	switch p.tok().kind {
+	case tokDecimal, tokHex, tokOctal:
+		return true
+	}
+	return false
+}
+
+// hops parses the bound of a variable length relationship.
+func (p *parser) hops() *int {
+	t := p.next()
+	// Hexadecimal and octal integers are written with the same prefixes
+	// as in Go.
+	n, err := strconv.ParseInt(t.text, 0, strconv.IntSize)
+	if err != nil {
+		p.report(p.newError(t.start, t.text, nil, "hop count out of range"))
+		return nil
+	}
+	hops := int(n)
+	return &hops
+}
+
+// nodeLabels parses one or more labels, as in ":Person:Actor".
+func (p *parser) nodeLabels() []string {
+	var labels []string
+	for first := true; first || p.is(tokColon); first = false {
+		p.want(tokColon)
+		labels = append(labels, p.schemaName())
+	}
+	return labels
+}
+
+// properties parses the properties of a node or relationship pattern: a
+// map literal or a parameter.
+func (p *parser) properties() ast.Expr {
+	if p.is(tokDollar) {
+		return p.parameter()
+	}
+	return p.mapLiteral()
+}
+
+// Names
+
+func (p *parser) variable() *ast.Variable {
+	if !p.tok().kind.isName() {
+		p.expect("a variable")
+		p.fail()
+	}
+	start := p.pos
+	t := p.next()
+	return &ast.Variable{NodeInfo: p.info(start), Name: unescapeName(t.text)}
+}
+
+// name parses a function or procedure name, or part of one.
+func (p *parser) name() string {
+	if !p.tok().kind.isName() {
+		p.expect("a name")
+		p.fail()
+	}
+	return unescapeName(p.next().text)
+}
+
+// schemaName parses a label, relationship type or property key, which may
+// be a reserved word.
+func (p *parser) schemaName() string {
+	if !p.tok().kind.isSchemaName() {
+		p.expect("a name")
+		p.fail()
+	}
+	return unescapeName(p.next().text)
+}
+
+// unescapeName removes the backticks around an escaped name. Backticks are
+// escaped inside a name by doubling them.
+func unescapeName(text string) string {
+	if !strings.HasPrefix(text, "`") {
+		return text
+	}
+	var sb strings.Builder
+	for i := 1; i < len(text); i++ {
+		if text[i] != '`' {
+			sb.WriteByte(text[i])
+			continue
+		}
+		if i+1 < len(text) && text[i+1] == '`' {
+			sb.WriteByte('`')
+		}
+		i++
+	}
+	return sb.String()
+}
+
+// Expressions
+//
+// Each level of precedence has a method of its own, from orExpr, which
+// binds loosest, down to atom. Binary operators that are keywords need
+// whitespace on both sides. A level only makes a node of its own if it
+// finds its operator.
+
+// expr parses an expression.
+func (p *parser) expr() ast.Expr {
+	return p.orExpr()
+}
+
+// binary returns the BinaryExpr for x op y, where x starts at the token at
+// index start.
+func (p *parser) binary(start int, op ast.BinaryOp, x, y ast.Expr) ast.Expr {
+	return &ast.BinaryExpr{NodeInfo: p.info(start), Op: op, X: x, Y: y}
+}
+
+func (p *parser) orExpr() ast.Expr {
+	start := p.pos
+	x := p.xorExpr()
+	for p.tok().sp && p.is(kwOr) {
+		p.next()
+		p.sp()
+		x = p.binary(start, ast.OpOr, x, p.xorExpr())
+	}
+	return x
+}
+
+func (p *parser) xorExpr() ast.Expr {
+	start := p.pos
+	x := p.andExpr()
+	for p.tok().sp && p.is(kwXor) {
+		p.next()
+		p.sp()
+		x = p.binary(start, ast.OpXor, x, p.andExpr())
+	}
+	return x
+}
+
+func (p *parser) andExpr() ast.Expr {
+	start := p.pos
+	x := p.notExpr()
+	for p.tok().sp && p.is(kwAnd) {
+		p.next()
+		p.sp()
+		x = p.binary(start, ast.OpAnd, x, p.notExpr())
+	}
+	return x
+}
+
+func (p *parser) notExpr() ast.Expr {
+	if !p.is(kwNot) {
+		return p.comparison()
+	}
+	p.enter()
+	defer p.leave()
+	start := p.pos
+	p.next()
+	return &ast.UnaryExpr{Op: ast.OpNot, X: p.notExpr(), NodeInfo: p.info(start)}
+}
+
+var comparisonOps = map[tokenKind]ast.BinaryOp{
+	tokEq:  ast.OpEq,
+	tokNeq: ast.OpNeq,
+	tokLt:  ast.OpLt,
+	tokGt:  ast.OpGt,
+	tokLe:  ast.OpLe,
+	tokGe:  ast.OpGe,
+}
+
+func (p *parser) comparison() ast.Expr {
+	start := p.pos
+	x := p.additive()
+	for {
+		op, ok := comparisonOps[p.tok().kind]
+		if !ok {
+			return x
+		}
+		p.next()
+		x = p.binary(start, op, x, p.additive())
+	}
+}
+
+func (p *parser) additive() ast.Expr {
+	start := p.pos
+	x := p.multiplicative()
+	for p.is(tokPlus) || p.is(tokMinus) {
+		op := ast.OpAdd
+		if p.next().kind == tokMinus {
+			op = ast.OpSub
+		}
+		x = p.binary(start, op, x, p.multiplicative())
+	}
+	return x
+}
+
+func (p *parser) multiplicative() ast.Expr {
+	start := p.pos
+	x := p.power()
+	for p.is(tokStar) || p.is(tokSlash) || p.is(tokPercent) {
+		op := ast.OpMul
+		switch p.next().kind {
+		case tokSlash:
+			op = ast.OpDiv
+		case tokPercent:
+			op = ast.OpMod
+		}
+		x = p.binary(start, op, x, p.power())
+	}
+	return x
+}
+
+// power parses exponentiation, which the grammar makes left-associative
+// like the other binary operators.
+func (p *parser) power() ast.Expr {
+	start := p.pos
+	x := p.unary()
+	for p.is(tokCaret) {
+		p.next()
+		x = p.binary(start, ast.OpPow, x, p.unary())
+	}
+	return x
+}
+
+func (p *parser) unary() ast.Expr {
+	if !p.is(tokPlus) && !p.is(tokMinus) {
+		return p.postfix()
+	}
+	p.enter()
+	defer p.leave()
+	start := p.pos
+	op := ast.OpPlus
+	if p.next().kind == tokMinus {
+		op = ast.OpMinus
+		p.negated = p.pos
+	}
+	return &ast.UnaryExpr{Op: op, X: p.unary(), NodeInfo: p.info(start)}
+}
+
+// postfix parses an expression followed by any number of string, list and
+// null predicates, indexes and slices.
+func (p *parser) postfix() ast.Expr {
+	start := p.pos
+	x := p.propertyOrLabels()
+	for {
+		t := p.tok()
+		switch {
+		case t.sp && t.kind == kwIn:
+			p.next()
+			list := p.propertyOrLabels()
+			x = &ast.In{X: x, List: list, NodeInfo: p.info(start)}
+		case t.sp && t.kind == kwContains:
+			p.next()
+			y := p.propertyOrLabels()
+			x = &ast.StringPredicate{Op: ast.Contains, X: x, Y: y, NodeInfo: p.info(start)}
+		case t.sp && (t.kind == kwStarts || t.kind == kwEnds):
+			op := ast.StartsWith
+			if p.next().kind == kwEnds {
+				op = ast.EndsWith
+			}
+			p.sp()
+			p.want(kwWith)
+			y := p.propertyOrLabels()
+			x = &ast.StringPredicate{Op: op, X: x, Y: y, NodeInfo: p.info(start)}
+		case t.sp && t.kind == kwIs:
+			p.next()
+			p.sp()
+			not := p.got(kwNot)
+			if not {
+				p.sp()
+			}
+			p.want(kwNull)
+			x = &ast.IsNull{X: x, Not: not, NodeInfo: p.info(start)}
+		case t.kind == tokLBracket:
+			x = p.index(start, x)
+		default:
+			return x
+		}
+	}
+}
+
+// index parses an index of x, as in "[0]", or a slice, as in "[1..2]",
+// where x starts at the token at index start. The grammar allows no
+// whitespace inside the brackets.
+func (p *parser) index(start int, x ast.Expr) ast.Expr {
+	p.enter()
+	defer p.leave()
+	p.want(tokLBracket)
+	p.noSP()
+	var low, high ast.Expr
+	if !p.at(tokDotDot) {
+		low = p.orExpr()
+		p.noSP()
+		if !p.at(tokDotDot) {
+			p.want(tokRBracket)
+			return &ast.IndexExpr{X: x, Index: low, NodeInfo: p.info(start)}
+		}
+	}
+	p.next()
+	p.noSP()
+	if !p.at(tokRBracket) {
+		high = p.orExpr()
+		p.noSP()
+	}
+	p.want(tokRBracket)
+	return &ast.SliceExpr{X: x, Low: low, High: high, NodeInfo: p.info(start)}
+}
+
+// propertyOrLabels parses an atom followed by property lookups and labels,
+// as in "n.address.city" or "n:Person".
+func (p *parser) propertyOrLabels() ast.Expr {
+	start := p.pos
+	x := p.atom()
+	if p.is(tokDot) {
+		x = p.propertyLookups(start, x)
+	}
+	if p.is(tokColon) {
+		labels := p.nodeLabels()
+		x = &ast.HasLabels{X: x, Labels: labels, NodeInfo: p.info(start)}
+	}
+	return x
+}
+
+// propertyLookups parses one or more property lookups of x, as in ".name",
+// where x starts at the token at index start.
+func (p *parser) propertyLookups(start int, x ast.Expr) ast.Expr {
+	for first := true; first || p.is(tokDot); first = false {
+		p.want(tokDot)
+		key := p.schemaName()
+		x = &ast.PropertyAccess{X: x, Key: key, NodeInfo: p.info(start)}
+	}
+	return x
+}
+
+func (p *parser) atom() ast.Expr {
+	start := p.pos
+	t := p.tok()
+	switch t.kind {
+	case tokDecimal, tokHex, tokOctal:
+		p.next()
+		n, err := parseInteger(t.text, start == p.negated)
+		p.reportLiteral(t, err)
+		return &ast.IntegerLiteral{NodeInfo: p.info(start), Literal: t.text, Value: n}
+	case tokFloat:
+		p.next()
+		f, err := parseFloat(t.text)
+		p.reportLiteral(t, err)
+		return &ast.FloatLiteral{NodeInfo: p.info(start), Literal: t.text, Value: f}
+	case tokString:
+		p.next()
+		s, err := unquote(t.text)
+		p.reportLiteral(t, err)
+		return &ast.StringLiteral{NodeInfo: p.info(start), Literal: t.text, Value: s}
+	case kwTrue, kwFalse:
+		p.next()
+		return &ast.BooleanLiteral{NodeInfo: p.info(start), Value: t.kind == kwTrue}
+	case kwNull:
+		p.next()
+		return &ast.NullLiteral{NodeInfo: p.info(start)}
+	case tokDollar:
+		return p.parameter()
+	case tokLBrace:
+		return p.mapLiteral()
+	case tokLBracket:
+		return p.list()
+	case kwCase:
+		return p.caseExpr()
+	case kwExists:
+		return p.existsSubquery()
+	case tokLParen:
+		p.enter()
+		defer p.leave()
+
+		// A relationship pattern starts with a node pattern, which can
+		// look just like an expression in parentheses.
+		var path *ast.PathPattern
+		if p.mayBePattern() {
+			if p.try(func() { path = p.relationshipsPattern() }) {
+				return path
+			}
+			if p.notPattern == nil {
+				p.notPattern = map[int]bool{}
+			}
+			p.notPattern[start] = true
+		}
+		p.next()
+		x := p.orExpr()
+		p.want(tokRParen)
+		return x
+	case kwAll:
+		return p.filterFunction()
+	case kwAny, kwNone, kwSingle:
+		// These are also the names of functions, and of variables.
+		var x ast.Expr
+		if p.atFilter() && p.try(func() { x = p.filterFunction() }) {
+			return x
+		}
+		return p.nameAtom()
+	case kwCount:
+		if p.peek(1).kind == tokLParen && p.peek(2).kind == tokStar {
+			p.next()
+			p.next()
+			p.next()
+			p.want(tokRParen)
+			return &ast.CountStar{NodeInfo: p.info(start)}
+		}
+		return p.nameAtom()
+	}
+	if !t.kind.isName() {
+		p.expect("an expression")
+		p.fail()
+	}
+	return p.nameAtom()
+}
+
+// mayBePattern reports whether a relationship pattern may start at the
+// current token, a "(": whether what follows the parentheses starts a
+// relationship, as "-[", "--", "<-[" and "<--" do, and no pattern has been
+// tried for there before. Parsing the text in the parentheses as a pattern
+// only to parse it again as an expression would take time exponential in
+// how deeply parentheses are nested.
+func (p *parser) mayBePattern() bool {
+	if p.notPattern[p.pos] {
+		return false
+	}
+	end := p.closer[p.pos]
+	if end < 0 {
+		return true
+	}
+	n := end - p.pos + 1
+	if k := p.peek(n).kind; k == tokLt || k == tokLeftArrowHead {
+		n++
+	}
+	if k := p.peek(n).kind; k != tokMinus && k != tokDash {
+		return false
+	}
+	switch p.peek(n + 1).kind {
+	case tokLBracket, tokMinus, tokDash:
+		return true
+	}
+	return false
+}
+
+// nameAtom parses a function call or a variable.
+func (p *parser) nameAtom() ast.Expr {
+	i := 0
+	for p.peek(i+1).kind == tokDot && !p.peek(i+1).sp && p.peek(i+2).kind.isName() && !p.peek(i+2).sp {
+		i += 2
+	}
+	if p.peek(i+1).kind != tokLParen {
+		return p.variable()
+	}
+	p.enter()
+	defer p.leave()
+	start := p.pos
+	f := &ast.FunctionCall{Name: p.qualifiedName()}
+	p.want(tokLParen)
+	f.Distinct = p.got(kwDistinct)
+	if !p.at(tokRParen) {
+		for {
+			f.Args = append(f.Args, p.orExpr())
+			if !p.got(tokComma) {
+				break
+			}
+		}
+	}
+	p.want(tokRParen)
+	f.NodeInfo = p.info(start)
+	if path := shortestPath(f); path != nil {
+		return path
+	}
+	return f
+}
+
+// shortestPath returns the pattern f finds the shortest paths for, if it
+// is a call of shortestPath or allShortestPaths, or nil otherwise. The
+// pattern takes the place of the call.
+func shortestPath(f *ast.FunctionCall) *ast.PathPattern {
+	var shortest ast.Shortest
+	switch {
+	case strings.EqualFold(f.Name, "shortestPath"):
+		shortest = ast.ShortestPath
+	case strings.EqualFold(f.Name, "allShortestPaths"):
+		shortest = ast.AllShortestPaths
+	default:
+		return nil
+	}
+	if f.Distinct || len(f.Args) != 1 {
+		return nil
+	}
+	path, ok := f.Args[0].(*ast.PathPattern)
+	if !ok || path.Shortest != ast.NotShortest {
+		return nil
+	}
+	path.Shortest = shortest
+	path.NodeInfo = f.NodeInfo
+	return path
+}
+
+// atFilter reports whether the current token starts what looks like
+// ALL, ANY, NONE or SINGLE with a filter, as in "any(x IN xs WHERE x > 0)".
+func (p *parser) atFilter() bool {
+	return p.peek(1).kind == tokLParen && p.peek(2).kind.isName() &&
+		p.peek(3).kind == kwIn && p.peek(3).sp && p.peek(4).sp
+}
+
+var filterKinds = map[tokenKind]ast.FilterKind{
+	kwAll:    ast.FilterAll,
+	kwAny:    ast.FilterAny,
+	kwNone:   ast.FilterNone,
+	kwSingle: ast.FilterSingle,
+}
+
+// filterFunction parses ALL, ANY, NONE or SINGLE with a filter.
+func (p *parser) filterFunction() *ast.FilterExpr {
+	p.enter()
+	defer p.leave()
+	start := p.pos
+	f := &ast.FilterExpr{Kind: filterKinds[p.next().kind]}
+	p.want(tokLParen)
+	f.Variable, f.List, f.Where = p.filterExpr()
+	p.want(tokRParen)
+	f.NodeInfo = p.info(start)
+	return f
+}
+
+// filterExpr parses "x IN list", optionally followed by a WHERE.
+func (p *parser) filterExpr() (v *ast.Variable, list, where ast.Expr) {
+	v = p.variable()
+	p.sp()
+	p.want(kwIn)
+	p.sp()
+	list = p.orExpr()
+	if p.at(kwWhere) {
+		where = p.where(false)
+	}
+	return v, list, where
+}
+
+// list parses a list literal or comprehension, or a pattern
+// comprehension. Each can look like the others, so the comprehensions are
+// tried first where they might match.
+func (p *parser) list() ast.Expr {
+	p.enter()
+	defer p.leave()
+	var x ast.Expr
+	if p.peek(1).kind.isName() && p.peek(2).kind == kwIn && p.peek(2).sp && p.peek(3).sp {
+		if p.try(func() { x = p.listComprehension() }) {
+			return x
+		}
+	}
+	if p.peek(1).kind == tokLParen || p.peek(1).kind.isName() && p.peek(2).kind == tokEq {
+		if p.try(func() { x = p.patternComprehension() }) {
+			return x
+		}
+	}
+	start := p.pos
+	l := &ast.ListLiteral{}
+	p.want(tokLBracket)
+	if !p.at(tokRBracket) {
+		for {
+			l.Elems = append(l.Elems, p.orExpr())
+			if !p.got(tokComma) {
+				break
+			}
+		}
+	}
+	p.want(tokRBracket)
+	l.NodeInfo = p.info(start)
+	return l
+}
+
+func (p *parser) listComprehension() *ast.ListComprehension {
+	start := p.pos
+	c := &ast.ListComprehension{}
+	p.want(tokLBracket)
+	c.Variable, c.List, c.Where = p.filterExpr()
+	if p.got(tokPipe) {
+		c.Result = p.orExpr()
+	}
+	p.want(tokRBracket)
+	c.NodeInfo = p.info(start)
+	return c
+}
+
+func (p *parser) patternComprehension() *ast.PatternComprehension {
+	start := p.pos
+	c := &ast.PatternComprehension{}
+	p.want(tokLBracket)
+	pathStart := p.pos
+	var v *ast.Variable
+	if p.tok().kind.isName() {
+		v = p.variable()
+		p.want(tokEq)
+	}
+	c.Pattern = p.relationshipsPattern()
+	if v != nil {
+		c.Pattern.Variable = v
+		c.Pattern.NodeInfo = p.info(pathStart)
+	}
+	if p.at(kwWhere) {
+		c.Where = p.where(false)
+	}
+	p.want(tokPipe)
+	c.Result = p.orExpr()
+	p.want(tokRBracket)
+	c.NodeInfo = p.info(start)
+	return c
+}
+
+// relationshipsPattern parses a pattern used as an expression, which must
+// have at least one relationship.
+func (p *parser) relationshipsPattern() *ast.PathPattern {
+	start := p.pos
+	path := &ast.PathPattern{}
+	path.Nodes = append(path.Nodes, p.nodePattern())
+	path.Relationships = append(path.Relationships, p.relationshipPattern())
+	path.Nodes = append(path.Nodes, p.nodePattern())
+	for p.atRelationship() {
+		var r *ast.RelationshipPattern
+		var n *ast.NodePattern
+		if !p.try(func() {
+			r = p.relationshipPattern()
+			n = p.nodePattern()
+		}) {
+			break
+		}
+		path.Relationships = append(path.Relationships, r)
+		path.Nodes = append(path.Nodes, n)
+	}
+	path.NodeInfo = p.info(start)
+	return path
+}
+
+func (p *parser) mapLiteral() *ast.MapLiteral {
+	p.enter()
+	defer p.leave()
+	start := p.pos
+	m := &ast.MapLiteral{}
+	p.want(tokLBrace)
+	if !p.at(tokRBrace) {
+		for {
+			entryStart := p.pos
+			e := &ast.MapEntry{Key: p.schemaName()}
+			p.want(tokColon)
+			e.Value = p.orExpr()
+			e.NodeInfo = p.info(entryStart)
+			m.Entries = append(m.Entries, e)
+			if !p.got(tokComma) {
+				break
+			}
+		}
+	}
+	p.want(tokRBrace)
+	m.NodeInfo = p.info(start)
+	return m
+}
+
+// parameter parses a parameter, as in "$name" or "$0".
+func (p *parser) parameter() *ast.Parameter {
+	start := p.pos
+	p.want(tokDollar)
+	p.noSP()
+	if !p.tok().kind.isName() && !p.is(tokDecimal) {
+		p.expect("a name")
+		p.fail()
+	}
+	name := unescapeName(p.next().text)
+	return &ast.Parameter{NodeInfo: p.info(start), Name: name}
+}
+
+func (p *parser) caseExpr() *ast.CaseExpr {
+	p.enter()
+	defer p.leave()
+	start := p.pos
+	c := &ast.CaseExpr{}
+	p.want(kwCase)
+	if !p.at(kwWhen) {
+		c.Subject = p.orExpr()
+	}
+	for first := true; first || p.is(kwWhen); first = false {
+		whenStart := p.pos
+		w := &ast.CaseWhen{}
+		p.want(kwWhen)
+		w.When = p.orExpr()
+		p.want(kwThen)
+		w.Then = p.orExpr()
+		w.NodeInfo = p.info(whenStart)
+		c.Whens = append(c.Whens, w)
+	}
+	if p.got(kwElse) {
+		c.Else = p.orExpr()
+	}
+	p.want(kwEnd)
+	c.NodeInfo = p.info(start)
+	return c
+}
+
+// existsSubquery parses EXISTS followed by a query or a pattern in braces.
+func (p *parser) existsSubquery() *ast.ExistsSubquery {
+	p.enter()
+	defer p.leave()
+	start := p.pos
+	e := &ast.ExistsSubquery{}
+	p.want(kwExists)
+	p.want(tokLBrace)
+	if p.atClause() {
+		e.Query = p.subquery()
+	} else {
+		e.Pattern = p.pattern()
+		if p.at(kwWhere) {
+			e.Where = p.where(false)
+		}
+	}
+	p.want(tokRBrace)
+	e.NodeInfo = p.info(start)
+	return e
+}
+
+// subquery parses the query of an EXISTS subquery. Errors in it are not
+// recovered from, but spoil the expression it is in.
+func (p *parser) subquery() *ast.Query {
+	p.nested++
+	defer func() { p.nested-- }()
+	start := p.pos
+	q := &ast.Query{}
+	p.clauses(q, false)
+	q.NodeInfo = p.info(start)
+	return q
+}
+
+// atClause reports whether the current token is a clause keyword.
+func (p *parser) atClause() bool {
+	switch p.tok().kind {
+	case kwOptional, kwMatch, kwUnwind, kwCall, kwMerge, kwCreate, kwSet,
+		kwDetach, kwDelete, kwRemove, kwWith, kwReturn:
+		return true
+	}
+	return false
+}
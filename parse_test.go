@@ -0,0 +1,791 @@
+package cypher
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/a-poor/cypher/ast"
+)
+
+// corpusQuery is a query from testdata/corpus.
+type corpusQuery struct {
+	name, text string
+}
+
+// readCorpus returns the queries in testdata/corpus, which should all be
+// valid.
+func readCorpus(tb testing.TB) []corpusQuery {
+	tb.Helper()
+	files, err := filepath.Glob(filepath.Join("testdata", "corpus", "*.cypher"))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	sort.Strings(files)
+	var queries []corpusQuery
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		name := strings.TrimSuffix(filepath.Base(f), ".cypher")
+		queries = append(queries, corpusQuery{name: name, text: string(b)})
+	}
+	if len(queries) == 0 {
+		tb.Fatal("no queries in testdata/corpus")
+	}
+	return queries
+}
+
+func TestParseCorpus(t *testing.T) {
+	for _, q := range readCorpus(t) {
+		if _, err := Parse(q.text); err != nil {
+			t.Errorf("%s: %v", q.name, err)
+		}
+	}
+}
+
+// summary describes the clauses of a query, for comparing parses.
+func summary(text string) string {
+	q, err := Parse(text)
+	var sb strings.Builder
+	for _, c := range q.Clauses {
+		sb.WriteString(c.Span().String())
+		sb.WriteByte(' ')
+		sb.WriteString(c.Text())
+		sb.WriteByte('\n')
+	}
+	if err != nil {
+		sb.WriteString(err.Error())
+	}
+	return sb.String()
+}
+
+// Parsing from many goroutines at once must give the same results as
+// parsing one query at a time.
+func TestParseConcurrent(t *testing.T) {
+	queries := readCorpus(t)
+	queries = append(queries,
+		corpusQuery{"typo", "MACTH (n) RETURN n"},
+		corpusQuery{"missing expression", "MATCH (n) WHERE RETURN n"},
+		corpusQuery{"unclosed", "MATCH (n RETURN n"},
+	)
+	want := make([]string, len(queries))
+	for i, q := range queries {
+		want[i] = summary(q.text)
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for n := 0; n < 5; n++ {
+				for i := range queries {
+					i := (i + g) % len(queries)
+					if got := summary(queries[i].text); got != want[i] {
+						t.Errorf("%s: got\n%s\nwant\n%s", queries[i].name, got, want[i])
+					}
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+// Each clause of a query is a node of its own, in source order.
+func TestParseClauses(t *testing.T) {
+	q, err := Parse("OPTIONAL MATCH (n)  DETACH DELETE n UNION ALL RETURN 1 AS x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range q.Clauses {
+		got = append(got, fmt.Sprintf("%T %s", c, c.Text()))
+	}
+	want := []string{
+		"*ast.Match OPTIONAL MATCH (n)",
+		"*ast.Delete DETACH DELETE n",
+		"*ast.Union UNION ALL",
+		"*ast.Return RETURN 1 AS x",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("clauses =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		want  ParseError // the first error
+		n     int        // the number of errors
+	}{
+		{
+			"MACTH (n) RETURN n",
+			ParseError{
+				Line: 1, Column: 1, UTF16Column: 1, Offset: 0, Token: "MACTH",
+				Expected: []string{"OPTIONAL", "MATCH", "UNWIND", "CALL", "MERGE", "CREATE", "SET", "DETACH", "DELETE", "REMOVE", "WITH", "RETURN"},
+				Msg:      `unexpected "MACTH", expected one of OPTIONAL, MATCH, UNWIND, CALL, MERGE, CREATE, SET, DETACH, DELETE, REMOVE, WITH or RETURN`,
+				Hint:     "did you mean MATCH?",
+			},
+			1,
+		},
+		{
+			"MATCH (n:Person WHERE n.x RETURN n",
+			ParseError{
+				Line: 1, Column: 17, UTF16Column: 17, Offset: 16, Token: "WHERE",
+				Expected: []string{"'{'", "'$'", "')'"},
+				Msg:      `unexpected "WHERE", expected one of '{', '$' or ')'`,
+			},
+			1,
+		},
+		{
+			"MATCH (n)\nWHERE n.x = RETURN n",
+			ParseError{
+				Line: 2, Column: 13, UTF16Column: 13, Offset: 22, Token: "RETURN",
+				Expected: []string{"an expression"},
+				Msg:      `unexpected "RETURN", expected an expression`,
+			},
+			1,
+		},
+		{
+			"RETURN 1 +",
+			ParseError{
+				Line: 1, Column: 11, UTF16Column: 11, Offset: 10,
+				Expected: []string{"an expression"},
+				Msg:      "unexpected end of input, expected an expression",
+			},
+			1,
+		},
+		{
+			"RETURN 'abc",
+			ParseError{Line: 1, Column: 8, UTF16Column: 8, Offset: 7, Msg: "unterminated string"},
+			1,
+		},
+		{
+			"MATCH (n) RETRUN n",
+			ParseError{
+				Line: 1, Column: 11, UTF16Column: 11, Offset: 10, Token: "RETRUN",
+				Expected: []string{"','", "WHERE", "OPTIONAL", "MATCH", "UNWIND", "CALL", "MERGE", "CREATE", "SET", "DETACH", "DELETE", "REMOVE", "WITH", "RETURN"},
+				Msg:      `unexpected "RETRUN", expected one of ',', WHERE, OPTIONAL, MATCH, UNWIND, CALL, MERGE, CREATE, SET, DETACH, DELETE, REMOVE, WITH or RETURN`,
+				Hint:     "did you mean RETURN?",
+			},
+			2,
+		},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.query)
+		errs, ok := err.(ErrorList)
+		if !ok {
+			t.Errorf("Parse(%q) error = %#v; want an ErrorList", tt.query, err)
+			continue
+		}
+		if len(errs) != tt.n {
+			t.Errorf("Parse(%q) = %d errors; want %d: %v", tt.query, len(errs), tt.n, errs)
+		}
+		if !reflect.DeepEqual(*errs[0], tt.want) {
+			t.Errorf("Parse(%q) first error =\n%#v\nwant\n%#v", tt.query, *errs[0], tt.want)
+		}
+	}
+
+	if q, err := Parse("MATCH (n) RETURN n"); err != nil || q == nil {
+		t.Errorf("Parse of a valid query = %v, %v", q, err)
+	}
+	if err := (ErrorList{}).Err(); err != nil {
+		t.Errorf("ErrorList{}.Err() = %v; want nil", err)
+	}
+}
+
+func TestParseScript(t *testing.T) {
+	tests := []struct {
+		script string
+		want   []string // the type, byte range and text of each statement
+	}{
+		{
+			"MATCH (n) RETURN n;\n:param x => 1\nRETURN $x; RETURN ';' // ;\n",
+			[]string{
+				"*ast.Query 0-18 MATCH (n) RETURN n",
+				"*ast.ParamCommand 20-33 :param x => 1",
+				"*ast.Query 34-43 RETURN $x",
+				"*ast.Query 45-55 RETURN ';'",
+			},
+		},
+		{
+			":begin\nCREATE (n);\n:commit\n:use neo4j\n:param {a: 1};\n:sysinfo -v",
+			[]string{
+				"*ast.BeginCommand 0-6 :begin",
+				"*ast.Query 7-17 CREATE (n)",
+				"*ast.CommitCommand 19-26 :commit",
+				"*ast.UseCommand 27-37 :use neo4j",
+				"*ast.ParamCommand 38-51 :param {a: 1}",
+				"*ast.Command 53-64 :sysinfo -v",
+			},
+		},
+		{
+			"RETURN 1;;  RETURN 2;",
+			[]string{"*ast.Query 0-8 RETURN 1", "*ast.Query 12-20 RETURN 2"},
+		},
+		{
+			// Only a colon at the start of a statement starts a command;
+			// this is the label predicate n:begin.
+			"RETURN n\n:begin",
+			[]string{"*ast.Query 0-15 RETURN n\n:begin"},
+		},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		s, err := ParseScript(tt.script)
+		if err != nil {
+			t.Errorf("ParseScript(%q): %v", tt.script, err)
+			continue
+		}
+		var got []string
+		for _, stmt := range s.Statements {
+			sp := stmt.Span()
+			got = append(got, fmt.Sprintf("%T %d-%d %s", stmt, sp.Start.Offset, sp.End.Offset, stmt.Text()))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseScript(%q) =\n%q\nwant\n%q", tt.script, got, tt.want)
+		}
+	}
+
+	s, _ := ParseScript(":param name => 'Ann'\n:param age: 42\n:use system\n:SysInfo -v")
+	commands := []ast.Statement{
+		&ast.ParamCommand{Name: "name", Value: "'Ann'"},
+		&ast.ParamCommand{Name: "age", Value: "42"},
+		&ast.UseCommand{Database: "system"},
+		&ast.Command{Name: "sysinfo", Args: "-v"},
+	}
+	for i, want := range commands {
+		if i >= len(s.Statements) || !ast.Equal(s.Statements[i], want, ast.IgnoreSpans) {
+			t.Errorf("command %d = %#v; want %#v", i, s.Statements[i], want)
+		}
+	}
+
+	// Errors are at their place in the whole script.
+	_, err := ParseScript("RETURN 1;\n\nRETRUN 2")
+	errs, ok := err.(ErrorList)
+	if !ok || errs[0].Line != 3 || errs[0].Column != 1 || errs[0].Offset != 11 {
+		t.Errorf("ParseScript error = %v; want one at 3:1, offset 11", err)
+	}
+}
+
+// A clause with an error becomes a BadClause, and the clauses after it
+// are parsed as usual.
+func TestParseRecovery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string // the type and text of each clause
+	}{
+		{
+			"MATCH (n) RETRUN n WITH n RETURN n",
+			[]string{"*ast.Match MATCH (n)", "*ast.BadClause RETRUN n", "*ast.With WITH n", "*ast.Return RETURN n"},
+		},
+		{
+			"MATCH (n WHERE n.x RETURN n",
+			[]string{"*ast.BadClause MATCH (n WHERE n.x", "*ast.Return RETURN n"},
+		},
+		{
+			"CREATE (n {a: }) SET n.x = 1 RETURN n",
+			[]string{"*ast.BadClause CREATE (n {a: })", "*ast.Set SET n.x = 1", "*ast.Return RETURN n"},
+		},
+		{
+			"MATCH (n) RETURN n UNION RETRUN 1",
+			[]string{"*ast.Match MATCH (n)", "*ast.Return RETURN n", "*ast.Union UNION", "*ast.BadClause RETRUN 1"},
+		},
+	}
+	for _, tt := range tests {
+		q, err := Parse(tt.query)
+		if err == nil {
+			t.Errorf("Parse(%q): no error", tt.query)
+		}
+		var got []string
+		for _, c := range q.Clauses {
+			got = append(got, fmt.Sprintf("%T %s", c, c.Text()))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) =\n%q\nwant\n%q", tt.query, got, tt.want)
+		}
+	}
+
+	// An error in the condition of a MATCH only spoils the condition.
+	q, _ := Parse("MATCH (n) WHERE n.x = RETURN n")
+	m, ok := q.Clauses[0].(*ast.Match)
+	if !ok {
+		t.Fatalf("got %T; want *ast.Match", q.Clauses[0])
+	}
+	bad, ok := m.Where.(*ast.BadExpr)
+	if !ok || bad.Text() != "n.x =" {
+		t.Errorf("WHERE = %#v; want a BadExpr for %q", m.Where, "n.x =")
+	}
+	if len(q.Clauses) != 2 {
+		t.Errorf("got %d clauses; want the MATCH and the RETURN", len(q.Clauses))
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		text string
+		err  ParseError
+		want string
+	}{
+		{
+			"MATCH (n) RETRUN n",
+			ParseError{Line: 1, Column: 11, Offset: 10, Token: "RETRUN", Msg: `unexpected "RETRUN"`, Hint: "did you mean RETURN?"},
+			"1:11: unexpected \"RETRUN\"\n" +
+				"  |\n" +
+				"1 | MATCH (n) RETRUN n\n" +
+				"  |           ^^^^^^\n" +
+				"  = did you mean RETURN?\n",
+		},
+		{
+			// Tabs are kept, and wide characters take two columns.
+			"MATCH\t(n {name: '日本'}) X",
+			ParseError{Line: 1, Column: 24, Offset: 27, Token: "X", Msg: `unexpected "X"`},
+			"1:24: unexpected \"X\"\n" +
+				"  |\n" +
+				"1 | MATCH\t(n {name: '日本'}) X\n" +
+				"  |      \t                   ^\n",
+		},
+		{
+			// Only the line of the error is shown, without its "\r", and
+			// a missing token gets one caret.
+			"MATCH (n)\r\n\n\n\n\n\n\n\n\nRETURN\r\n",
+			ParseError{Line: 10, Column: 7, Offset: 25, Msg: "unexpected end of input"},
+			"10:7: unexpected end of input\n" +
+				"   |\n" +
+				"10 | RETURN\n" +
+				"   |       ^\n",
+		},
+		{
+			// A token running over the end of the line is underlined up to it.
+			"RETURN 'a\nb'",
+			ParseError{Line: 1, Column: 8, Offset: 7, Token: "'a\nb'", Msg: "unterminated string"},
+			"1:8: unterminated string\n" +
+				"  |\n" +
+				"1 | RETURN 'a\n" +
+				"  |        ^^\n",
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.err.Render(tt.text); got != tt.want {
+			t.Errorf("Render(%q) =\n%s\nwant\n%s", tt.text, got, tt.want)
+		}
+	}
+
+	const query = "MATCH (n) xyzzy n"
+	_, err := Parse(query)
+	want := "1:11: unexpected \"xyzzy\", expected one of ',', WHERE, OPTIONAL, MATCH, UNWIND, CALL, MERGE, CREATE, SET, DETACH, DELETE, REMOVE, WITH or RETURN\n" +
+		"  |\n" +
+		"1 | MATCH (n) xyzzy n\n" +
+		"  |           ^^^^^\n" +
+		"\n" +
+		"1:18: unexpected end of input, expected one of OPTIONAL, MATCH, UNWIND, CALL, MERGE, CREATE, SET, DETACH, DELETE, REMOVE, WITH or RETURN\n" +
+		"  |\n" +
+		"1 | MATCH (n) xyzzy n\n" +
+		"  |                  ^\n"
+	if got := err.(ErrorList).Render(query); got != want {
+		t.Errorf("ErrorList.Render =\n%s\nwant\n%s", got, want)
+	}
+}
+
+// Misspelt keywords get a "did you mean" hint, preferring the keywords that
+// were expected.
+func TestKeywordHints(t *testing.T) {
+	tests := []struct {
+		query, hint string
+	}{
+		{"MACTH (n) RETURN n", "did you mean MATCH?"},
+		{"match (n) retrun n", "did you mean RETURN?"},
+		{"MATCH (n) RETURN n ORDR BY n", "did you mean ORDER?"},
+		{"MATCH (n) RETURN n LIMT 1", "did you mean LIMIT?"},
+		{"MATCH (n) WHRE n.x RETURN n", "did you mean WHERE?"},
+		{"OPTINAL MATCH (n) RETURN n", "did you mean OPTIONAL?"},
+		{"MATCH (n) xyzzy n", ""},
+		{"MATCH (n) RETURN n RETURN n", ""},
+		{"RETURN 1 +", ""},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.query)
+		errs, ok := err.(ErrorList)
+		if !ok {
+			t.Errorf("Parse(%q) error = %v; want an ErrorList", tt.query, err)
+			continue
+		}
+		if got := errs[0].Hint; got != tt.hint {
+			t.Errorf("Parse(%q) hint = %q; want %q", tt.query, got, tt.hint)
+		}
+	}
+}
+
+// Spans count columns in runes and in UTF-16 code units, which differ for
+// characters outside the Basic Multilingual Plane, like emoji.
+func TestSpans(t *testing.T) {
+	pos := func(offset, line, column, utf16Column int) ast.Position {
+		return ast.Position{Offset: offset, Line: line, Column: column, UTF16Column: utf16Column}
+	}
+	tests := []struct {
+		query string
+		text  string // of the last node with this text
+		want  ast.Span
+	}{
+		{"RETURN 'x', e", "e", ast.Span{Start: pos(12, 1, 13, 13), End: pos(13, 1, 14, 14)}},
+		{"RETURN '😀', é", "é", ast.Span{Start: pos(15, 1, 13, 14), End: pos(17, 1, 14, 15)}},
+		{"MATCH (n)\nWHERE n.x = '日本😀' RETURN n", "'日本😀'", ast.Span{Start: pos(22, 2, 13, 13), End: pos(34, 2, 18, 19)}},
+		{"MATCH (n)\nWHERE n.x = '日本😀' RETURN n", "n", ast.Span{Start: pos(42, 2, 26, 27), End: pos(43, 2, 27, 28)}},
+		{"MATCH (n)\nWHERE n.x = '日本😀' RETURN n", "MATCH (n)\nWHERE n.x = '日本😀'", ast.Span{Start: pos(0, 1, 1, 1), End: pos(34, 2, 18, 19)}},
+	}
+	for _, tt := range tests {
+		q, err := Parse(tt.query)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.query, err)
+			continue
+		}
+		var found ast.Node
+		ast.Inspect(q, func(n ast.Node) bool {
+			if n != nil && n.Text() == tt.text {
+				found = n
+			}
+			return true
+		})
+		if found == nil {
+			t.Errorf("%q: no node for %q", tt.query, tt.text)
+		} else if got := found.Span(); got != tt.want {
+			t.Errorf("%q: span of %q = %+v; want %+v", tt.query, tt.text, got, tt.want)
+		}
+	}
+
+	// Errors have both columns too.
+	_, err := Parse("RETURN '😀' +")
+	if e := err.(ErrorList)[0]; e.Column != 13 || e.UTF16Column != 14 || e.Offset != 15 {
+		t.Errorf("error at %d:%d (UTF-16 %d), offset %d; want 1:13 (UTF-16 14), offset 15", e.Line, e.Column, e.UTF16Column, e.Offset)
+	}
+
+	// Every rune, including a byte that is not UTF-8, is a column.
+	text := "RETURN 'é😀'\n'\xff日', x\n\n😀😀 x"
+	src := newSource(text)
+	want := pos(0, 1, 1, 1)
+	for offset, r := range text {
+		want.Offset = offset
+		if got := src.pos(offset); got != want {
+			t.Errorf("%q: pos(%d) = %+v; want %+v", text, offset, got, want)
+		}
+		switch {
+		case r == '\n':
+			want = pos(0, want.Line+1, 1, 1)
+		case r >= 0x10000:
+			want = pos(0, want.Line, want.Column+1, want.UTF16Column+2)
+		default:
+			want = pos(0, want.Line, want.Column+1, want.UTF16Column+1)
+		}
+	}
+	if got, want := src.pos(len(text)), pos(len(text), 4, 5, 7); got != want {
+		t.Errorf("%q: pos at the end = %+v; want %+v", text, got, want)
+	}
+}
+
+// A concrete syntax tree prints back to exactly the text it was parsed
+// from, even when that has errors.
+func TestParseCSTRoundTrip(t *testing.T) {
+	texts := []string{
+		"",
+		"  MATCH (n) // trailing\r\n\tRETURN n ;  /* end */ ",
+		"MATCH (n RETURN n",
+		":param x => 1\nRETURN $x;\n:begin\n",
+		"RETURN 'unterminated",
+	}
+	for _, q := range readCorpus(t) {
+		texts = append(texts, q.text)
+	}
+	for _, q := range readInvalid(t) {
+		texts = append(texts, q.text)
+	}
+	for _, text := range texts {
+		if tree, _ := ParseCST(text); tree.String() != text {
+			t.Errorf("ParseCST(%q).String() = %q", text, tree.String())
+		}
+		if tree, _ := ParseScriptCST(text); tree.String() != text {
+			t.Errorf("ParseScriptCST(%q).String() = %q", text, tree.String())
+		}
+	}
+}
+
+// Comments are attached to the nodes next to them.
+func TestParseCSTComments(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string // the type and text of each node with comments, and its comments
+	}{
+		{
+			"// find people\nMATCH (n:Person) /* all */\nWHERE n.age > 30 // adults\nRETURN n.name, // the name\n  n.age\n// done\n",
+			[]string{
+				"*ast.Query: // done (trailing)",
+				"*ast.Match: // find people",
+				"*ast.Match: // adults (trailing)",
+				"*ast.Pattern (n:Person): /* all */ (trailing)",
+				"*ast.ProjectionItem n.name: // the name (trailing)",
+			},
+		},
+		{
+			"RETURN /* a */ 1 /* b */, 2",
+			[]string{
+				"*ast.Projection 1 /* b */, 2: /* a */",
+				"*ast.ProjectionItem 1: /* b */ (trailing)",
+			},
+		},
+		{"  RETURN 1 ;  // x\n", []string{"*ast.Return RETURN 1: // x (trailing)"}},
+	}
+	for _, tt := range tests {
+		tree, err := ParseCST(tt.query)
+		if err != nil {
+			t.Errorf("ParseCST(%q): %v", tt.query, err)
+			continue
+		}
+		var got []string
+		ast.Inspect(tree.AST(), func(n ast.Node) bool {
+			if n == nil {
+				return false
+			}
+			for _, c := range nodeInfo(n).Comments {
+				desc := fmt.Sprintf("%T", n)
+				if !strings.Contains(n.Text(), "\n") {
+					desc += " " + n.Text()
+				}
+				desc += ": " + c.Raw
+				if c.Trailing {
+					desc += " (trailing)"
+				}
+				got = append(got, desc)
+			}
+			return true
+		})
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseCST(%q) comments =\n%q\nwant\n%q", tt.query, got, tt.want)
+		}
+	}
+
+	// Parse leaves comments out.
+	q, _ := Parse("RETURN 1 // one")
+	if c := q.Clauses[0].(*ast.Return).Comments; c != nil {
+		t.Errorf("Parse kept comments %v", c)
+	}
+}
+
+func TestParseContext(t *testing.T) {
+	tests := []struct {
+		query string
+		opts  *Options
+		want  error
+	}{
+		{"MATCH (n) RETURN n", nil, nil},
+		{"MATCH (n) RETURN n", &Options{MaxBytes: 18, MaxTokens: 6, MaxDepth: 1}, nil},
+		{"MATCH (n) RETURN n", &Options{MaxBytes: 17}, &SizeLimitError{Size: 18, Limit: 17}},
+		{"MATCH (n) RETURN n // comments do not count", &Options{MaxTokens: 5}, &TokenLimitError{Limit: 5}},
+		{
+			"RETURN [1, [2, {a: (3)}]]",
+			&Options{MaxDepth: 3},
+			&DepthLimitError{Limit: 3, Pos: ast.Position{Offset: 19, Line: 1, Column: 20, UTF16Column: 20}},
+		},
+		{"RETURN CASE WHEN [1] THEN 2 END", &Options{MaxDepth: 1}, &DepthLimitError{Limit: 1, Pos: ast.Position{Offset: 17, Line: 1, Column: 18, UTF16Column: 18}}},
+		{"RETURN [1] + [2] + [3]", &Options{MaxDepth: 1}, nil},
+
+		// The depth is counted where the parser recurses, which it also
+		// does for NOT and signs.
+		{
+			"RETURN " + strings.Repeat("NOT\n", 2e6) + "true",
+			&Options{MaxDepth: 64},
+			&DepthLimitError{Limit: 64, Pos: ast.Position{Offset: 263, Line: 65, Column: 1, UTF16Column: 1}},
+		},
+		{"RETURN " + strings.Repeat("- ", 100) + "1", &Options{MaxDepth: 10}, &DepthLimitError{Limit: 10, Pos: ast.Position{Offset: 27, Line: 1, Column: 28, UTF16Column: 28}}},
+		{"RETURN " + strings.Repeat("(", 2000) + "1" + strings.Repeat(")", 2000), nil, &DepthLimitError{Limit: DefaultMaxDepth, Pos: ast.Position{Offset: 1007, Line: 1, Column: 1008, UTF16Column: 1008}}},
+	}
+	for _, tt := range tests {
+		q, err := ParseContext(context.Background(), tt.query, tt.opts)
+		if !reflect.DeepEqual(err, tt.want) {
+			t.Errorf("ParseContext(%q, %+v) error = %#v; want %#v", tt.query, tt.opts, err, tt.want)
+		}
+		if (q == nil) != (tt.want != nil) {
+			t.Errorf("ParseContext(%q, %+v) query = %v", tt.query, tt.opts, q)
+		}
+	}
+
+	// Syntax errors are reported as by Parse.
+	if _, err := ParseContext(context.Background(), "MATCH (n RETURN n", nil); !reflect.DeepEqual(err, mustErr(Parse("MATCH (n RETURN n"))) {
+		t.Errorf("ParseContext of a syntax error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if q, err := ParseContext(ctx, "RETURN 1", nil); q != nil || err != context.Canceled {
+		t.Errorf("ParseContext with a cancelled context = %v, %v", q, err)
+	}
+
+	// A context done while the tokens are counted, or while the query is
+	// parsed, stops the parse.
+	long := "RETURN " + strings.Repeat("1 + ", 5000) + "1"
+	for _, n := range []int{3, 120} {
+		ctx := &countdownContext{Context: context.Background(), n: n}
+		if q, err := ParseContext(ctx, long, &Options{MaxTokens: 1 << 20}); q != nil || err != context.Canceled {
+			t.Errorf("ParseContext with a context done after %d checks = %v, %v", n, q, err)
+		}
+	}
+}
+
+func mustErr(_ *ast.Query, err error) error { return err }
+
+// countdownContext is a context that is done after Err has been called n
+// times.
+type countdownContext struct {
+	context.Context
+	n    int
+	done chan struct{}
+}
+
+func (c *countdownContext) Done() <-chan struct{} {
+	if c.done == nil {
+		c.done = make(chan struct{})
+	}
+	return c.done
+}
+
+func (c *countdownContext) Err() error {
+	if c.n--; c.n < 0 {
+		return context.Canceled
+	}
+	return nil
+}
+
+// Parentheses, which may start patterns or expressions, must not make the
+// parser go over the tokens inside them again for each level of nesting:
+// the work must grow linearly with the nesting.
+func TestParseNestingLinear(t *testing.T) {
+	for _, tt := range []struct{ open, close string }{
+		{"(", ")"},
+		{"({a: ", "})"},
+		{"({a: ", "}) - 1"},
+		{"({a: ", "})-->()"},
+		{"[(a {b: ", "})-->() | 1]"},
+		{"[x IN ", "]"},
+		{"any(x IN ", " WHERE true)"},
+	} {
+		steps := make([]int, 2)
+		for i, depth := range []int{20, 40} {
+			q := "RETURN " + strings.Repeat(tt.open, depth) + "1" + strings.Repeat(tt.close, depth)
+			p := newParser(context.Background(), newSource(q))
+			p.query()
+			if len(p.errs) > 0 {
+				t.Fatalf("%s: %v", q, p.errs)
+			}
+			steps[i] = p.steps
+		}
+		if steps[1] > 3*steps[0] {
+			t.Errorf("%s...%s: parsing takes %d steps at depth 20 and %d at depth 40", tt.open, tt.close, steps[0], steps[1])
+		}
+	}
+}
+
+// No input may nest deeply enough to make the parser run out of stack.
+// Statements that go over the limit are kept whole, as a BadClause.
+func TestParseTooDeep(t *testing.T) {
+	for _, tt := range []struct{ open, close string }{
+		{"(", ")"},
+		{"[", "]"},
+		{"NOT ", ""},
+		{"-", ""},
+		{"f(", ")"},
+		{"CASE WHEN ", " THEN 1 END"},
+	} {
+		body := "RETURN " + strings.Repeat(tt.open, 1e5) + "1" + strings.Repeat(tt.close, 1e5)
+		q, err := Parse(body + ";")
+		errs, ok := err.(ErrorList)
+		if !ok || len(errs) != 1 || errs[0].Msg != "query is nested more than 1000 levels deep" {
+			t.Errorf("%s...%s: got error %v", tt.open, tt.close, err)
+			continue
+		}
+		if len(q.Clauses) != 1 || q.Clauses[0].Text() != body {
+			t.Errorf("%s...%s: got %d clauses", tt.open, tt.close, len(q.Clauses))
+		}
+		if _, ok := q.Clauses[0].(*ast.BadClause); !ok {
+			t.Errorf("%s...%s: got %T", tt.open, tt.close, q.Clauses[0])
+		}
+	}
+}
+
+func TestLiterals(t *testing.T) {
+	tests := []struct {
+		expr string
+		want interface{}
+	}{
+		{`42`, int64(42)},
+		{`0x2a`, int64(42)},
+		{`052`, int64(42)},
+		{`-9223372036854775808`, int64(math.MinInt64)},
+		{`-0x8000000000000000`, int64(math.MinInt64)},
+		{`1.5`, 1.5},
+		{`.5e1`, 5.0},
+		{`-1E-3`, -0.001},
+		{`'it\'s'`, "it's"},
+		{`"a\tb\\"`, "a\tb\\"},
+		{`'\u00e9\U0001F600'`, "é😀"},
+		{`'\uD83D\uDE00'`, "😀"},
+		{`'\x'`, `\x`},
+		{`true`, true},
+		{`null`, nil},
+		{`[1, 'a', [false]]`, []interface{}{int64(1), "a", []interface{}{false}}},
+		{`{a: 1, b: {c: -2.5}}`, map[string]interface{}{"a": int64(1), "b": map[string]interface{}{"c": -2.5}}},
+	}
+	for _, tt := range tests {
+		q, _ := Parse("RETURN " + tt.expr)
+		ret, ok := q.Clauses[0].(*ast.Return)
+		if !ok {
+			t.Errorf("%s: got %T", tt.expr, q.Clauses[0])
+			continue
+		}
+		got, err := ast.Value(ret.Projection.Items[0].Expr)
+		if err != nil {
+			t.Errorf("%s: %v", tt.expr, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %#v, want %#v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestLiteralErrors(t *testing.T) {
+	tests := []struct {
+		query, want string
+	}{
+		{`RETURN 9223372036854775808`, "1:8: integer out of range"},
+		{`RETURN 1 - 9223372036854775808`, "1:12: integer out of range"},
+		{`RETURN 0x10000000000000000`, "1:8: integer out of range"},
+		{`RETURN 1e999`, "1:8: number out of range"},
+		{`RETURN 'ab\uD83Dc'`, "1:11: invalid Unicode code point in string"},
+		{`RETURN '\UFFFFFFFF'`, "1:9: invalid Unicode code point in string"},
+		{`RETURN '\x'`, "1:9: invalid escape sequence in string"},
+		{`RETURN [1, $x]`, ""},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.query)
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tt.want {
+			t.Errorf("%s: got error %q, want %q", tt.query, got, tt.want)
+		}
+	}
+
+	q, _ := Parse(`RETURN [1, $x]`)
+	_, err := ast.Value(q.Clauses[0].(*ast.Return).Projection.Items[0].Expr)
+	if want := "ast.Value: 1:12: *ast.Parameter is not a literal"; err == nil || err.Error() != want {
+		t.Errorf("got error %v, want %q", err, want)
+	}
+}
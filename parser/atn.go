@@ -0,0 +1,10 @@
+package parser
+
+// This file is not generated, and must be kept when the parser is.
+
+// SerializedATN returns the ATN of the parser, in the form ANTLR serializes
+// it, for tools that walk the grammar themselves. The ANTLR runtime does
+// not export its own copy's transitions.
+func SerializedATN() []uint16 {
+	return parserATN
+}
@@ -0,0 +1,371 @@
+package cypher
+
+import (
+	"reflect"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/a-poor/cypher/ast"
+)
+
+// Style says how Pretty lays out Cypher. The zero Style lays it out as
+// Format does.
+type Style struct {
+	// LowerKeywords writes keywords, such as MATCH and WHERE, in lower
+	// case rather than in upper case.
+	LowerKeywords bool
+
+	// Indent is the number of spaces in a level of indentation. Zero means
+	// two.
+	Indent int
+
+	// MaxWidth is how wide a line may be. A clause that is wider is broken
+	// over several lines: each path of its pattern and each item of its
+	// list has a line of its own, paths are broken before relationships,
+	// clauses such as WHERE and ORDER BY start lines, and so does each
+	// operand of a chain of ANDs or ORs after WHERE. Other expressions are
+	// not broken, so some lines may still be wider. Zero means no limit,
+	// with each clause on a single line.
+	MaxWidth int
+
+	// Quote is the quote string literals are written in.
+	Quote Quote
+
+	// MapSpaces puts spaces inside the braces of maps, as in
+	// "{ name: 'Ann' }".
+	MapSpaces bool
+
+	// ArrowSpaces puts spaces around the relationships of paths, as in
+	// "(a) -[:KNOWS]-> (b)".
+	ArrowSpaces bool
+}
+
+// Quote is a quote for string literals.
+type Quote int
+
+const (
+	QuoteAsWritten Quote = iota // the quote the literal was written in
+	QuoteSingle                 // '...'
+	QuoteDouble                 // "..."
+)
+
+// Pretty prints a node as Cypher in the given style, which may be nil for
+// the zero Style. Unlike Format, it keeps the comments of the nodes, as
+// filled in by ParseCST: those before a node are written before it, and
+// those after it after it, moved past any commas and closing parentheses
+// and braces that follow. A "//" comment ends its line.
+//
+// Parsing the result gives back the same tree as Format does, apart from
+// spans and comments, and the Literal fields of string literals written
+// in another quote.
+func Pretty(node ast.Node, style *Style) string {
+	p := &printer{comments: true}
+	if style != nil {
+		p.style = *style
+	}
+	p.node(node)
+	return p.String()
+}
+
+// printer prints nodes as Cypher.
+type printer struct {
+	buf      []byte
+	style    Style
+	comments bool           // whether to print comments
+	col      int            // column the first line starts at
+	pending  []*ast.Comment // comments to write after what comes next
+}
+
+// String returns what has been written, with any comments still pending.
+func (p *printer) String() string {
+	p.flush()
+	return string(p.buf)
+}
+
+// write writes s, first writing any pending comments. Commas, closing
+// parentheses and braces, and spaces at the start of s are written before
+// them.
+func (p *printer) write(s string) {
+	if len(p.pending) > 0 {
+		i := 0
+		for i < len(s) && strings.IndexByte(",;)} ", s[i]) >= 0 {
+			i++
+		}
+		p.buf = append(p.buf, s[:i]...)
+		if s = s[i:]; s == "" {
+			return
+		}
+		switch {
+		case !p.flush():
+			p.buf = append(p.buf, ' ')
+		case s[0] != '\n':
+			p.newline(1)
+		}
+	}
+	p.buf = append(p.buf, s...)
+}
+
+// keyword writes keywords in the case of the style.
+func (p *printer) keyword(s string) {
+	if p.style.LowerKeywords {
+		s = strings.ToLower(s)
+	}
+	p.write(s)
+}
+
+// newline starts a new line, indented by the given number of levels more
+// than the lines of the top-level clauses.
+func (p *printer) newline(level int) {
+	p.write("\n")
+	p.trimSpace()
+	p.buf = append(p.buf, strings.Repeat(" ", level*p.indent())...)
+}
+
+// space writes a space, or starts a new line if broken is set.
+func (p *printer) space(broken bool, level int) {
+	if broken {
+		p.newline(level)
+	} else {
+		p.write(" ")
+	}
+}
+
+func (p *printer) indent() int {
+	if p.style.Indent <= 0 {
+		return 2
+	}
+	return p.style.Indent
+}
+
+// trimSpace removes spaces from the end of what has been written, and
+// from before the last line break.
+func (p *printer) trimSpace() {
+	nl := len(p.buf) > 0 && p.buf[len(p.buf)-1] == '\n'
+	if nl {
+		p.buf = p.buf[:len(p.buf)-1]
+	}
+	for len(p.buf) > 0 && p.buf[len(p.buf)-1] == ' ' {
+		p.buf = p.buf[:len(p.buf)-1]
+	}
+	if nl {
+		p.buf = append(p.buf, '\n')
+	}
+}
+
+// column returns the column the next character will be written at,
+// counting characters from zero.
+func (p *printer) column() int {
+	if i := strings.LastIndexByte(string(p.buf), '\n'); i >= 0 {
+		return utf8.RuneCount(p.buf[i+1:])
+	}
+	return p.col + utf8.RuneCount(p.buf)
+}
+
+// lineStart reports whether nothing but indentation has been written on
+// the current line.
+func (p *printer) lineStart() bool {
+	i := strings.LastIndexByte(string(p.buf), '\n')
+	if i < 0 && p.col > 0 {
+		return false
+	}
+	return strings.Trim(string(p.buf[i+1:]), " ") == ""
+}
+
+// lineIndent returns the number of spaces the current line starts with.
+func (p *printer) lineIndent() int {
+	line := p.buf[strings.LastIndexByte(string(p.buf), '\n')+1:]
+	n := 0
+	for n < len(line) && line[n] == ' ' {
+		n++
+	}
+	return n
+}
+
+// group writes what f writes with broken false, if that fits on the
+// current line, and what it writes with broken true otherwise.
+func (p *printer) group(f func(p *printer, broken bool)) {
+	if p.style.MaxWidth <= 0 {
+		f(p, false)
+		return
+	}
+	if p.flush() {
+		p.newline(1)
+	}
+	q := &printer{style: p.style, comments: p.comments, col: p.column()}
+	f(q, false)
+	if !strings.Contains(string(q.buf), "\n") && q.column() <= p.style.MaxWidth {
+		p.buf = append(p.buf, q.buf...)
+		p.pending = append(p.pending, q.pending...)
+		return
+	}
+	f(p, true)
+}
+
+// open writes the comments before a node.
+func (p *printer) open(n ast.Node) {
+	for _, c := range p.nodeComments(n) {
+		if c.Trailing {
+			continue
+		}
+		start := p.lineStart()
+		p.write(c.Raw)
+		switch {
+		case !isLineComment(c):
+			p.write(" ")
+		case start:
+			// Keep the indentation of the line the comment is on.
+			indent := p.lineIndent()
+			p.write("\n")
+			p.buf = append(p.buf, strings.Repeat(" ", indent)...)
+		default:
+			p.newline(1)
+		}
+	}
+}
+
+// close adds the comments after a node to those pending.
+func (p *printer) close(n ast.Node) {
+	for _, c := range p.nodeComments(n) {
+		if c.Trailing {
+			p.pending = append(p.pending, c)
+		}
+	}
+}
+
+// flush writes the pending comments, reporting whether the last of them
+// is a "//" comment, which must be followed by a line break.
+func (p *printer) flush() bool {
+	if len(p.pending) == 0 {
+		return false
+	}
+	p.trimSpace()
+	indent := p.lineIndent()
+	line := false
+	for _, c := range p.pending {
+		if line {
+			p.buf = append(p.buf, '\n')
+			p.buf = append(p.buf, strings.Repeat(" ", indent)...)
+		} else if len(p.buf) > 0 && p.buf[len(p.buf)-1] != '\n' {
+			p.buf = append(p.buf, ' ')
+		}
+		p.buf = append(p.buf, c.Raw...)
+		line = isLineComment(c)
+	}
+	p.pending = nil
+	return line
+}
+
+func (p *printer) nodeComments(n ast.Node) []*ast.Comment {
+	if !p.comments || n == nil || reflect.ValueOf(n).IsNil() {
+		return nil
+	}
+	return nodeInfo(n).Comments
+}
+
+func isLineComment(c *ast.Comment) bool {
+	return strings.HasPrefix(c.Raw, "//")
+}
+
+// PrettyScript prints a script in the given style, as Pretty does, keeping
+// its comments. Statements are separated by blank lines, and end with
+// semicolons, except that a last one written without a semicolon stays
+// without one. Commands, such as ":param", are kept as they are, together
+// with the comments before them. The result ends with a line break.
+//
+// If the script has syntax errors, it is returned unchanged, with an
+// ErrorList as for ParseScript.
+func PrettyScript(script string, style *Style) (string, error) {
+	s, err := ParseScript(script)
+	if err != nil {
+		return script, err
+	}
+	if len(s.Statements) == 0 {
+		return script, nil
+	}
+
+	var sb strings.Builder
+	cut := 0
+	for i, stmt := range s.Statements {
+		// Each statement takes the text from the end of the one before,
+		// so that comments between them are kept, up to its terminator,
+		// or to the end of the script for the last.
+		end := stmt.Span().End.Offset
+		semicolon := false
+		if tok := nextToken(script, end); tok.kind == tokSemicolon {
+			end, semicolon = tok.end, true
+		}
+		last := i == len(s.Statements)-1
+		q, isQuery := stmt.(*ast.Query)
+		switch {
+		case last:
+			end = len(script)
+		case !isQuery:
+			// A command runs to the end of its line.
+			if j := strings.IndexByte(script[end:], '\n'); j >= 0 {
+				end += j
+			}
+		case semicolon:
+			// Comments after the semicolon on its line stay with the
+			// statement.
+			end = triviaEnd(script, end)
+		}
+		text := script[cut:end]
+		cut = end
+
+		if i > 0 {
+			_, prevQuery := s.Statements[i-1].(*ast.Query)
+			if isQuery && prevQuery {
+				sb.WriteString("\n")
+			}
+			sb.WriteString("\n")
+		}
+		if !isQuery {
+			sb.WriteString(strings.TrimSpace(text))
+			continue
+		}
+		tree, err := ParseCST(text)
+		if err != nil {
+			// Not expected, as the statement has already parsed.
+			return script, err
+		}
+		q = tree.AST().(*ast.Query)
+		term := ";"
+		if last && !semicolon {
+			term = ""
+		}
+		p := &printer{comments: true}
+		if style != nil {
+			p.style = *style
+		}
+		p.query(q, term)
+		sb.WriteString(p.String())
+	}
+	sb.WriteString("\n")
+	return sb.String(), nil
+}
+
+// triviaEnd returns the end of the comments that follow offset in text on
+// the same line.
+func triviaEnd(text string, offset int) int {
+	end := offset
+	l := newLexer(text, offset, len(text), nil)
+	for t := l.next(); t.kind.isTrivia(); t = l.next() {
+		if t.kind != tokSpace {
+			end = t.end
+		} else if strings.Contains(text[t.start:t.end], "\n") {
+			break
+		}
+	}
+	return end
+}
+
+// nextToken returns the first token after offset in text that is not
+// whitespace or a comment.
+func nextToken(text string, offset int) token {
+	l := newLexer(text, offset, len(text), nil)
+	for {
+		if t := l.next(); !t.kind.isTrivia() {
+			return t
+		}
+	}
+}
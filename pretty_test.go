@@ -0,0 +1,170 @@
+package cypher
+
+import (
+	"testing"
+
+	"github.com/a-poor/cypher/ast"
+)
+
+func TestPretty(t *testing.T) {
+	tests := []struct {
+		in    string
+		style Style
+		want  string
+	}{
+		{
+			"match (a)-[:KNOWS]->(b {name: 'Ann'}) return a",
+			Style{LowerKeywords: true, MapSpaces: true, ArrowSpaces: true},
+			"match (a) -[:KNOWS]-> (b { name: 'Ann' })\nreturn a",
+		},
+		{
+			`RETURN 'it\'s', "say \"hi\"", {}`,
+			Style{Quote: QuoteDouble, MapSpaces: true},
+			`RETURN "it's", "say \"hi\"", {}`,
+		},
+		{
+			`RETURN 'it\'s', "say \"hi\""`,
+			Style{Quote: QuoteSingle},
+			`RETURN 'it\'s', 'say "hi"'`,
+		},
+		{
+			"MATCH (a:Person)-[:KNOWS]->(b:Person)-[:LIKES]->(c:Movie) WHERE a.age > 30 AND c.year < 2000 AND c.title <> 'Heat' RETURN a.name AS name, b.name AS friend, c.title ORDER BY name LIMIT 10",
+			Style{MaxWidth: 40},
+			"MATCH (a:Person)\n" +
+				"  -[:KNOWS]->(b:Person)\n" +
+				"  -[:LIKES]->(c:Movie)\n" +
+				"WHERE a.age > 30\n" +
+				"  AND c.year < 2000\n" +
+				"  AND c.title <> 'Heat'\n" +
+				"RETURN\n" +
+				"  a.name AS name,\n" +
+				"  b.name AS friend,\n" +
+				"  c.title\n" +
+				"ORDER BY name\n" +
+				"LIMIT 10",
+		},
+		{
+			"MATCH (a:Person {name: 'Ann'}), (b:Person {name: 'Ben'}) MERGE (a)-[:KNOWS]->(b) ON CREATE SET a.x = 1",
+			Style{MaxWidth: 30, Indent: 4},
+			"MATCH\n" +
+				"    (a:Person {name: 'Ann'}),\n" +
+				"    (b:Person {name: 'Ben'})\n" +
+				"MERGE (a)-[:KNOWS]->(b)\n" +
+				"    ON CREATE SET a.x = 1",
+		},
+		{
+			"// Find active users\nMATCH (u:User) /* only active */ WHERE u.active\nRETURN u, // the user\n  u.name // and the name\n// done",
+			Style{},
+			"// Find active users\nMATCH (u:User) /* only active */ WHERE u.active\nRETURN u, // the user\n  u.name // and the name\n// done",
+		},
+		{
+			"MATCH (n) WHERE n.x = 1 // one\n AND n.y = 2 RETURN n",
+			Style{MaxWidth: 80},
+			"MATCH (n)\nWHERE n.x = 1 // one\n  AND n.y = 2\nRETURN n",
+		},
+	}
+	for _, tt := range tests {
+		tree, err := ParseCST(tt.in)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.in, err)
+		}
+		if got := Pretty(tree.AST(), &tt.style); got != tt.want {
+			t.Errorf("Pretty(%q, %+v) =\n%s\nwant\n%s", tt.in, tt.style, got, tt.want)
+		}
+	}
+}
+
+func TestPrettyScript(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"match (n) return n", "MATCH (n)\nRETURN n\n"},
+		{
+			"// setup\n:param x => 1\nmatch (n) return n;  create (m) // new\n;\n:begin\nreturn $x;\n",
+			"// setup\n:param x => 1\nMATCH (n)\nRETURN n;\n\nCREATE (m); // new\n:begin\nRETURN $x;\n",
+		},
+	}
+	for _, tt := range tests {
+		got, err := PrettyScript(tt.in, nil)
+		if err != nil || got != tt.want {
+			t.Errorf("PrettyScript(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+		if again, _ := PrettyScript(got, nil); again != got {
+			t.Errorf("PrettyScript(%q) is not stable: %q", got, again)
+		}
+	}
+	if got, err := PrettyScript("MATCH (n RETURN n", nil); err == nil || got != "MATCH (n RETURN n" {
+		t.Errorf("PrettyScript of a syntax error = %q, %v", got, err)
+	}
+}
+
+var prettyStyles = []*Style{
+	nil,
+	{MaxWidth: 40},
+	{MaxWidth: 1},
+	{MaxWidth: 20, LowerKeywords: true, Indent: 4, MapSpaces: true, ArrowSpaces: true},
+}
+
+// Pretty must keep the tree and its comments, and printing what it prints
+// must not change it.
+func TestPrettyRoundTrip(t *testing.T) {
+	for _, q := range readCorpus(t) {
+		tree, err := ParseCST(q.text)
+		if err != nil {
+			t.Errorf("%s: %v", q.name, err)
+			continue
+		}
+		for _, style := range prettyStyles {
+			checkPretty(t, q.name, tree.AST(), style)
+		}
+	}
+}
+
+// Comments anywhere must be kept, and stay where they are printed.
+func TestPrettyComments(t *testing.T) {
+	for _, q := range readCorpus(t) {
+		l := newLexer(q.text, 0, len(q.text), nil)
+		for tok := l.next(); tok.kind != tokEOF; tok = l.next() {
+			for _, c := range []string{" /* c */ ", " // c\n"} {
+				text := q.text[:tok.end] + c + q.text[tok.end:]
+				tree, err := ParseCST(text)
+				if err != nil {
+					continue
+				}
+				checkPretty(t, q.name, tree.AST(), &Style{MaxWidth: 30})
+			}
+		}
+	}
+}
+
+func checkPretty(t *testing.T, name string, want ast.Node, style *Style) {
+	t.Helper()
+	text := Pretty(want, style)
+	tree, err := ParseCST(text)
+	if err != nil {
+		t.Errorf("%s: pretty query does not parse: %v\n%s", name, err, text)
+		return
+	}
+	got := tree.AST()
+	if !ast.Equal(got, want, ast.IgnoreSpans|ast.IgnoreComments) {
+		t.Errorf("%s: pretty query parses differently:\n%s", name, text)
+	}
+	if n, m := countComments(got), countComments(want); n != m {
+		t.Errorf("%s: pretty query has %d comments, want %d:\n%s", name, n, m, text)
+	}
+	if again := Pretty(got, style); again != text {
+		t.Errorf("%s: pretty printing is not stable:\n%s\n%s", name, text, again)
+	}
+}
+
+func countComments(n ast.Node) int {
+	count := 0
+	ast.Inspect(n, func(n ast.Node) bool {
+		if n != nil {
+			count += len(nodeInfo(n).Comments)
+		}
+		return true
+	})
+	return count
+}
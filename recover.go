@@ -0,0 +1,170 @@
+package cypher
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/a-poor/cypher/ast"
+)
+
+// The parser recovers from syntax errors clause by clause. A clause with an
+// error in it is skipped up to the keyword of the next clause and kept as a
+// BadClause, and parsing carries on from there. Within a MATCH, an error in
+// the condition of its WHERE only spoils the condition, which becomes a
+// BadExpr.
+//
+// Errors inside an EXISTS subquery, or while the parser is speculating,
+// are not recovered from where they happen, but unwind to the clause or
+// expression the subquery or speculation is part of.
+
+// recovering reports whether the parser recovers from errors at this
+// point, rather than leaving it to its caller.
+func (p *parser) recovering() bool {
+	return p.spec == 0 && p.nested == 0
+}
+
+// recoverClause parses a clause with f, turning it into a BadClause if it
+// has errors.
+func (p *parser) recoverClause(f func() ast.Clause) (c ast.Clause) {
+	if !p.recovering() {
+		return f()
+	}
+	start := p.pos
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(bailout); !ok {
+				panic(r)
+			}
+			p.skipClause()
+			if p.pos == start {
+				p.next()
+			}
+			c = p.badClause(start)
+		}
+	}()
+	return f()
+}
+
+// recoverExpr parses an expression with f, turning it into a BadExpr
+// running to the end of the clause if it has errors.
+func (p *parser) recoverExpr(f func() ast.Expr) (e ast.Expr) {
+	if !p.recovering() {
+		return f()
+	}
+	start := p.pos
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(bailout); !ok {
+				panic(r)
+			}
+			p.skipClause()
+			info := p.info(start)
+			if p.pos == start {
+				// The expression is missing altogether. It belongs
+				// right after whatever came before it.
+				off := p.toks[start-1].end
+				info.Loc = p.src.span(off, off)
+			}
+			e = &ast.BadExpr{NodeInfo: info}
+		}
+	}()
+	return f()
+}
+
+// skipClause moves on to the keyword of the next clause, or the end of
+// the statement. Clause keywords inside braces are skipped over, since
+// they belong to a subquery; parentheses and brackets are not counted,
+// since a clause keyword inside them means they were never closed.
+func (p *parser) skipClause() {
+	braces := 0
+	for {
+		switch t := p.tok(); t.kind {
+		case tokEOF:
+			return
+		case tokSemicolon:
+			if braces == 0 {
+				return
+			}
+		case tokLBrace:
+			braces++
+		case tokRBrace:
+			if braces == 0 && p.nested > 0 {
+				return
+			}
+			if braces > 0 {
+				braces--
+			}
+		}
+		if braces == 0 && p.startsClause(p.pos) {
+			return
+		}
+		p.next()
+	}
+}
+
+// startsClause reports whether the token at index i starts a new clause.
+// Clause keywords also appear inside other clauses, as in "ON CREATE SET"
+// or "STARTS WITH", and may be used as property keys and labels.
+func (p *parser) startsClause(i int) bool {
+	prev, prev2 := tokEOF, tokEOF
+	if i > 0 {
+		prev = p.toks[i-1].kind
+	}
+	if i > 1 {
+		prev2 = p.toks[i-2].kind
+	}
+	if prev == tokDot || prev == tokColon {
+		return false
+	}
+	switch p.toks[i].kind {
+	case kwOptional, kwUnwind, kwMerge, kwRemove, kwCall, kwReturn, kwUnion, kwDetach:
+		return true
+	case kwMatch:
+		return prev != kwOptional && prev != kwOn
+	case kwCreate:
+		return prev != kwOn
+	case kwSet:
+		onAction := prev == kwMatch || prev == kwCreate
+		return !onAction || prev2 != kwOn
+	case kwDelete:
+		return prev != kwDetach
+	case kwWith:
+		return prev != kwStarts && prev != kwEnds
+	}
+	return false
+}
+
+func (p *parser) badClause(start int) *ast.BadClause {
+	return &ast.BadClause{NodeInfo: p.info(start)}
+}
+
+// abandon gives up on a statement nested too deeply to parse, keeping all
+// of it but a semicolon at the end as a BadClause, with an error where it
+// went over the limit.
+func (p *parser) abandon(d tooDeep) (*ast.Query, ErrorList) {
+	p.pos = len(p.toks) - 1
+	if p.pos > 0 && p.toks[p.pos-1].kind == tokSemicolon {
+		p.pos--
+	}
+	q := &ast.Query{Clauses: []ast.Clause{p.badClause(0)}, NodeInfo: p.info(0)}
+
+	msg := fmt.Sprintf("query is nested more than %d levels deep", d.err.Limit)
+	p.errs = append(p.errs, p.newError(d.tok.start, d.tok.text, nil, msg))
+	return q, p.errors()
+}
+
+// errors returns the errors found, in the order they appear in the text.
+func (p *parser) errors() ErrorList {
+	errs := p.errs
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Offset < errs[j].Offset })
+
+	// An error the parser recovered from can leave it at a token that
+	// causes another error, which only repeats the first.
+	uniq := errs[:0]
+	for i, e := range errs {
+		if i == 0 || e.Offset != errs[i-1].Offset {
+			uniq = append(uniq, e)
+		}
+	}
+	return uniq
+}
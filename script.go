@@ -0,0 +1,159 @@
+package cypher
+
+import (
+	"context"
+	"strings"
+
+	"github.com/a-poor/cypher/ast"
+)
+
+// ParseScript parses a script of statements separated by semicolons, such as
+// a migration file or a cypher-shell script.
+//
+// Statements starting with a colon, like ":param" or ":begin", are parsed
+// as cypher-shell meta-commands, which run to the end of their line. A
+// colon anywhere else belongs to the statement it is in, so a command
+// after a query must be separated from it by a semicolon. Each statement
+// and command records its range in the script. Syntax errors are reported
+// relative to the whole script.
+//
+// As with Parse, the script is returned even if some of its statements have
+// errors, with each of them parsed as far as possible.
+func ParseScript(script string) (*ast.Script, error) {
+	src := newSource(script)
+	s := &ast.Script{NodeInfo: ast.NodeInfo{
+		Raw: script,
+		Loc: src.span(0, len(script)),
+	}}
+
+	var errs ErrorList
+	for _, r := range splitScript(src) {
+		if r.command {
+			s.Statements = append(s.Statements, parseCommand(src, r.start, r.end))
+			continue
+		}
+		q, qerrs := parse(context.Background(), src.sub(r.start, r.end))
+		errs = append(errs, qerrs...)
+		s.Statements = append(s.Statements, q)
+	}
+	return s, errs.Err()
+}
+
+// scriptRange is the byte range of a single statement or command in a
+// script, without its terminator.
+type scriptRange struct {
+	start, end int
+	command    bool
+}
+
+// splitScript finds the statements and commands in a script. It runs the
+// Cypher lexer over the text, so semicolons inside strings, escaped names
+// and comments do not split statements. Lexer errors are left for the
+// statement parser to report.
+func splitScript(src *source) []scriptRange {
+	var ranges []scriptRange
+	start, end := -1, -1
+	flush := func() {
+		if start >= 0 {
+			ranges = append(ranges, scriptRange{start: start, end: end})
+		}
+		start, end = -1, -1
+	}
+
+	l := newLexer(src.text, 0, len(src.text), nil)
+	for {
+		tok := l.next()
+		if tok.kind == tokEOF {
+			break
+		}
+
+		switch {
+		case tok.kind.isTrivia():
+			continue
+		case tok.kind == tokSemicolon:
+			flush()
+			continue
+		case tok.kind == tokColon && start < 0:
+			// A command runs to the end of its line. Lexing starts
+			// over on the next line, in case the command's arguments
+			// are not valid Cypher tokens.
+			eol := strings.IndexByte(src.text[tok.start:], '\n')
+			if eol < 0 {
+				eol = len(src.text)
+			} else {
+				eol += tok.start
+			}
+			line := strings.TrimRight(src.text[tok.start:eol], " \t\r")
+			line = strings.TrimSuffix(line, ";")
+			ranges = append(ranges, scriptRange{
+				start:   tok.start,
+				end:     tok.start + len(strings.TrimRight(line, " \t")),
+				command: true,
+			})
+			l = newLexer(src.text, eol, len(src.text), nil)
+			continue
+		}
+
+		if start < 0 {
+			start = tok.start
+		}
+		end = tok.end
+	}
+	flush()
+	return ranges
+}
+
+// parseCommand parses the meta-command in the byte range [start, end).
+func parseCommand(src *source, start, end int) ast.Statement {
+	text := src.text[start:end]
+	info := ast.NodeInfo{Raw: text, Loc: src.span(start, end)}
+
+	line := strings.TrimPrefix(text, ":")
+	name := line
+	if i := strings.IndexAny(line, " \t"); i >= 0 {
+		name = line[:i]
+	}
+	args := strings.TrimSpace(line[len(name):])
+	name = strings.ToLower(name)
+
+	switch name {
+	case "param":
+		if cmd := parseParamCommand(args); cmd != nil {
+			cmd.NodeInfo = info
+			return cmd
+		}
+	case "use":
+		if args != "" {
+			return &ast.UseCommand{NodeInfo: info, Database: args}
+		}
+	case "begin":
+		return &ast.BeginCommand{NodeInfo: info}
+	case "commit":
+		return &ast.CommitCommand{NodeInfo: info}
+	case "rollback":
+		return &ast.RollbackCommand{NodeInfo: info}
+	}
+	return &ast.Command{NodeInfo: info, Name: name, Args: args}
+}
+
+// parseParamCommand parses the arguments of a ":param" command, which take
+// one of the forms "name => value", "name: value" or "{map}".
+func parseParamCommand(args string) *ast.ParamCommand {
+	if strings.HasPrefix(args, "{") {
+		return &ast.ParamCommand{Value: args}
+	}
+	sep := strings.Index(args, "=>")
+	width := 2
+	if sep < 0 {
+		sep, width = strings.IndexByte(args, ':'), 1
+	}
+	if sep <= 0 {
+		return nil
+	}
+	name := strings.TrimSpace(args[:sep])
+	value := strings.TrimSpace(args[sep+width:])
+	if value == "" {
+		return nil
+	}
+	return &ast.ParamCommand{Name: name, Value: value}
+}
@@ -0,0 +1,150 @@
+package sema
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Function describes a built-in function of Cypher.
+type Function struct {
+	Name      string // as documented, e.g. "toLower"
+	Signature string // e.g. "toLower(input :: STRING) :: STRING"
+	Doc       string // what the function does, in a sentence
+
+	MinArgs int
+	MaxArgs int // -1 if there is no limit
+
+	Aggregate bool // whether the function aggregates rows, as count does
+}
+
+func (f *Function) arity() string {
+	plural := func(n int) string {
+		if n == 1 {
+			return "1 argument"
+		}
+		return fmt.Sprintf("%d arguments", n)
+	}
+	switch {
+	case f.MaxArgs < 0:
+		return "at least " + plural(f.MinArgs)
+	case f.MinArgs == f.MaxArgs:
+		return plural(f.MinArgs)
+	}
+	return fmt.Sprintf("%d to %d arguments", f.MinArgs, f.MaxArgs)
+}
+
+// LookupFunction returns the built-in function with the given name, which
+// is matched without regard to case, or nil if there is none.
+func LookupFunction(name string) *Function {
+	return functionsByName[strings.ToLower(name)]
+}
+
+// Functions returns the built-in functions, sorted by name.
+func Functions() []*Function {
+	fs := make([]*Function, len(functions))
+	copy(fs, functions)
+	sort.Slice(fs, func(i, j int) bool { return fs[i].Name < fs[j].Name })
+	return fs
+}
+
+var functionsByName = func() map[string]*Function {
+	m := map[string]*Function{}
+	for _, f := range functions {
+		m[strings.ToLower(f.Name)] = f
+	}
+	return m
+}()
+
+var functions = []*Function{
+	// Aggregating functions
+	{Name: "avg", Signature: "avg(input :: NUMBER | DURATION) :: NUMBER | DURATION", Doc: "Returns the average of a set of values.", MinArgs: 1, MaxArgs: 1, Aggregate: true},
+	{Name: "collect", Signature: "collect(input :: ANY) :: LIST", Doc: "Returns a list of the values of a set, leaving out nulls.", MinArgs: 1, MaxArgs: 1, Aggregate: true},
+	{Name: "count", Signature: "count(input :: ANY) :: INTEGER", Doc: "Returns the number of values or rows.", MinArgs: 1, MaxArgs: 1, Aggregate: true},
+	{Name: "max", Signature: "max(input :: ANY) :: ANY", Doc: "Returns the largest of a set of values.", MinArgs: 1, MaxArgs: 1, Aggregate: true},
+	{Name: "min", Signature: "min(input :: ANY) :: ANY", Doc: "Returns the smallest of a set of values.", MinArgs: 1, MaxArgs: 1, Aggregate: true},
+	{Name: "percentileCont", Signature: "percentileCont(input :: FLOAT, percentile :: FLOAT) :: FLOAT", Doc: "Returns the percentile of a set of values, interpolating between them.", MinArgs: 2, MaxArgs: 2, Aggregate: true},
+	{Name: "percentileDisc", Signature: "percentileDisc(input :: NUMBER, percentile :: FLOAT) :: NUMBER", Doc: "Returns the percentile of a set of values, rounding to the nearest one.", MinArgs: 2, MaxArgs: 2, Aggregate: true},
+	{Name: "stDev", Signature: "stDev(input :: FLOAT) :: FLOAT", Doc: "Returns the standard deviation of a sample of values.", MinArgs: 1, MaxArgs: 1, Aggregate: true},
+	{Name: "stDevP", Signature: "stDevP(input :: FLOAT) :: FLOAT", Doc: "Returns the standard deviation of a whole population of values.", MinArgs: 1, MaxArgs: 1, Aggregate: true},
+	{Name: "sum", Signature: "sum(input :: NUMBER | DURATION) :: NUMBER | DURATION", Doc: "Returns the sum of a set of values.", MinArgs: 1, MaxArgs: 1, Aggregate: true},
+
+	// Predicate functions
+	{Name: "exists", Signature: "exists(input :: ANY) :: BOOLEAN", Doc: "Returns true if a property exists or a pattern has a match.", MinArgs: 1, MaxArgs: 1},
+	{Name: "isEmpty", Signature: "isEmpty(input :: LIST | MAP | STRING) :: BOOLEAN", Doc: "Returns true if a list, map or string is empty.", MinArgs: 1, MaxArgs: 1},
+
+	// Scalar functions
+	{Name: "coalesce", Signature: "coalesce(input :: ANY, ...) :: ANY", Doc: "Returns the first of its arguments that is not null.", MinArgs: 1, MaxArgs: -1},
+	{Name: "elementId", Signature: "elementId(input :: NODE | RELATIONSHIP) :: STRING", Doc: "Returns the element id of a node or relationship.", MinArgs: 1, MaxArgs: 1},
+	{Name: "endNode", Signature: "endNode(input :: RELATIONSHIP) :: NODE", Doc: "Returns the end node of a relationship.", MinArgs: 1, MaxArgs: 1},
+	{Name: "head", Signature: "head(list :: LIST) :: ANY", Doc: "Returns the first element of a list.", MinArgs: 1, MaxArgs: 1},
+	{Name: "id", Signature: "id(input :: NODE | RELATIONSHIP) :: INTEGER", Doc: "Returns the id of a node or relationship.", MinArgs: 1, MaxArgs: 1},
+	{Name: "last", Signature: "last(list :: LIST) :: ANY", Doc: "Returns the last element of a list.", MinArgs: 1, MaxArgs: 1},
+	{Name: "length", Signature: "length(input :: PATH) :: INTEGER", Doc: "Returns the number of relationships in a path.", MinArgs: 1, MaxArgs: 1},
+	{Name: "properties", Signature: "properties(input :: MAP | NODE | RELATIONSHIP) :: MAP", Doc: "Returns a map of the properties of a node or relationship.", MinArgs: 1, MaxArgs: 1},
+	{Name: "randomUUID", Signature: "randomUUID() :: STRING", Doc: "Returns a random UUID.", MinArgs: 0, MaxArgs: 0},
+	{Name: "size", Signature: "size(input :: LIST | STRING) :: INTEGER", Doc: "Returns the number of elements in a list, or characters in a string.", MinArgs: 1, MaxArgs: 1},
+	{Name: "startNode", Signature: "startNode(input :: RELATIONSHIP) :: NODE", Doc: "Returns the start node of a relationship.", MinArgs: 1, MaxArgs: 1},
+	{Name: "timestamp", Signature: "timestamp() :: INTEGER", Doc: "Returns the milliseconds since midnight, January 1, 1970 UTC.", MinArgs: 0, MaxArgs: 0},
+	{Name: "toBoolean", Signature: "toBoolean(input :: STRING | INTEGER | BOOLEAN) :: BOOLEAN", Doc: "Converts a value to a boolean, or null if it cannot.", MinArgs: 1, MaxArgs: 1},
+	{Name: "toFloat", Signature: "toFloat(input :: STRING | INTEGER | FLOAT) :: FLOAT", Doc: "Converts a value to a float, or null if it cannot.", MinArgs: 1, MaxArgs: 1},
+	{Name: "toInteger", Signature: "toInteger(input :: STRING | INTEGER | FLOAT | BOOLEAN) :: INTEGER", Doc: "Converts a value to an integer, or null if it cannot.", MinArgs: 1, MaxArgs: 1},
+	{Name: "type", Signature: "type(input :: RELATIONSHIP) :: STRING", Doc: "Returns the type of a relationship.", MinArgs: 1, MaxArgs: 1},
+
+	// List functions
+	{Name: "keys", Signature: "keys(input :: MAP | NODE | RELATIONSHIP) :: LIST<STRING>", Doc: "Returns the keys of a map, or the property keys of a node or relationship.", MinArgs: 1, MaxArgs: 1},
+	{Name: "labels", Signature: "labels(input :: NODE) :: LIST<STRING>", Doc: "Returns the labels of a node.", MinArgs: 1, MaxArgs: 1},
+	{Name: "nodes", Signature: "nodes(input :: PATH) :: LIST<NODE>", Doc: "Returns the nodes of a path.", MinArgs: 1, MaxArgs: 1},
+	{Name: "range", Signature: "range(start :: INTEGER, end :: INTEGER, step :: INTEGER) :: LIST<INTEGER>", Doc: "Returns the integers from start to end, inclusive, by step.", MinArgs: 2, MaxArgs: 3},
+	{Name: "relationships", Signature: "relationships(input :: PATH) :: LIST<RELATIONSHIP>", Doc: "Returns the relationships of a path.", MinArgs: 1, MaxArgs: 1},
+	{Name: "reverse", Signature: "reverse(input :: STRING | LIST) :: STRING | LIST", Doc: "Returns a string or list in reverse order.", MinArgs: 1, MaxArgs: 1},
+	{Name: "tail", Signature: "tail(input :: LIST) :: LIST", Doc: "Returns all but the first element of a list.", MinArgs: 1, MaxArgs: 1},
+
+	// Mathematical functions
+	{Name: "abs", Signature: "abs(input :: NUMBER) :: NUMBER", Doc: "Returns the absolute value of a number.", MinArgs: 1, MaxArgs: 1},
+	{Name: "ceil", Signature: "ceil(input :: FLOAT) :: FLOAT", Doc: "Rounds a number up to the nearest integer.", MinArgs: 1, MaxArgs: 1},
+	{Name: "floor", Signature: "floor(input :: FLOAT) :: FLOAT", Doc: "Rounds a number down to the nearest integer.", MinArgs: 1, MaxArgs: 1},
+	{Name: "rand", Signature: "rand() :: FLOAT", Doc: "Returns a random number from 0 up to, but not including, 1.", MinArgs: 0, MaxArgs: 0},
+	{Name: "round", Signature: "round(value :: FLOAT, precision :: INTEGER, mode :: STRING) :: FLOAT", Doc: "Rounds a number to the nearest integer, or to a precision.", MinArgs: 1, MaxArgs: 3},
+	{Name: "sign", Signature: "sign(input :: NUMBER) :: INTEGER", Doc: "Returns the sign of a number: -1, 0 or 1.", MinArgs: 1, MaxArgs: 1},
+	{Name: "e", Signature: "e() :: FLOAT", Doc: "Returns e, the base of the natural logarithm.", MinArgs: 0, MaxArgs: 0},
+	{Name: "exp", Signature: "exp(input :: FLOAT) :: FLOAT", Doc: "Returns e raised to the power of a number.", MinArgs: 1, MaxArgs: 1},
+	{Name: "log", Signature: "log(input :: FLOAT) :: FLOAT", Doc: "Returns the natural logarithm of a number.", MinArgs: 1, MaxArgs: 1},
+	{Name: "log10", Signature: "log10(input :: FLOAT) :: FLOAT", Doc: "Returns the base 10 logarithm of a number.", MinArgs: 1, MaxArgs: 1},
+	{Name: "sqrt", Signature: "sqrt(input :: FLOAT) :: FLOAT", Doc: "Returns the square root of a number.", MinArgs: 1, MaxArgs: 1},
+	{Name: "acos", Signature: "acos(input :: FLOAT) :: FLOAT", Doc: "Returns the arccosine of a number, in radians.", MinArgs: 1, MaxArgs: 1},
+	{Name: "asin", Signature: "asin(input :: FLOAT) :: FLOAT", Doc: "Returns the arcsine of a number, in radians.", MinArgs: 1, MaxArgs: 1},
+	{Name: "atan", Signature: "atan(input :: FLOAT) :: FLOAT", Doc: "Returns the arctangent of a number, in radians.", MinArgs: 1, MaxArgs: 1},
+	{Name: "atan2", Signature: "atan2(y :: FLOAT, x :: FLOAT) :: FLOAT", Doc: "Returns the arctangent of y/x, in radians.", MinArgs: 2, MaxArgs: 2},
+	{Name: "cos", Signature: "cos(input :: FLOAT) :: FLOAT", Doc: "Returns the cosine of an angle in radians.", MinArgs: 1, MaxArgs: 1},
+	{Name: "cot", Signature: "cot(input :: FLOAT) :: FLOAT", Doc: "Returns the cotangent of an angle in radians.", MinArgs: 1, MaxArgs: 1},
+	{Name: "degrees", Signature: "degrees(input :: FLOAT) :: FLOAT", Doc: "Converts radians to degrees.", MinArgs: 1, MaxArgs: 1},
+	{Name: "haversin", Signature: "haversin(input :: FLOAT) :: FLOAT", Doc: "Returns half the versine of a number.", MinArgs: 1, MaxArgs: 1},
+	{Name: "pi", Signature: "pi() :: FLOAT", Doc: "Returns pi.", MinArgs: 0, MaxArgs: 0},
+	{Name: "radians", Signature: "radians(input :: FLOAT) :: FLOAT", Doc: "Converts degrees to radians.", MinArgs: 1, MaxArgs: 1},
+	{Name: "sin", Signature: "sin(input :: FLOAT) :: FLOAT", Doc: "Returns the sine of an angle in radians.", MinArgs: 1, MaxArgs: 1},
+	{Name: "tan", Signature: "tan(input :: FLOAT) :: FLOAT", Doc: "Returns the tangent of an angle in radians.", MinArgs: 1, MaxArgs: 1},
+
+	// String functions
+	{Name: "left", Signature: "left(original :: STRING, length :: INTEGER) :: STRING", Doc: "Returns the first characters of a string.", MinArgs: 2, MaxArgs: 2},
+	{Name: "lTrim", Signature: "lTrim(input :: STRING) :: STRING", Doc: "Returns a string without its leading whitespace.", MinArgs: 1, MaxArgs: 1},
+	{Name: "replace", Signature: "replace(original :: STRING, search :: STRING, replace :: STRING) :: STRING", Doc: "Replaces every occurrence of one string in another.", MinArgs: 3, MaxArgs: 3},
+	{Name: "right", Signature: "right(original :: STRING, length :: INTEGER) :: STRING", Doc: "Returns the last characters of a string.", MinArgs: 2, MaxArgs: 2},
+	{Name: "rTrim", Signature: "rTrim(input :: STRING) :: STRING", Doc: "Returns a string without its trailing whitespace.", MinArgs: 1, MaxArgs: 1},
+	{Name: "split", Signature: "split(original :: STRING, splitDelimiter :: STRING) :: LIST<STRING>", Doc: "Splits a string at each occurrence of a delimiter.", MinArgs: 2, MaxArgs: 2},
+	{Name: "substring", Signature: "substring(original :: STRING, start :: INTEGER, length :: INTEGER) :: STRING", Doc: "Returns part of a string, from a start index and of a length.", MinArgs: 2, MaxArgs: 3},
+	{Name: "toLower", Signature: "toLower(input :: STRING) :: STRING", Doc: "Returns a string in lower case.", MinArgs: 1, MaxArgs: 1},
+	{Name: "toString", Signature: "toString(input :: ANY) :: STRING", Doc: "Converts a value to a string.", MinArgs: 1, MaxArgs: 1},
+	{Name: "toUpper", Signature: "toUpper(input :: STRING) :: STRING", Doc: "Returns a string in upper case.", MinArgs: 1, MaxArgs: 1},
+	{Name: "trim", Signature: "trim(input :: STRING) :: STRING", Doc: "Returns a string without its leading and trailing whitespace.", MinArgs: 1, MaxArgs: 1},
+
+	// Temporal and spatial functions
+	{Name: "date", Signature: "date(input :: ANY) :: DATE", Doc: "Returns a date, from a string or map, or the current date.", MinArgs: 0, MaxArgs: 1},
+	{Name: "datetime", Signature: "datetime(input :: ANY) :: ZONED DATETIME", Doc: "Returns a datetime with a time zone, from a string or map, or the current one.", MinArgs: 0, MaxArgs: 1},
+	{Name: "duration", Signature: "duration(input :: ANY) :: DURATION", Doc: "Returns a duration, from a string or map.", MinArgs: 1, MaxArgs: 1},
+	{Name: "localdatetime", Signature: "localdatetime(input :: ANY) :: LOCAL DATETIME", Doc: "Returns a datetime without a time zone, from a string or map, or the current one.", MinArgs: 0, MaxArgs: 1},
+	{Name: "localtime", Signature: "localtime(input :: ANY) :: LOCAL TIME", Doc: "Returns a time without a time zone, from a string or map, or the current one.", MinArgs: 0, MaxArgs: 1},
+	{Name: "time", Signature: "time(input :: ANY) :: ZONED TIME", Doc: "Returns a time with a time zone, from a string or map, or the current one.", MinArgs: 0, MaxArgs: 1},
+	{Name: "point", Signature: "point(input :: MAP) :: POINT", Doc: "Returns a point in a coordinate system, from a map of its coordinates.", MinArgs: 1, MaxArgs: 1},
+	{Name: "distance", Signature: "distance(from :: POINT, to :: POINT) :: FLOAT", Doc: "Returns the distance between two points.", MinArgs: 2, MaxArgs: 2},
+}
@@ -0,0 +1,709 @@
+// Package sema checks the meaning of Cypher queries, beyond their syntax.
+//
+// Check resolves each variable of a query to the symbol it names, so that
+// tools can find where a variable is declared and used, and reports
+// semantic errors, such as a variable used where none is in scope or a
+// relationship created without a type.
+package sema
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/a-poor/cypher/ast"
+)
+
+// Kind is what a symbol holds, as far as can be told from the query.
+type Kind int
+
+const (
+	Value        Kind = iota // a value of any type
+	Node                     // a node, from a node pattern
+	Relationship             // a relationship, or a list of them if variable length
+	Path                     // a path, from a named path pattern
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Value:
+		return "value"
+	case Node:
+		return "node"
+	case Relationship:
+		return "relationship"
+	case Path:
+		return "path"
+	}
+	return "Kind(?)"
+}
+
+// Symbol is a variable of a query: a name bound to a value by one
+// declaration, and used in the places that refer to it.
+type Symbol struct {
+	Name string
+	Kind Kind
+
+	// Decl is where the symbol is declared.
+	Decl *ast.Variable
+
+	// Clause is the clause that declares the symbol, such as the MATCH
+	// of a node pattern or the WITH of an alias. It is nil for the
+	// variables of list comprehensions and other expressions, which are
+	// local to them.
+	Clause ast.Clause
+
+	// Refs are the uses of the symbol, in source order, not counting its
+	// declaration.
+	Refs []*ast.Variable
+}
+
+// Info is what Check finds out about a query.
+type Info struct {
+	// Symbols holds the symbols of the query, in the order of their
+	// declarations in the source.
+	Symbols []*Symbol
+
+	// Uses maps each variable of the query to the symbol it names. It
+	// holds declarations as well as uses, but not variables that name no
+	// symbol, such as those that are not declared.
+	Uses map[*ast.Variable]*Symbol
+
+	// Errors holds the semantic errors in the query, in source order.
+	Errors []*Error
+}
+
+// Error is a semantic error in a query.
+type Error struct {
+	Span ast.Span
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Span.Start, e.Msg)
+}
+
+// Check resolves the variables of a query and checks it for semantic
+// errors. It is meant for queries that parsed without syntax errors, but
+// does what it can with BadClause and BadExpr nodes, which it skips.
+func Check(q *ast.Query) *Info {
+	c := &checker{info: &Info{Uses: map[*ast.Variable]*Symbol{}}}
+	c.query(q, nil)
+	sort.SliceStable(c.info.Symbols, func(i, j int) bool {
+		return c.info.Symbols[i].Decl.Span().Start.Offset < c.info.Symbols[j].Decl.Span().Start.Offset
+	})
+	for _, s := range c.info.Symbols {
+		sort.Slice(s.Refs, func(i, j int) bool {
+			return s.Refs[i].Span().Start.Offset < s.Refs[j].Span().Start.Offset
+		})
+	}
+	sort.SliceStable(c.info.Errors, func(i, j int) bool {
+		return c.info.Errors[i].Span.Start.Offset < c.info.Errors[j].Span.Start.Offset
+	})
+	return c.info
+}
+
+// scope holds the symbols visible at a point of a query, by name.
+type scope struct {
+	names  map[string]*Symbol
+	parent *scope
+}
+
+func newScope(parent *scope) *scope {
+	return &scope{names: map[string]*Symbol{}, parent: parent}
+}
+
+func (s *scope) lookup(name string) *Symbol {
+	for ; s != nil; s = s.parent {
+		if sym := s.names[name]; sym != nil {
+			return sym
+		}
+	}
+	return nil
+}
+
+// Contexts an expression can be in, which limit what it may hold.
+const (
+	ctxProjection = iota // a projection or ORDER BY, where aggregates are allowed
+	ctxOther             // anywhere else
+)
+
+type checker struct {
+	info      *Info
+	ctx       int    // the context of the expression being checked
+	aggregate string // the aggregate function being checked, if any
+}
+
+func (c *checker) errorf(n ast.Node, format string, args ...interface{}) {
+	c.info.Errors = append(c.info.Errors, &Error{Span: n.Span(), Msg: fmt.Sprintf(format, args...)})
+}
+
+// declare declares a new symbol for v in s.
+func (c *checker) declare(s *scope, v *ast.Variable, kind Kind, clause ast.Clause) *Symbol {
+	sym := &Symbol{Name: v.Name, Kind: kind, Decl: v, Clause: clause}
+	s.names[v.Name] = sym
+	c.info.Symbols = append(c.info.Symbols, sym)
+	c.info.Uses[v] = sym
+	return sym
+}
+
+// use records v as a use of sym.
+func (c *checker) use(v *ast.Variable, sym *Symbol) {
+	sym.Refs = append(sym.Refs, v)
+	c.info.Uses[v] = sym
+}
+
+// Queries and clauses
+
+// query checks a query, in a scope under outer, which is nil for a query
+// of its own and the enclosing scope for an EXISTS subquery.
+func (c *checker) query(q *ast.Query, outer *scope) {
+	var first *ast.Union // the first UNION
+	var columns []string // returned by the part before it
+	start := 0
+	for i := 0; i <= len(q.Clauses); i++ {
+		if i < len(q.Clauses) && !isUnion(q.Clauses[i]) {
+			continue
+		}
+		// The clauses from start to i are a part of the query.
+		s := newScope(outer)
+		for _, cl := range q.Clauses[start:i] {
+			s = c.clause(cl, s)
+		}
+		if first != nil && i > start && !equalStrings(returnColumns(q.Clauses[start:i]), columns) {
+			c.errorf(q.Clauses[i-1], "all parts of a UNION must return the same columns")
+		}
+		if i == len(q.Clauses) {
+			break
+		}
+		u := q.Clauses[i].(*ast.Union)
+		switch {
+		case first == nil:
+			first, columns = u, returnColumns(q.Clauses[start:i])
+		case u.All != first.All:
+			c.errorf(u, "UNION and UNION ALL cannot be mixed")
+		}
+		start = i + 1
+	}
+}
+
+func isUnion(c ast.Clause) bool {
+	_, ok := c.(*ast.Union)
+	return ok
+}
+
+// returnColumns returns the names of the columns returned by the clauses
+// of a part of a query, or nil if it does not end with RETURN.
+func returnColumns(clauses []ast.Clause) []string {
+	if len(clauses) == 0 {
+		return nil
+	}
+	r, ok := clauses[len(clauses)-1].(*ast.Return)
+	if !ok {
+		return nil
+	}
+	names := []string{}
+	if r.Projection.Star {
+		names = append(names, "*")
+	}
+	for _, item := range r.Projection.Items {
+		names = append(names, columnName(item))
+	}
+	return names
+}
+
+// columnName returns the name of the column a projection item gives: its
+// alias, or else the text of its expression.
+func columnName(item *ast.ProjectionItem) string {
+	if item.Alias != nil {
+		return item.Alias.Name
+	}
+	if v, ok := item.Expr.(*ast.Variable); ok {
+		return v.Name
+	}
+	return item.Expr.Text()
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// clause checks a clause in scope s, returning the scope of the clauses
+// after it.
+func (c *checker) clause(cl ast.Clause, s *scope) *scope {
+	c.ctx = ctxOther
+	switch cl := cl.(type) {
+	case *ast.Match:
+		c.pattern(cl.Pattern, s, cl)
+		c.where(cl.Where, s)
+	case *ast.Unwind:
+		c.expr(cl.Expr, s)
+		if cl.Variable != nil {
+			c.declareNew(s, cl.Variable, Value, cl)
+		}
+	case *ast.InQueryCall:
+		c.call(cl.Args, cl.Yield, cl.Where, s, cl)
+	case *ast.StandaloneCall:
+		c.call(cl.Args, cl.Yield, cl.Where, s, cl)
+	case *ast.Create:
+		c.pattern(cl.Pattern, s, cl)
+	case *ast.Merge:
+		if cl.Pattern != nil {
+			c.pathPattern(cl.Pattern, s, cl)
+		}
+		for _, a := range cl.Actions {
+			c.set(a.Set, s)
+		}
+	case *ast.Set:
+		c.set(cl, s)
+	case *ast.Remove:
+		for _, item := range cl.Items {
+			c.expr(item.Target, s)
+		}
+	case *ast.Delete:
+		for _, x := range cl.Exprs {
+			c.expr(x, s)
+		}
+	case *ast.With:
+		s = c.projection(cl.Projection, s, cl)
+		c.where(cl.Where, s)
+	case *ast.Return:
+		s = c.projection(cl.Projection, s, cl)
+	}
+	return s
+}
+
+func (c *checker) where(cond ast.Expr, s *scope) {
+	if cond == nil {
+		return
+	}
+	ctx := c.ctx
+	c.ctx = ctxOther
+	c.expr(cond, s)
+	c.ctx = ctx
+}
+
+// declareNew declares v in s, reporting an error if it is already
+// declared.
+func (c *checker) declareNew(s *scope, v *ast.Variable, kind Kind, clause ast.Clause) {
+	if sym := s.lookup(v.Name); sym != nil {
+		c.errorf(v, "variable `%s` is already declared", v.Name)
+		c.use(v, sym)
+		return
+	}
+	c.declare(s, v, kind, clause)
+}
+
+func (c *checker) call(args []ast.Expr, yield []*ast.YieldItem, where ast.Expr, s *scope, cl ast.Clause) {
+	for _, x := range args {
+		c.expr(x, s)
+	}
+	for _, item := range yield {
+		if item.Variable != nil {
+			c.declareNew(s, item.Variable, Value, cl)
+		}
+	}
+	c.where(where, s)
+}
+
+func (c *checker) set(set *ast.Set, s *scope) {
+	if set == nil {
+		return
+	}
+	for _, item := range set.Items {
+		c.expr(item.Target, s)
+		c.expr(item.Value, s)
+	}
+}
+
+// projection checks the projection of a WITH or RETURN, returning the
+// scope of what it projects.
+func (c *checker) projection(proj *ast.Projection, s *scope, cl ast.Clause) *scope {
+	_, with := cl.(*ast.With)
+	next := newScope(s.parent)
+	if proj.Star {
+		if !s.any() {
+			c.errorf(cl, "%s * needs variables in scope", clauseName(cl))
+		}
+		s.copyTo(next)
+	}
+
+	c.ctx = ctxProjection
+	seen := map[string]bool{}
+	for _, item := range proj.Items {
+		c.expr(item.Expr, s)
+		name := columnName(item)
+		if seen[name] {
+			c.errorf(item, "column `%s` is returned more than once", name)
+		}
+		seen[name] = true
+
+		v, isVar := item.Expr.(*ast.Variable)
+		switch {
+		case item.Alias != nil:
+			kind := Value
+			if isVar && c.info.Uses[v] != nil {
+				kind = c.info.Uses[v].Kind
+			}
+			c.declare(next, item.Alias, kind, cl)
+		case isVar:
+			if sym := c.info.Uses[v]; sym != nil {
+				next.names[v.Name] = sym
+			}
+		case with:
+			c.errorf(item, "expression in WITH must be given a name with AS")
+		}
+	}
+
+	// ORDER BY sees both what is projected and what was in scope
+	// before.
+	order := newScope(s)
+	next.copyTo(order)
+	for _, item := range proj.OrderBy {
+		c.expr(item.Expr, order)
+	}
+	c.ctx = ctxOther
+	c.limit(proj.Skip, "SKIP", s)
+	c.limit(proj.Limit, "LIMIT", s)
+	return next
+}
+
+// limit checks the expression of a SKIP or LIMIT.
+func (c *checker) limit(x ast.Expr, what string, s *scope) {
+	if x == nil {
+		return
+	}
+	c.expr(x, s)
+	switch x := x.(type) {
+	case *ast.UnaryExpr:
+		if _, ok := x.X.(*ast.IntegerLiteral); ok && x.Op == ast.OpMinus {
+			c.errorf(x, "%s must not be negative", what)
+		}
+	case *ast.IntegerLiteral:
+		if x.Value < 0 {
+			c.errorf(x, "%s must not be negative", what)
+		}
+	case *ast.FloatLiteral, *ast.StringLiteral, *ast.BooleanLiteral, *ast.NullLiteral,
+		*ast.ListLiteral, *ast.MapLiteral:
+		c.errorf(x, "%s must be an integer", what)
+	}
+}
+
+func clauseName(cl ast.Clause) string {
+	if _, ok := cl.(*ast.With); ok {
+		return "WITH"
+	}
+	return "RETURN"
+}
+
+// any reports whether any symbol is in scope.
+func (s *scope) any() bool {
+	for ; s != nil; s = s.parent {
+		if len(s.names) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// copyTo copies the symbols in scope s, but not in its parents, to t.
+func (s *scope) copyTo(t *scope) {
+	for name, sym := range s.names {
+		t.names[name] = sym
+	}
+}
+
+// Patterns
+
+// pattern checks the paths of a pattern in a MATCH, CREATE or MERGE,
+// declaring their new variables in s.
+func (c *checker) pattern(pat *ast.Pattern, s *scope, cl ast.Clause) {
+	if pat == nil {
+		return
+	}
+	for _, path := range pat.Paths {
+		c.declarePath(path, s, cl)
+	}
+	for _, path := range pat.Paths {
+		c.pathProperties(path, s)
+	}
+}
+
+func (c *checker) pathPattern(path *ast.PathPattern, s *scope, cl ast.Clause) {
+	c.declarePath(path, s, cl)
+	c.pathProperties(path, s)
+}
+
+// declarePath declares the new variables of a path of a clause, and
+// checks that those already declared may be used where they are.
+func (c *checker) declarePath(path *ast.PathPattern, s *scope, cl ast.Clause) {
+	var create string // the clause, if it creates what it does not find
+	switch cl.(type) {
+	case *ast.Create:
+		create = "CREATE"
+	case *ast.Merge:
+		create = "MERGE"
+	}
+
+	if path.Variable != nil {
+		c.declareNew(s, path.Variable, Path, cl)
+	}
+	if create != "" && path.Shortest != ast.NotShortest {
+		c.errorf(path, "%s cannot be used in %s", path.Shortest, create)
+	}
+	for _, n := range path.Nodes {
+		if n.Variable == nil {
+			continue
+		}
+		sym := s.lookup(n.Variable.Name)
+		switch {
+		case sym == nil:
+			c.declare(s, n.Variable, Node, cl)
+			continue
+		case create != "" && (len(n.Labels) > 0 || n.Properties != nil):
+			c.errorf(n, "variable `%s` is already declared, so its node cannot be given labels or properties in %s", n.Variable.Name, create)
+		}
+		c.useAs(n.Variable, sym, Node)
+	}
+	for _, r := range path.Relationships {
+		if create != "" {
+			if len(r.Types) != 1 {
+				c.errorf(r, "a relationship in %s must have exactly one type", create)
+			}
+			if create == "CREATE" && (r.Direction == ast.DirectionNone || r.Direction == ast.DirectionBoth) {
+				c.errorf(r, "a relationship in CREATE must have a direction")
+			}
+			if r.VarLength {
+				c.errorf(r, "a variable length relationship cannot be used in %s", create)
+			}
+		}
+		if r.Variable == nil {
+			continue
+		}
+		sym := s.lookup(r.Variable.Name)
+		switch {
+		case sym == nil:
+			c.declare(s, r.Variable, Relationship, cl)
+			continue
+		case create != "":
+			c.errorf(r.Variable, "relationship `%s` is already declared, so it cannot be used in %s", r.Variable.Name, create)
+		}
+		c.useAs(r.Variable, sym, Relationship)
+	}
+}
+
+// useAs records v as a use of sym, which must be of the given kind if its
+// kind is known.
+func (c *checker) useAs(v *ast.Variable, sym *Symbol, kind Kind) {
+	if sym.Kind != Value && sym.Kind != kind {
+		c.errorf(v, "variable `%s` is a %s, not a %s", v.Name, sym.Kind, kind)
+	}
+	c.use(v, sym)
+}
+
+// pathProperties checks the property maps of the nodes and relationships
+// of a path.
+func (c *checker) pathProperties(path *ast.PathPattern, s *scope) {
+	for _, n := range path.Nodes {
+		c.expr(n.Properties, s)
+	}
+	for _, r := range path.Relationships {
+		c.expr(r.Properties, s)
+	}
+}
+
+// localPath checks a path of a pattern comprehension or EXISTS subquery,
+// whose new variables are local to it.
+func (c *checker) localPath(path *ast.PathPattern, s *scope) {
+	if path.Variable != nil {
+		c.declareNew(s, path.Variable, Path, nil)
+	}
+	for _, n := range path.Nodes {
+		if n.Variable != nil {
+			if sym := s.lookup(n.Variable.Name); sym != nil {
+				c.useAs(n.Variable, sym, Node)
+			} else {
+				c.declare(s, n.Variable, Node, nil)
+			}
+		}
+	}
+	for _, r := range path.Relationships {
+		if r.Variable != nil {
+			if sym := s.lookup(r.Variable.Name); sym != nil {
+				c.useAs(r.Variable, sym, Relationship)
+			} else {
+				c.declare(s, r.Variable, Relationship, nil)
+			}
+		}
+	}
+	c.pathProperties(path, s)
+}
+
+// Expressions
+
+func (c *checker) expr(x ast.Expr, s *scope) {
+	switch x := x.(type) {
+	case nil, *ast.BadExpr:
+	case *ast.Variable:
+		if sym := s.lookup(x.Name); sym != nil {
+			c.use(x, sym)
+		} else {
+			c.errorf(x, "variable `%s` is not declared", x.Name)
+		}
+	case *ast.Parameter, *ast.IntegerLiteral, *ast.FloatLiteral, *ast.StringLiteral,
+		*ast.BooleanLiteral, *ast.NullLiteral:
+	case *ast.ListLiteral:
+		for _, e := range x.Elems {
+			c.expr(e, s)
+		}
+	case *ast.MapLiteral:
+		for _, e := range x.Entries {
+			c.expr(e.Value, s)
+		}
+	case *ast.BinaryExpr:
+		c.expr(x.X, s)
+		c.expr(x.Y, s)
+	case *ast.UnaryExpr:
+		c.expr(x.X, s)
+	case *ast.StringPredicate:
+		c.expr(x.X, s)
+		c.expr(x.Y, s)
+	case *ast.IsNull:
+		c.expr(x.X, s)
+	case *ast.In:
+		c.expr(x.X, s)
+		c.expr(x.List, s)
+	case *ast.PropertyAccess:
+		c.expr(x.X, s)
+	case *ast.HasLabels:
+		c.expr(x.X, s)
+	case *ast.IndexExpr:
+		c.expr(x.X, s)
+		c.expr(x.Index, s)
+	case *ast.SliceExpr:
+		c.expr(x.X, s)
+		c.expr(x.Low, s)
+		c.expr(x.High, s)
+	case *ast.CaseExpr:
+		c.expr(x.Subject, s)
+		for _, w := range x.Whens {
+			c.expr(w.When, s)
+			c.expr(w.Then, s)
+		}
+		c.expr(x.Else, s)
+	case *ast.FunctionCall:
+		c.functionCall(x, s)
+	case *ast.CountStar:
+		c.checkAggregate(x, "count")
+	case *ast.FilterExpr:
+		c.expr(x.List, s)
+		inner := newScope(s)
+		if x.Variable != nil {
+			c.declare(inner, x.Variable, Value, nil)
+		}
+		c.expr(x.Where, inner)
+	case *ast.ListComprehension:
+		c.expr(x.List, s)
+		inner := newScope(s)
+		if x.Variable != nil {
+			c.declare(inner, x.Variable, Value, nil)
+		}
+		c.expr(x.Where, inner)
+		c.expr(x.Result, inner)
+	case *ast.PatternComprehension:
+		inner := newScope(s)
+		if x.Pattern != nil {
+			c.localPath(x.Pattern, inner)
+		}
+		c.expr(x.Where, inner)
+		c.expr(x.Result, inner)
+	case *ast.ExistsSubquery:
+		ctx, aggregate := c.ctx, c.aggregate
+		if x.Query != nil {
+			c.query(x.Query, s)
+		} else if x.Pattern != nil {
+			inner := newScope(s)
+			for _, path := range x.Pattern.Paths {
+				c.localPath(path, inner)
+			}
+			c.where(x.Where, inner)
+		}
+		c.ctx, c.aggregate = ctx, aggregate
+	case *ast.PathPattern:
+		c.patternExpr(x, s)
+	default:
+		panic(fmt.Sprintf("sema: unexpected expression type %T", x))
+	}
+}
+
+// patternExpr checks a pattern used as an expression, which may not
+// declare variables.
+func (c *checker) patternExpr(path *ast.PathPattern, s *scope) {
+	check := func(v *ast.Variable, kind Kind) {
+		if v == nil {
+			return
+		}
+		if sym := s.lookup(v.Name); sym != nil {
+			c.useAs(v, sym, kind)
+		} else {
+			c.errorf(v, "variable `%s` is not declared; a pattern expression cannot declare variables", v.Name)
+		}
+	}
+	check(path.Variable, Path)
+	for _, n := range path.Nodes {
+		check(n.Variable, Node)
+	}
+	for _, r := range path.Relationships {
+		check(r.Variable, Relationship)
+	}
+	c.pathProperties(path, s)
+}
+
+func (c *checker) functionCall(f *ast.FunctionCall, s *scope) {
+	fn := LookupFunction(f.Name)
+	switch {
+	case fn != nil:
+		if len(f.Args) < fn.MinArgs || fn.MaxArgs >= 0 && len(f.Args) > fn.MaxArgs {
+			c.errorf(f, "function %s takes %s, not %d", fn.Name, fn.arity(), len(f.Args))
+		}
+		if f.Distinct && !fn.Aggregate {
+			c.errorf(f, "DISTINCT can only be used with aggregate functions, not %s", fn.Name)
+		}
+	case !strings.Contains(f.Name, "."):
+		// Functions in namespaces are user-defined, and unknown here.
+		c.errorf(f, "unknown function %s", f.Name)
+	}
+
+	if fn == nil || !fn.Aggregate {
+		for _, x := range f.Args {
+			c.expr(x, s)
+		}
+		return
+	}
+	c.checkAggregate(f, fn.Name)
+	aggregate := c.aggregate
+	c.aggregate = fn.Name
+	for _, x := range f.Args {
+		c.expr(x, s)
+	}
+	c.aggregate = aggregate
+}
+
+// checkAggregate checks that a call of an aggregate function is where one
+// may be.
+func (c *checker) checkAggregate(x ast.Expr, name string) {
+	switch {
+	case c.aggregate != "":
+		c.errorf(x, "aggregate function %s cannot be used inside %s", name, c.aggregate)
+	case c.ctx != ctxProjection:
+		c.errorf(x, "aggregate function %s can only be used in WITH, RETURN or ORDER BY", name)
+	}
+}
@@ -0,0 +1,144 @@
+package sema_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/a-poor/cypher"
+	"github.com/a-poor/cypher/ast"
+	"github.com/a-poor/cypher/sema"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"MATCH (n)-[r]->(m) WHERE n.x = m.x RETURN n, r, count(*)", nil},
+		{"MATCH (n) RETURN m", []string{"1:18: variable `m` is not declared"}},
+		{"MATCH (n)-[r]->(m) WITH n, count(*) AS c RETURN n, r", []string{"1:52: variable `r` is not declared"}},
+		{"MATCH (n) WITH n.x RETURN 1", []string{"1:16: expression in WITH must be given a name with AS"}},
+		{"MATCH (n) WITH n AS m ORDER BY n.x RETURN m", nil},
+		{"MATCH (n) RETURN n, n", []string{"1:21: column `n` is returned more than once"}},
+		{"RETURN *", []string{"1:1: RETURN * needs variables in scope"}},
+		{"MATCH (n) CREATE (n:Foo)", []string{"1:18: variable `n` is already declared, so its node cannot be given labels or properties in CREATE"}},
+		{"CREATE (a)-[:A|B]->(b)", []string{"1:11: a relationship in CREATE must have exactly one type"}},
+		{"CREATE (a)-[:A]-(b)", []string{"1:11: a relationship in CREATE must have a direction"}},
+		{"MERGE (a)-[:A]-(b)", nil},
+		{"MATCH p = (a)-[r]->(b) MATCH (r)-->(p) RETURN *", []string{
+			"1:31: variable `r` is a relationship, not a node",
+			"1:37: variable `p` is a path, not a node",
+		}},
+		{"MATCH (n) WHERE count(n) > 1 RETURN n", []string{"1:17: aggregate function count can only be used in WITH, RETURN or ORDER BY"}},
+		{"MATCH (n) RETURN sum(count(n))", []string{"1:22: aggregate function count cannot be used inside sum"}},
+		{"RETURN foo(1)", []string{"1:8: unknown function foo"}},
+		{"RETURN apoc.text.join(['a'], ',')", nil},
+		{"RETURN toLower(1, 2)", []string{"1:8: function toLower takes 1 argument, not 2"}},
+		{"RETURN toUpper(DISTINCT 'a')", []string{"1:8: DISTINCT can only be used with aggregate functions, not toUpper"}},
+		{"RETURN 1 AS a UNION RETURN 2 AS b", []string{"1:21: all parts of a UNION must return the same columns"}},
+		{"RETURN 1 AS a UNION RETURN 2 AS a UNION ALL RETURN 3 AS a", []string{"1:35: UNION and UNION ALL cannot be mixed"}},
+		{"MATCH (n) WHERE (n)-->(m) RETURN n", []string{"1:24: variable `m` is not declared; a pattern expression cannot declare variables"}},
+		{"MATCH (n) RETURN [x IN n.xs | x + y], [(n)-->(z) | z.name]", []string{"1:35: variable `y` is not declared"}},
+		{"MATCH (n) WHERE EXISTS { MATCH (n)-->(m) RETURN m } RETURN n, m", []string{"1:63: variable `m` is not declared"}},
+		{"MATCH (n) RETURN n LIMIT -1", []string{"1:26: LIMIT must not be negative"}},
+		{"UNWIND [1, 2] AS x WITH x WHERE x > 1 RETURN x", nil},
+	}
+	for _, tt := range tests {
+		q, err := cypher.Parse(tt.query)
+		if err != nil {
+			t.Errorf("%q: %v", tt.query, err)
+			continue
+		}
+		var got []string
+		for _, e := range sema.Check(q).Errors {
+			got = append(got, e.Error())
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Check(%q) errors =\n%q\nwant\n%q", tt.query, got, tt.want)
+		}
+	}
+}
+
+// The queries of the corpus are all meaningful.
+func TestCheckCorpus(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "testdata", "corpus", "*.cypher"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no queries in testdata/corpus: %v", err)
+	}
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		q, err := cypher.Parse(string(b))
+		if err != nil {
+			t.Errorf("%s: %v", f, err)
+			continue
+		}
+		for _, e := range sema.Check(q).Errors {
+			t.Errorf("%s: %v", f, e)
+		}
+	}
+}
+
+func TestSymbols(t *testing.T) {
+	q, err := cypher.Parse("MATCH (a)-[r:KNOWS]->(b) WITH a, b AS friend WHERE friend.age > a.age RETURN a, friend")
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := sema.Check(q)
+	if len(info.Errors) > 0 {
+		t.Fatal(info.Errors)
+	}
+	type symbol struct {
+		Name string
+		Kind sema.Kind
+		Decl int
+		Refs []int
+	}
+	offsets := func(vs []*ast.Variable) []int {
+		var offs []int
+		for _, v := range vs {
+			offs = append(offs, v.Span().Start.Offset)
+		}
+		return offs
+	}
+	var got []symbol
+	for _, s := range info.Symbols {
+		got = append(got, symbol{s.Name, s.Kind, s.Decl.Span().Start.Offset, offsets(s.Refs)})
+	}
+	want := []symbol{
+		// WITH a passes a on, rather than declaring a new symbol.
+		{"a", sema.Node, 7, []int{30, 64, 77}},
+		{"r", sema.Relationship, 11, nil},
+		{"b", sema.Node, 22, []int{33}},
+		{"friend", sema.Node, 38, []int{51, 80}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got symbols\n%v\nwant\n%v", got, want)
+	}
+	for v, s := range info.Uses {
+		if v.Name != s.Name {
+			t.Errorf("variable %s at %d resolves to symbol %s", v.Name, v.Span().Start.Offset, s.Name)
+		}
+	}
+}
+
+func TestLookupFunction(t *testing.T) {
+	for _, name := range []string{"count", "COUNT", "toLower", "tolower"} {
+		if f := sema.LookupFunction(name); f == nil {
+			t.Errorf("LookupFunction(%q) = nil", name)
+		}
+	}
+	if f := sema.LookupFunction("nope"); f != nil {
+		t.Errorf("LookupFunction(%q) = %v, want nil", "nope", f)
+	}
+	fs := sema.Functions()
+	for i := 1; i < len(fs); i++ {
+		if fs[i-1].Name >= fs[i].Name {
+			t.Errorf("Functions not sorted: %s before %s", fs[i-1].Name, fs[i].Name)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package cypher
+
+import (
+	"sort"
+	"unicode/utf8"
+
+	"github.com/a-poor/cypher/ast"
+)
+
+// source maps byte offsets in a query or script to positions.
+//
+// The input being parsed may be only part of the text, as when each
+// statement of a script is parsed on its own. Offsets and positions are
+// always given relative to the whole text.
+type source struct {
+	text  string
+	lines []int      // byte offset of the start of each line of text
+	wide  []wideRune // the runes of text encoded in more than one byte
+
+	base  int    // byte offset of input within text
+	input string // the part of text being parsed
+}
+
+// wideRune is a rune encoded in more than one byte, which the columns of a
+// Position count as one. extra and pairs are running totals over the text
+// up to and including the rune: the bytes taken beyond one for each rune,
+// and the runes encoded in UTF-16 as surrogate pairs.
+type wideRune struct {
+	offset int
+	extra  int
+	pairs  int
+}
+
+func newSource(text string) *source {
+	s := &source{text: text, lines: []int{0}, input: text}
+	extra, pairs := 0, 0
+	for i := 0; i < len(text); {
+		if c := text[i]; c < utf8.RuneSelf {
+			if c == '\n' {
+				s.lines = append(s.lines, i+1)
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if size > 1 {
+			extra += size - 1
+			if r >= 0x10000 {
+				// Encoded as a surrogate pair.
+				pairs++
+			}
+			s.wide = append(s.wide, wideRune{offset: i, extra: extra, pairs: pairs})
+		}
+		i += size
+	}
+	return s
+}
+
+// sub returns a source whose input is the byte range [start, end) of the
+// text.
+func (s *source) sub(start, end int) *source {
+	return &source{text: s.text, lines: s.lines, base: start, input: s.text[start:end]}
+}
+
+// end returns the byte offset just past the input.
+func (s *source) end() int {
+	return s.base + len(s.input)
+}
+
+// pos converts a byte offset into a Position. It takes time logarithmic
+// in the length of the text, however long the line.
+func (s *source) pos(offset int) ast.Position {
+	if offset > len(s.text) {
+		offset = len(s.text)
+	}
+	line := sort.Search(len(s.lines), func(i int) bool { return s.lines[i] > offset })
+	start := s.lines[line-1]
+
+	// The columns count the bytes since the start of the line, less the
+	// extra bytes of the wide runes among them, and in UTF-16 the second
+	// halves of surrogate pairs too.
+	extra, pairs := s.wideBefore(offset)
+	startExtra, startPairs := s.wideBefore(start)
+	col := 1 + offset - start - (extra - startExtra)
+	return ast.Position{Offset: offset, Line: line, Column: col, UTF16Column: col + pairs - startPairs}
+}
+
+// wideBefore returns the running totals of the wide runes that start
+// before a byte offset.
+func (s *source) wideBefore(offset int) (extra, pairs int) {
+	i := sort.Search(len(s.wide), func(i int) bool { return s.wide[i].offset >= offset })
+	if i == 0 {
+		return 0, 0
+	}
+	return s.wide[i-1].extra, s.wide[i-1].pairs
+}
+
+// span returns the Span covering the byte range [start, end).
+func (s *source) span(start, end int) ast.Span {
+	return ast.Span{Start: s.pos(start), End: s.pos(end)}
+}
@@ -0,0 +1 @@
+RETURN 1 + 2 * 3 - 4 / 2 % 3 ^ 2 AS x, -(1 + 2) AS y, +5 AS z
@@ -0,0 +1 @@
+MATCH (n) WHERE (n.a OR n.b) XOR NOT n.c AND n.d RETURN n
@@ -0,0 +1,7 @@
+MATCH (n)
+RETURN n.name,
+  CASE
+    WHEN n.age < 18 THEN 'minor'
+    WHEN n.age < 65 THEN 'adult'
+    ELSE 'senior'
+  END AS bracket
@@ -0,0 +1,3 @@
+// Find active users
+MATCH (u:User) /* only active */ WHERE u.active
+RETURN u // done
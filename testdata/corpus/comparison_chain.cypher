@@ -0,0 +1 @@
+MATCH (n) WHERE 1 < n.x <= 10 AND n.y <> 3 AND n.z = n.w RETURN n
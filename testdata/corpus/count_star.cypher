@@ -0,0 +1 @@
+MATCH (n:Person) RETURN count(*) AS people
@@ -0,0 +1 @@
+CREATE (n:Person {name: 'Ann', born: 1990, tags: ['a', 'b']})
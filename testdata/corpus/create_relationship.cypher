@@ -0,0 +1,2 @@
+MATCH (a:Person {name: 'Ann'}), (b:Person {name: 'Ben'})
+CREATE (a)-[:KNOWS {since: date('2020-01-01')}]->(b)
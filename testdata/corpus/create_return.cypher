@@ -0,0 +1 @@
+CREATE p = (a:A)-[:R]->(b:B) RETURN p
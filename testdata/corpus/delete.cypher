@@ -0,0 +1 @@
+MATCH (n:Session) WHERE n.expires < timestamp() DELETE n
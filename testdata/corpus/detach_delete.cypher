@@ -0,0 +1 @@
+MATCH (n:Orphan) DETACH DELETE n
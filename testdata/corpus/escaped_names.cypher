@@ -0,0 +1 @@
+MATCH (`weird node`:`Label With Spaces`)-[`rel``s`:`TYPE`]->(m) RETURN `weird node`.`prop name`
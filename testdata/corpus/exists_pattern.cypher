@@ -0,0 +1 @@
+MATCH (p:Person) WHERE EXISTS { (p)-[:HAS_DOG]->() } RETURN p
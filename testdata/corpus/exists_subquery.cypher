@@ -0,0 +1,3 @@
+MATCH (p:Person)
+WHERE EXISTS { MATCH (p)-[:HAS_DOG]->(d:Dog) WHERE d.name = p.name RETURN d }
+RETURN p
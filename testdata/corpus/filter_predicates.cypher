@@ -0,0 +1,4 @@
+MATCH p = (a)-[*1..3]->(b)
+WHERE all(n IN nodes(p) WHERE n.active) AND any(r IN relationships(p) WHERE r.weight > 2)
+  AND none(n IN nodes(p) WHERE n:Banned) AND single(x IN nodes(p) WHERE x.root)
+RETURN p
@@ -0,0 +1 @@
+MATCH (n) RETURN count(DISTINCT n.city) AS cities, collect(DISTINCT n.country)
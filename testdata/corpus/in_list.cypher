@@ -0,0 +1 @@
+MATCH (n) WHERE n.status IN ['open', 'pending'] AND NOT n.id IN $excluded RETURN n
@@ -0,0 +1,3 @@
+MATCH (n:Person)
+CALL apoc.path.expand(n, 'KNOWS', null, 1, 2) YIELD path
+RETURN path
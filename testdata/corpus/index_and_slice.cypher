@@ -0,0 +1 @@
+WITH [1, 2, 3, 4, 5] AS xs RETURN xs[0], xs[-1], xs[1..3], xs[..2], xs[2..]
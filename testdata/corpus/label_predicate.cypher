@@ -0,0 +1 @@
+MATCH (n) WHERE n:Person:Admin RETURN n
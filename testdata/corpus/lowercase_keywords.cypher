@@ -0,0 +1 @@
+match (n:person) where n.age > 30 return n.name order by n.name limit 3
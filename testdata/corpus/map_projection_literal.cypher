@@ -0,0 +1 @@
+RETURN {name: 'x', nested: {list: [1, 2.5, -3, 1e3], flag: false}, nothing: null} AS m
@@ -0,0 +1 @@
+MATCH (m:Movie)<-[:ACTED_IN {role: 'Neo'}]-(actor) RETURN actor
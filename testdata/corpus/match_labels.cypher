@@ -0,0 +1 @@
+MATCH (p:Person:Employee) RETURN p.name AS name, p.age
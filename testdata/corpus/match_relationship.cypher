@@ -0,0 +1,2 @@
+MATCH (a:Person)-[r:KNOWS]->(b:Person)
+RETURN a.name, type(r), b.name
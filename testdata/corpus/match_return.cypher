@@ -0,0 +1 @@
+MATCH (n) RETURN n
@@ -0,0 +1 @@
+MATCH (a)-[:FRIEND|COLLEAGUE]-(b) RETURN count(*)
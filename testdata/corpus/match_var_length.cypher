@@ -0,0 +1,2 @@
+MATCH p = (a:Station {name: $from})-[:NEXT*1..10]->(b:Station {name: $to})
+RETURN p, length(p) AS hops
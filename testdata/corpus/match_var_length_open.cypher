@@ -0,0 +1 @@
+MATCH (a)-[*]->(b), (b)-[*2..]->(c), (c)-[*..3]->(d) RETURN a, d
@@ -0,0 +1,3 @@
+MATCH (p:Person)
+WHERE p.age >= 18 AND p.name STARTS WITH 'A'
+RETURN p
@@ -0,0 +1,4 @@
+MERGE (u:User {email: $email})
+ON CREATE SET u.created = timestamp(), u.visits = 1
+ON MATCH SET u.visits = u.visits + 1
+RETURN u
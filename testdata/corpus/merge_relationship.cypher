@@ -0,0 +1,3 @@
+MATCH (a:Tag {name: $a}), (b:Tag {name: $b})
+MERGE (a)-[r:RELATED]-(b)
+RETURN r
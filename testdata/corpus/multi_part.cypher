@@ -0,0 +1,5 @@
+MATCH (a:Person)
+WITH a ORDER BY a.age LIMIT 5
+MATCH (a)-[:LIVES_IN]->(c:City)
+WITH c.name AS city, count(*) AS n
+RETURN city, n
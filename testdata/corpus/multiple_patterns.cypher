@@ -0,0 +1,3 @@
+MATCH (a:Person), (b:Company {name: 'Acme'})
+WHERE NOT (a)-[:WORKS_AT]->(b)
+RETURN a
@@ -0,0 +1 @@
+RETURN apoc.text.join(['a', 'b'], '-') AS s, date.truncate('month', date()) AS d
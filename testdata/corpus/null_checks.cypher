@@ -0,0 +1 @@
+MATCH (n) WHERE n.deleted IS NULL AND n.email IS NOT NULL RETURN n
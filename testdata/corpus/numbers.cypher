@@ -0,0 +1 @@
+RETURN 0, 42, 0x1F, 017, 3.14, .5, 6.02e23, 1E-9
@@ -0,0 +1,3 @@
+MATCH (p:Person)
+OPTIONAL MATCH (p)-[:OWNS]->(c:Car)
+RETURN p.name, collect(c.model) AS cars
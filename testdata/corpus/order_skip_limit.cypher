@@ -0,0 +1,5 @@
+MATCH (p:Product)
+RETURN p.name, p.price
+ORDER BY p.price DESC, p.name ASC
+SKIP 20
+LIMIT 10
@@ -0,0 +1 @@
+MATCH (n:User) WHERE n.id = $id AND n.tenant = $0 RETURN n LIMIT $max
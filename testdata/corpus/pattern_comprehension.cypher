@@ -0,0 +1,2 @@
+MATCH (a:Person {name: 'Keanu'})
+RETURN [(a)-->(b:Movie) WHERE b.released > 2000 | b.title] AS movies
@@ -0,0 +1 @@
+MATCH (n:Temp) REMOVE n:Temp, n.expires RETURN n
@@ -0,0 +1 @@
+MATCH (n)-[r]->() RETURN DISTINCT labels(n), type(r)
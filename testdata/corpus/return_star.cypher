@@ -0,0 +1 @@
+MATCH (a)-->(b) RETURN *
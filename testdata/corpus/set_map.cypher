@@ -0,0 +1 @@
+MATCH (n:Config) SET n = $props RETURN n
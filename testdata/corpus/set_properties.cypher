@@ -0,0 +1,2 @@
+MATCH (n {id: 1})
+SET n += {active: true}, n:Verified, n.score = n.score * 1.5
@@ -0,0 +1 @@
+CALL db.labels()
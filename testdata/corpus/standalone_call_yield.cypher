@@ -0,0 +1 @@
+CALL db.index.fulltext.queryNodes('search', $q) YIELD node, score
@@ -0,0 +1 @@
+RETURN 'It\'s', "say \"hi\"", 'tab\there', 'unicode \u00e9'
@@ -0,0 +1 @@
+MATCH (n) WHERE n.name ENDS WITH 'son' OR n.name CONTAINS 'mc' OR n.email STARTS WITH 'admin@' RETURN n
@@ -0,0 +1 @@
+MATCH (n:Città {nome: 'Zürich 😀'}) RETURN n.名前
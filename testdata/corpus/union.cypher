@@ -0,0 +1,3 @@
+MATCH (a:Actor) RETURN a.name AS name
+UNION
+MATCH (d:Director) RETURN d.name AS name
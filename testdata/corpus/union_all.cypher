@@ -0,0 +1 @@
+RETURN 1 AS x UNION ALL RETURN 2 AS x UNION ALL RETURN 3 AS x
@@ -0,0 +1,2 @@
+UNWIND [1, 2, 3] AS x
+RETURN x * x AS square
@@ -0,0 +1,5 @@
+MATCH (c:Customer)-[:PLACED]->(o:Order)
+WITH c, count(o) AS orders, sum(o.total) AS spent
+WHERE orders > 5
+RETURN c.email, orders, spent
+ORDER BY spent DESC
@@ -0,0 +1,5 @@
+MATCH (u:User {id: $id})
+WITH u
+MATCH (u)-[:FOLLOWS]->(f)
+WITH u, collect(f) AS following
+RETURN u.name, size(following)